@@ -3,28 +3,79 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"os/signal"
-	"sync"
+	"strings"
 	"syscall"
+	"time"
 
-	"traffic-guardian/internal/alerter"
-	"traffic-guardian/internal/collector"
-	"traffic-guardian/internal/config"
-	"traffic-guardian/internal/engine"
-	"traffic-guardian/internal/state"
+	"traffic-guardian/pkg/guardian"
 )
 
+// defaultConfigPath 是未指定 -config 时读取的配置文件
+const defaultConfigPath = "config.yaml"
+
+// configPaths 是可以重复指定的 -config 参数
+type configPaths []string
+
+func (p *configPaths) String() string { return strings.Join(*p, ",") }
+
+func (p *configPaths) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
 func main() {
 	// 1. 初始化
 	// 解析命令行参数
-	configFile := flag.String("config", "config.yaml", "Path to the configuration file")
+	var configFiles configPaths
+	flag.Var(&configFiles, "config", "Path to a configuration file; repeat to merge overlays in order (default config.yaml)")
+	validate := flag.Bool("validate", false, "Check the configuration, print any problems and exit (0 if valid, 1 otherwise)")
+	once := flag.Bool("once", false, "Collect for -duration, evaluate rules once, send any alerts, print them to stdout as JSON lines and exit (logs go to stderr)")
+	replay := flag.String("replay", "", "Replay events from a file recorded via collector.record_path instead of attaching eBPF")
+	replaySpeed := flag.Float64("replay-speed", 0, "Pace -replay by the recorded event timing, sped up by this factor (1 = real time); 0 replays as fast as possible")
+	onceDuration := flag.Duration("duration", 0, "Collection duration for -once (defaults to rules.time_window_minutes)")
+	snapshotPath := flag.String("snapshot-file", "", "File to write the JSON stats snapshot to on SIGUSR1 (defaults to stderr)")
+	top := flag.Bool("top", false, "Show a live, top-like view of the heaviest processes without evaluating rules (logs are discarded unless log_output is a file or syslog)")
 	flag.Parse()
 
-	// 加载配置
-	cfg, err := config.LoadConfig(*configFile)
+	// 加载配置，多个文件按顺序合并。没有指定 -config 且默认的 config.yaml 不存在时使用内置的基础配置，
+	// 显式指定的文件不存在或文件内容无效时仍然报错
+	explicitConfig := len(configFiles) > 0
+	if !explicitConfig {
+		configFiles = configPaths{defaultConfigPath}
+	}
+	var cfg *guardian.Config
+	var err error
+	_, statErr := os.Stat(defaultConfigPath)
+	usingDefaults := !explicitConfig && errors.Is(statErr, fs.ErrNotExist)
+	if usingDefaults && *validate {
+		// 内置的基础配置总是合法的，报告它有效会让人误以为检查了 config.yaml
+		fmt.Fprintf(os.Stderr, "Config file %s not found, nothing to validate\n", defaultConfigPath)
+		os.Exit(1)
+	}
+	if usingDefaults {
+		slog.Warn("Config file not found, using built-in defaults (all alerters disabled)", "path", defaultConfigPath)
+		cfg = guardian.DefaultConfig()
+	} else {
+		cfg, err = guardian.LoadConfigs(configFiles)
+	}
+	if *validate {
+		// 只检查配置，不加载 eBPF 也不启动任何组件
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Config %s is invalid:\n%v\n", configFiles.String(), err)
+			os.Exit(1)
+		}
+		fmt.Printf("Config %s is valid\n", configFiles.String())
+		return
+	}
 	if err != nil {
 		slog.Error("Failed to load config", "error", err)
 		os.Exit(1)
@@ -42,100 +93,111 @@ func main() {
 	default:
 		logLevel.Set(slog.LevelInfo)
 	}
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
+	logOutput, err := guardian.OpenLogOutput(cfg)
+	if err != nil {
+		slog.Error("Failed to open log output", "output", cfg.LogOutput, "error", err)
+		os.Exit(1)
+	}
+	defer logOutput.Close()
+	var logWriter io.Writer = logOutput
+	switch {
+	case *top && (cfg.LogOutput == "" || cfg.LogOutput == "stdout" || cfg.LogOutput == "stderr"):
+		// 终端日志会打乱 -top 的表格
+		logWriter = io.Discard
+	case *once && (cfg.LogOutput == "" || cfg.LogOutput == "stdout"):
+		// 标准输出只留给 -once 打印的警报，便于通过管道交给 jq 等工具处理
+		logWriter = os.Stderr
+	}
+	logger := slog.New(slog.NewTextHandler(logWriter, &slog.HandlerOptions{Level: logLevel}))
 	slog.SetDefault(logger)
+	slog.Info("Effective configuration", cfg.Summary()...)
+
+	// 2. 创建所有组件
+	var opts []guardian.Option
+	if !usingDefaults {
+		opts = append(opts, guardian.WithConfigFiles(configFiles))
+	}
+	if *replay != "" {
+		opts = append(opts, guardian.WithSource(guardian.ReplayFileAtSpeed(*replay, *replaySpeed)))
+	}
+	g, err := guardian.New(cfg, opts...)
+	if err != nil {
+		slog.Error("Failed to create Traffic Guardian", "error", err)
+		os.Exit(1)
+	}
 
 	// 设置优雅退出的上下文
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 使用 WaitGroup 等待所有 goroutine 退出
-	var wg sync.WaitGroup
-
-	// 2. 创建组件
-	// 创建用于数据流转的 channels
-	trafficEventsChan := make(chan collector.TrafficEvent, 100)
-	alertsChan := make(chan alerter.Alert, 10)
-
-	// 创建状态管理器
-	stateManager := state.NewManager(logger.With("module", "state"), cfg)
-
-	// 创建规则引擎
-	ruleEngine := engine.NewEngine(logger.With("module", "engine"), cfg, stateManager, alertsChan)
-
-	// 创建并注册警报器
-	var alerters []alerter.Alerter
-	telegramAlerter := alerter.NewTelegramAlerter(logger.With("module", "alerter-telegram"), cfg.Alerter.Telegram)
-	if telegramAlerter.IsEnabled() {
-		slog.Info("Telegram alerter is enabled")
-		alerters = append(alerters, telegramAlerter)
-	} else {
-		slog.Info("Telegram alerter is disabled")
-	}
-
-	// 创建 eBPF 采集器
-	bpfCollector := collector.New(logger.With("module", "collector"), trafficEventsChan)
-
-	// 3. 启动所有组件（作为 Goroutines）
-	wg.Add(4)
-
-	// 启动状态管理器
-	go func() {
-		defer wg.Done()
-		stateManager.Start(ctx, trafficEventsChan)
-	}()
-
-	// 启动规则引擎
+	// 3. 等待退出信号，SIGUSR1 输出当前的流量快照，SIGHUP 重新打开日志文件并重新加载配置
 	go func() {
-		defer wg.Done()
-		ruleEngine.Start(ctx)
-	}()
-
-	// 启动警报处理器
-	go func() {
-		defer wg.Done()
-		slog.Info("Starting alert processor")
+		termChan := make(chan os.Signal, 1)
+		signal.Notify(termChan, syscall.SIGINT, syscall.SIGTERM)
+		usr1Chan := make(chan os.Signal, 1)
+		signal.Notify(usr1Chan, syscall.SIGUSR1)
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
 		for {
 			select {
-			case <-ctx.Done():
-				slog.Info("Alert processor stopped")
+			case <-termChan:
+				slog.Info("Shutdown signal received, gracefully shutting down...")
+				cancel()
 				return
-			case alert := <-alertsChan:
-				for _, a := range alerters {
-					if err := a.Send(ctx, alert); err != nil {
-						slog.Error("Failed to send alert", "alerter", a, "error", err)
-					}
+			case <-usr1Chan:
+				dumpSnapshot(g, *snapshotPath)
+			case <-hupChan:
+				if err := guardian.ReopenLogOutput(logOutput); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to reopen log output: %v\n", err)
 				}
+				slog.Info("Reload signal received")
+				// 失败时 Reload 已经记录了错误，原来的配置继续生效
+				_, _ = g.Reload()
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
 
-	// 启动 eBPF 采集器
-	go func() {
-		defer wg.Done()
-		if err := bpfCollector.Start(ctx); err != nil {
-			slog.Error("Failed to start eBPF collector", "error", err)
-			cancel() // 如果采集器启动失败，则取消所有操作
+	// 4. 运行直到退出
+	if *once {
+		runOnce(ctx, g, cfg, *onceDuration)
+		return
+	}
+	if *top {
+		view := newTopView(os.Stdout)
+		if err := g.Watch(ctx, time.Second, view.render); err != nil {
+			fmt.Fprintf(os.Stderr, "Traffic Guardian stopped with error: %v\n", err)
+			os.Exit(1)
 		}
-	}()
+		return
+	}
 
-	// 4. 等待退出信号
-	slog.Info("Traffic Guardian is running. Press Ctrl+C to exit.")
-	termChan := make(chan os.Signal, 1)
-	signal.Notify(termChan, syscall.SIGINT, syscall.SIGTERM)
+	slog.Info("Press Ctrl+C to exit.")
+	if err := g.Run(ctx); err != nil {
+		slog.Error("Traffic Guardian stopped with error", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Shutdown complete.")
+}
 
-	select {
-	case <-termChan:
-		slog.Info("Shutdown signal received, gracefully shutting down...")
-	case <-ctx.Done():
-		slog.Warn("Context cancelled, possibly due to a startup error.")
+// runOnce 执行单次检查模式，并将产生的警报以 JSON 行的形式打印到标准输出
+func runOnce(ctx context.Context, g *guardian.Guardian, cfg *guardian.Config, duration time.Duration) {
+	if duration <= 0 {
+		duration = cfg.Rules.GetTimeWindow()
 	}
 
-	// 触发所有 goroutine 的退出
-	cancel()
+	alerts, err := g.RunOnce(ctx, duration)
+	if err != nil {
+		slog.Error("Single evaluation failed", "error", err)
+		os.Exit(1)
+	}
 
-	// 等待所有 goroutine 完成清理工作
-	slog.Info("Waiting for all services to stop...")
-	wg.Wait()
-	slog.Info("Shutdown complete.")
+	enc := json.NewEncoder(os.Stdout)
+	for _, alert := range alerts {
+		if err := enc.Encode(alert); err != nil {
+			slog.Error("Failed to print alert", "error", err)
+		}
+	}
+	slog.Info("Single evaluation complete", "alerts", len(alerts))
 }
@@ -0,0 +1,46 @@
+// cmd/traffic-guardian/snapshot.go
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+
+	"traffic-guardian/pkg/guardian"
+)
+
+// writeSnapshot 将进程流量状态按 PID 排序后以缩进的 JSON 写入 w
+func writeSnapshot(w io.Writer, stats []guardian.ProcessStats) error {
+	sort.Slice(stats, func(i, j int) bool { return stats[i].PID < stats[j].PID })
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(stats)
+}
+
+// dumpSnapshot 在收到 SIGUSR1 时输出当前的流量快照。path 为空时写入标准错误，否则覆盖写入该文件
+func dumpSnapshot(g *guardian.Guardian, path string) {
+	stats := g.Stats()
+	if path == "" {
+		if err := writeSnapshot(os.Stderr, stats); err != nil {
+			slog.Error("Failed to write snapshot", "error", err)
+		}
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		slog.Error("Failed to create snapshot file", "path", path, "error", err)
+		return
+	}
+	err = writeSnapshot(f, stats)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		slog.Error("Failed to write snapshot", "path", path, "error", err)
+		return
+	}
+	slog.Info("Wrote snapshot", "path", path, "processes", len(stats))
+}
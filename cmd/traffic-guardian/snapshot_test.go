@@ -0,0 +1,59 @@
+// cmd/traffic-guardian/snapshot_test.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"traffic-guardian/pkg/guardian"
+)
+
+func TestWriteSnapshot(t *testing.T) {
+	seen := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	stats := []guardian.ProcessStats{
+		{PID: 300, Comm: "rsync", UID: 1000, Username: "backup", TotalBytes: 5 << 20, FirstSeen: seen, LastSeen: seen, RateBps: 1024.5},
+		{PID: 7, Comm: "sshd", TotalBytes: 42, ConnectionCount: 2},
+		{PID: 41, Comm: "curl", TotalBytes: 1000, Exited: true, ExitedAt: seen},
+	}
+
+	var buf bytes.Buffer
+	if err := writeSnapshot(&buf, stats); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("snapshot is not a JSON array: %v\n%s", err, buf.String())
+	}
+	if len(got) != 3 {
+		t.Fatalf("snapshot has %d entries, want 3", len(got))
+	}
+	// 按 PID 排序，便于比较两次快照
+	for i, pid := range []float64{7, 41, 300} {
+		if got[i]["pid"] != pid {
+			t.Errorf("entry %d has pid %v, want %v", i, got[i]["pid"], pid)
+		}
+	}
+	rsync := got[2]
+	if rsync["comm"] != "rsync" || rsync["username"] != "backup" || rsync["total_bytes"] != float64(5<<20) ||
+		rsync["rate_bps"] != 1024.5 || rsync["first_seen"] != "2024-01-01T12:00:00Z" {
+		t.Errorf("rsync entry = %v", rsync)
+	}
+	if got[1]["exited"] != true {
+		t.Errorf("curl entry = %v, want exited", got[1])
+	}
+	if !strings.Contains(buf.String(), "\n  {\n    \"pid\": 7,") {
+		t.Errorf("snapshot is not indented:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := writeSnapshot(&buf, []guardian.ProcessStats{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Errorf("empty snapshot = %q, want []", got)
+	}
+}
@@ -0,0 +1,59 @@
+// cmd/traffic-guardian/top.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"traffic-guardian/pkg/guardian"
+)
+
+// topRows 是 -top 视图中显示的进程数量
+const topRows = 20
+
+// topView 以类似 top 的表格渲染流量最大的进程，速率根据相邻两次刷新之间的字节增量计算
+type topView struct {
+	out io.Writer
+	// prev 是上一次刷新时每个进程的累计字节数，prevAt 是上一次刷新的时间
+	prev   map[uint32]uint64
+	prevAt time.Time
+}
+
+// newTopView 创建一个新的 topView 实例
+func newTopView(out io.Writer) *topView {
+	return &topView{out: out, prev: make(map[uint32]uint64)}
+}
+
+// render 清屏并重新绘制表格
+func (v *topView) render(stats []guardian.ProcessStats) {
+	now := time.Now()
+	elapsed := now.Sub(v.prevAt).Seconds()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].TotalBytes > stats[j].TotalBytes })
+
+	w := bufio.NewWriter(v.out)
+	// 光标移到左上角并清屏
+	fmt.Fprint(w, "\033[H\033[2J")
+	fmt.Fprintf(w, "traffic-guardian - %s - %d processes\n\n", now.Format(time.TimeOnly), len(stats))
+	fmt.Fprintf(w, "%8s  %-16s  %-12s  %12s  %12s\n", "PID", "COMM", "USER", "BYTES", "RATE/s")
+	for i, s := range stats {
+		if i == topRows {
+			break
+		}
+		rate := "-"
+		if prev, ok := v.prev[s.PID]; ok && elapsed > 0 && s.TotalBytes >= prev {
+			rate = guardian.FormatBytes(uint64(float64(s.TotalBytes-prev) / elapsed))
+		}
+		fmt.Fprintf(w, "%8d  %-16s  %-12s  %12s  %12s\n", s.PID, s.Comm, s.Username, guardian.FormatBytes(s.TotalBytes), rate)
+	}
+	w.Flush()
+
+	clear(v.prev)
+	for _, s := range stats {
+		v.prev[s.PID] = s.TotalBytes
+	}
+	v.prevAt = now
+}
@@ -0,0 +1,109 @@
+// internal/alerter/alert.go
+package alerter
+
+import (
+	"context"
+	"time"
+
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/state"
+)
+
+// Kind 表示警报针对的对象类型
+type Kind string
+
+const (
+	// KindProcess 表示针对单个进程的警报
+	KindProcess Kind = "process"
+	// KindUser 表示针对单个用户所有进程之和的警报
+	KindUser Kind = "user"
+	// KindHost 表示针对所有进程之和（整台主机）的警报
+	KindHost Kind = "host"
+	// KindPort 表示针对一个本端端口上所有流量之和的警报
+	KindPort Kind = "port"
+	// KindSubnet 表示针对所有进程发往一组目的网段的流量之和的警报
+	KindSubnet Kind = "subnet"
+	// KindDigest 表示定期发送的流量摘要，不对应任何规则的违规
+	KindDigest Kind = "digest"
+)
+
+// Reason 表示触发警报的规则类型
+type Reason string
+
+const (
+	// ReasonCumulativeThreshold 表示进程在时间窗口内的累计流量超过阈值
+	ReasonCumulativeThreshold Reason = "cumulative_threshold"
+	// ReasonPerUserThreshold 表示用户所有进程的累计流量之和超过阈值
+	ReasonPerUserThreshold Reason = "per_user_threshold"
+	// ReasonHostThreshold 表示所有进程的累计流量之和超过主机级别的阈值
+	ReasonHostThreshold Reason = "host_threshold"
+	// ReasonPortThreshold 表示从一个本端端口发出的累计流量之和超过阈值
+	ReasonPortThreshold Reason = "port_threshold"
+	// ReasonSubnetThreshold 表示发往一组目的网段的累计流量之和超过阈值
+	ReasonSubnetThreshold Reason = "subnet_threshold"
+	// ReasonRate 表示进程平滑后的发送速率超过阈值
+	ReasonRate Reason = "rate"
+	// ReasonAnomaly 表示进程最新的发送速率显著高于其自身的历史基线
+	ReasonAnomaly Reason = "anomaly"
+	// ReasonGrowth 表示进程最新的发送速率相对其自身的历史基线增长超过了配置的百分比
+	ReasonGrowth Reason = "growth"
+	// ReasonFanOut 表示进程在时间窗口内通信过的不同对端数量超过阈值
+	ReasonFanOut Reason = "fan_out"
+	// ReasonBlocklist 表示进程与禁止通信的地址有过通信
+	ReasonBlocklist Reason = "blocklist"
+	// ReasonProcessExit 表示进程已退出，警报是其最终流量的摘要
+	ReasonProcessExit Reason = "process_exit"
+	// ReasonDigest 表示警报是定期发送的流量最多的进程的摘要
+	ReasonDigest Reason = "digest"
+)
+
+// Alert 定义了警报事件的数据结构，是所有警报器格式化消息的唯一数据来源
+type Alert struct {
+	Kind      Kind            `json:"kind"`
+	Severity  config.Severity `json:"severity"`
+	Timestamp time.Time       `json:"timestamp"`
+	// Repeat 是规则对同一对象连续报警的次数（包括本次），大于 1 表示对象在冷却期结束后仍然超限，
+	// 达到规则的 escalate_after 时严重级别会被提升
+	Repeat int `json:"repeat,omitempty"`
+
+	// RuleName 是触发警报的规则名称，Reason 是规则类型，Detail 是对触发原因的可读描述
+	RuleName       string `json:"rule_name"`
+	Reason         Reason `json:"reason"`
+	Detail         string `json:"detail"`
+	ThresholdBytes uint64 `json:"threshold_bytes"`
+	// ThresholdRateBps 仅在速率和突增规则触发时设置（单位: 字节/秒），
+	// 对突增规则是本次样本对应的基线上限 mean + sigma×stddev，对增长规则是 mean × (1 + increase_percent/100)
+	ThresholdRateBps float64 `json:"threshold_rate_bps,omitempty"`
+	// ObservedRateBps 是规则与 ThresholdRateBps 比较的速率（单位: 字节/秒）: 速率规则为 EWMA，
+	// 配置了 smoothing_samples 时为最近几个样本的移动平均；突增和增长规则为最新的速率样本
+	ObservedRateBps float64          `json:"observed_rate_bps,omitempty"`
+	Direction       config.Direction `json:"direction"`
+	// ThresholdConnections 仅在对端数量规则触发时设置
+	ThresholdConnections int `json:"threshold_connections,omitempty"`
+
+	// ProcessStats 是触发警报的进程的完整状态；用户警报中只包含用户信息和流量之和，主机、端口和网段警报中只包含流量之和
+	ProcessStats state.ProcessStats `json:"process_stats"`
+	// UserStats 仅在 Kind 为 KindUser 时设置
+	UserStats *state.UserStats `json:"user_stats,omitempty"`
+	// PortStats 仅在 Kind 为 KindPort 时设置
+	PortStats *state.PortStats `json:"port_stats,omitempty"`
+	// SubnetStats 仅在 Kind 为 KindSubnet 时设置
+	SubnetStats *state.SubnetStats `json:"subnet_stats,omitempty"`
+	// Destinations 是进程警报中发送字节数最多的对端，blocklist 警报中是命中的对端，启用反向解析时会填充主机名
+	Destinations []state.Destination `json:"destinations,omitempty"`
+	// TopProcesses 仅在 Kind 为 KindHost 或 KindDigest 时设置，是在规则（或摘要）统计范围内发送流量最多的进程
+	TopProcesses []state.ProcessStats `json:"top_processes,omitempty"`
+
+	// Alerters 是此警报的目标警报器名称，为空时按严重级别路由
+	Alerters []string `json:"alerters,omitempty"`
+}
+
+// Alerter 是所有警报器都需要实现的接口
+type Alerter interface {
+	// Name 返回警报器的名称，用于路由配置、日志和健康检查
+	Name() string
+	Send(ctx context.Context, alert Alert) error
+	IsEnabled() bool
+	// Validate 检查配置、连通性和凭据，用于启动时的自检
+	Validate(ctx context.Context) error
+}
@@ -0,0 +1,146 @@
+// internal/alerter/exec.go
+package alerter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"traffic-guardian/internal/config"
+)
+
+// maxExecStderr 是命令失败时错误信息中保留的 stderr 字节数
+const maxExecStderr = 4096
+
+// execWaitDelay 是超时杀死命令后等待其子进程关闭输出管道的最长时间
+const execWaitDelay = time.Second
+
+// ExecAlerter 对每条警报执行一个外部命令，用于对接 ntfy 或自定义脚本等任意渠道。
+// 警报以 JSON 写入命令的 stdin，常用字段同时以 TG_* 环境变量传递
+type ExecAlerter struct {
+	log    *slog.Logger
+	cfg    config.ExecConfig
+	locale string
+}
+
+// NewExecAlerter 创建一个新的 ExecAlerter 实例，locale 是 TG_MESSAGE 使用的语言
+func NewExecAlerter(log *slog.Logger, cfg config.ExecConfig, locale string) *ExecAlerter {
+	return &ExecAlerter{log: log, cfg: cfg, locale: locale}
+}
+
+// Name 实现了 Alerter 接口的 Name 方法
+func (x *ExecAlerter) Name() string {
+	return "exec"
+}
+
+// IsEnabled 检查此警报器是否被启用
+func (x *ExecAlerter) IsEnabled() bool {
+	return x.cfg.Enabled
+}
+
+// Send 实现了 Alerter 接口的 Send 方法，命令以非零状态退出或超时视为发送失败
+func (x *ExecAlerter) Send(ctx context.Context, alert Alert) error {
+	x.log.Info("Running alert command", "command", x.cfg.Command, "rule", alert.RuleName, "pid", alert.ProcessStats.PID)
+
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, x.cfg.GetTimeout())
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, x.cfg.Command, x.cfg.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), alertEnv(alert, x.locale)...)
+	cmd.WaitDelay = execWaitDelay
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", x.cfg.GetTimeout())
+		}
+		if msg := strings.TrimSpace(truncate(stderr.String(), maxExecStderr)); msg != "" {
+			return fmt.Errorf("alert command %s failed: %w: %s", x.cfg.Command, err, msg)
+		}
+		return fmt.Errorf("alert command %s failed: %w", x.cfg.Command, err)
+	}
+
+	x.log.Info("Alert command completed", "pid", alert.ProcessStats.PID)
+	return nil
+}
+
+// Validate 检查命令是否存在且可执行
+func (x *ExecAlerter) Validate(ctx context.Context) error {
+	if x.cfg.Command == "" {
+		return fmt.Errorf("exec command is empty")
+	}
+	if _, err := exec.LookPath(x.cfg.Command); err != nil {
+		return fmt.Errorf("exec command: %w", err)
+	}
+	return nil
+}
+
+// alertField 是警报的一个结构化字段，名称同时用作环境变量名和 journal 字段名
+type alertField struct {
+	name  string
+	value string
+}
+
+// alertFields 返回警报的常用字段，不包括格式化后的消息
+func alertFields(alert Alert) []alertField {
+	s := alert.ProcessStats
+	return []alertField{
+		{"TG_KIND", string(alert.Kind)},
+		{"TG_SEVERITY", string(alert.Severity)},
+		{"TG_REPEAT", strconv.Itoa(max(alert.Repeat, 1))},
+		{"TG_TIMESTAMP", alert.Timestamp.Format(time.RFC3339)},
+		{"TG_RULE", alert.RuleName},
+		{"TG_REASON", string(alert.Reason)},
+		{"TG_DETAIL", alert.Detail},
+		{"TG_THRESHOLD_BYTES", strconv.FormatUint(alert.ThresholdBytes, 10)},
+		{"TG_PID", strconv.FormatUint(uint64(s.PID), 10)},
+		{"TG_COMM", s.Comm},
+		{"TG_UID", strconv.FormatUint(uint64(s.UID), 10)},
+		{"TG_USERNAME", s.Username},
+		{"TG_TOTAL_BYTES", strconv.FormatUint(s.TotalBytes, 10)},
+		{"TG_TOP_DESTINATION", topDestination(alert)},
+	}
+}
+
+// topDestination 返回警报中发送字节数最多的对端 (地址:端口)，没有对端信息时返回空字符串
+func topDestination(alert Alert) string {
+	if len(alert.Destinations) == 0 {
+		return ""
+	}
+	d := alert.Destinations[0]
+	return netip.AddrPortFrom(d.Addr, d.Port).String()
+}
+
+// alertEnv 返回传递给命令的环境变量，TG_MESSAGE 是内置格式的完整消息
+func alertEnv(alert Alert, locale string) []string {
+	fields := alertFields(alert)
+	env := make([]string, 0, len(fields)+1)
+	for _, f := range fields {
+		env = append(env, f.name+"="+f.value)
+	}
+	return append(env, "TG_MESSAGE="+FormatMessage(alert, locale))
+}
+
+// truncate 将字符串截断到最多 n 个字节
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
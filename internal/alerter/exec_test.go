@@ -0,0 +1,80 @@
+// internal/alerter/exec_test.go
+package alerter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/state"
+)
+
+// newTestExec 创建一个通过 /bin/sh 运行 script 的 ExecAlerter，args 作为脚本的 $1、$2 ...
+func newTestExec(script string, timeoutSeconds int, args ...string) *ExecAlerter {
+	cfg := config.ExecConfig{
+		Enabled:        true,
+		Command:        "/bin/sh",
+		Args:           append([]string{"-c", script, "sh"}, args...),
+		TimeoutSeconds: timeoutSeconds,
+	}
+	return NewExecAlerter(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, "en")
+}
+
+func TestExecAlerterPassesAlert(t *testing.T) {
+	dir := t.TempDir()
+	pidFile, payloadFile := filepath.Join(dir, "pid"), filepath.Join(dir, "alert.json")
+	x := newTestExec(`printf '%s %s' "$TG_PID" "$TG_RULE" > "$1" && cat > "$2"`, 0, pidFile, payloadFile)
+
+	alert := Alert{
+		Kind:         KindProcess,
+		RuleName:     "egress",
+		Reason:       ReasonCumulativeThreshold,
+		Timestamp:    time.Now(),
+		ProcessStats: state.ProcessStats{PID: 4242, Comm: "curl", TotalBytes: 3 << 20},
+	}
+	if err := x.Send(context.Background(), alert); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	env, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(env) != "4242 egress" {
+		t.Errorf("script saw TG_PID and TG_RULE = %q, want \"4242 egress\"", env)
+	}
+	payload, err := os.ReadFile(payloadFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Alert
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("stdin is not an alert: %v\n%s", err, payload)
+	}
+	if got.ProcessStats.PID != 4242 || got.ProcessStats.Comm != "curl" || got.RuleName != "egress" {
+		t.Errorf("stdin alert = PID %d %q rule %q, want PID 4242 curl egress", got.ProcessStats.PID, got.ProcessStats.Comm, got.RuleName)
+	}
+}
+
+func TestExecAlerterFailures(t *testing.T) {
+	err := newTestExec(`echo "token rejected" >&2; exit 3`, 0).Send(context.Background(), Alert{})
+	if err == nil || !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "token rejected") {
+		t.Errorf("failing command: err = %v, want the exit status and stderr", err)
+	}
+
+	start := time.Now()
+	err = newTestExec(`exec sleep 30`, 1).Send(context.Background(), Alert{})
+	if err == nil || !strings.Contains(err.Error(), "timed out after 1s") {
+		t.Errorf("slow command: err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("slow command returned after %s", elapsed)
+	}
+}
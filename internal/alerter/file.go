@@ -0,0 +1,86 @@
+// internal/alerter/file.go
+package alerter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/logging"
+)
+
+// FileAlerter 将每条警报以一行 JSON 追加到本地文件，作为不依赖外部服务的审计记录。
+// 文件按大小轮转，轮转后重新打开一个新文件继续写入
+type FileAlerter struct {
+	log *slog.Logger
+	cfg config.FileConfig
+
+	// f 在第一次发送或自检时打开，之后一直保持打开
+	mu sync.Mutex
+	f  *logging.RotatingFile
+}
+
+// NewFileAlerter 创建一个新的 FileAlerter 实例
+func NewFileAlerter(log *slog.Logger, cfg config.FileConfig) *FileAlerter {
+	return &FileAlerter{log: log, cfg: cfg}
+}
+
+// Name 实现了 Alerter 接口的 Name 方法
+func (a *FileAlerter) Name() string {
+	return "file"
+}
+
+// IsEnabled 检查此警报器是否被启用
+func (a *FileAlerter) IsEnabled() bool {
+	return a.cfg.Enabled
+}
+
+// Send 实现了 Alerter 接口的 Send 方法，配置了 fsync 时每条警报写入后都同步到磁盘
+func (a *FileAlerter) Send(ctx context.Context, alert Alert) error {
+	line, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := a.file()
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write alert file: %w", err)
+	}
+	if a.cfg.Fsync {
+		if err := f.Sync(); err != nil {
+			return fmt.Errorf("failed to sync alert file: %w", err)
+		}
+	}
+	a.log.Debug("Alert written to file", "path", a.cfg.Path, "rule", alert.RuleName, "pid", alert.ProcessStats.PID)
+	return nil
+}
+
+// Validate 检查警报文件能否以追加方式打开
+func (a *FileAlerter) Validate(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err := a.file()
+	return err
+}
+
+// file 返回已打开的警报文件，尚未打开时打开它，调用方需持有 a.mu
+func (a *FileAlerter) file() (*logging.RotatingFile, error) {
+	if a.f != nil {
+		return a.f, nil
+	}
+	f, err := logging.NewRotatingFile(a.cfg.Path, a.cfg.GetMaxSizeBytes(), a.cfg.GetMaxBackups())
+	if err != nil {
+		return nil, fmt.Errorf("alert file: %w", err)
+	}
+	a.f = f
+	return f, nil
+}
@@ -0,0 +1,111 @@
+// internal/alerter/file_test.go
+package alerter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/state"
+)
+
+// newTestFile 创建一个写入临时目录中 alerts.jsonl 的 FileAlerter，测试结束时关闭文件
+func newTestFile(t *testing.T, cfg config.FileConfig) *FileAlerter {
+	t.Helper()
+	cfg.Enabled = true
+	cfg.Path = filepath.Join(t.TempDir(), "alerts.jsonl")
+	a := NewFileAlerter(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+	t.Cleanup(func() {
+		if a.f != nil {
+			a.f.Close()
+		}
+	})
+	return a
+}
+
+// readAlerts 解码文件中的每一行 JSON，文件不存在时使测试失败
+func readAlerts(t *testing.T, path string) []Alert {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var alerts []Alert
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 4<<20)
+	for scanner.Scan() {
+		var alert Alert
+		if err := json.Unmarshal(scanner.Bytes(), &alert); err != nil {
+			t.Fatalf("line %d is not a JSON alert: %v", len(alerts)+1, err)
+		}
+		alerts = append(alerts, alert)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return alerts
+}
+
+func TestFileAlerterWritesJSONLines(t *testing.T) {
+	a := newTestFile(t, config.FileConfig{Fsync: true})
+	if err := a.Validate(context.Background()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	rules := []string{"egress", "rate", "scan"}
+	for i, name := range rules {
+		alert := Alert{Kind: KindProcess, RuleName: name, Reason: ReasonCumulativeThreshold, ProcessStats: state.ProcessStats{PID: uint32(100 + i), Comm: "curl"}}
+		if err := a.Send(context.Background(), alert); err != nil {
+			t.Fatalf("Send %s: %v", name, err)
+		}
+	}
+
+	got := readAlerts(t, a.cfg.Path)
+	if len(got) != len(rules) {
+		t.Fatalf("file has %d alerts, want %d", len(got), len(rules))
+	}
+	for i, alert := range got {
+		if alert.RuleName != rules[i] || alert.ProcessStats.PID != uint32(100+i) {
+			t.Errorf("line %d: rule = %q, pid = %d; want %q, %d", i+1, alert.RuleName, alert.ProcessStats.PID, rules[i], 100+i)
+		}
+	}
+}
+
+func TestFileAlerterRotates(t *testing.T) {
+	a := newTestFile(t, config.FileConfig{MaxSizeMB: 1, MaxBackups: 1})
+
+	// 每条警报约 300KB，第 4 条写入前超过 1MB，第 7 条写入前再次轮转并删除最旧的备份
+	detail := strings.Repeat("x", 300<<10)
+	for i := range 7 {
+		alert := Alert{Kind: KindProcess, RuleName: "egress", Detail: detail, ProcessStats: state.ProcessStats{PID: uint32(100 + i)}}
+		if err := a.Send(context.Background(), alert); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+
+	pids := func(alerts []Alert) []uint32 {
+		var pids []uint32
+		for _, alert := range alerts {
+			pids = append(pids, alert.ProcessStats.PID)
+		}
+		return pids
+	}
+	if got := pids(readAlerts(t, a.cfg.Path)); len(got) != 1 || got[0] != 106 {
+		t.Errorf("current file has alerts for PIDs %v, want [106]", got)
+	}
+	if got := pids(readAlerts(t, a.cfg.Path+".1")); len(got) != 3 || got[0] != 103 {
+		t.Errorf("backup has alerts for PIDs %v, want [103 104 105]", got)
+	}
+	if _, err := os.Stat(a.cfg.Path + ".2"); !os.IsNotExist(err) {
+		t.Errorf("backup beyond max_backups exists (stat error %v)", err)
+	}
+}
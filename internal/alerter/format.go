@@ -0,0 +1,108 @@
+// internal/alerter/format.go
+package alerter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FormatMessage 将警报按指定语言（见 config.Locale*）格式化为 Markdown 文本，供所有基于文本的警报器使用。
+// 只翻译消息中的标签，Detail 和规则名等数据原样输出
+func FormatMessage(alert Alert, locale string) string {
+	c := catalogFor(locale)
+	var b strings.Builder
+	fmt.Fprintf(&b, "🚨 **%s** 🚨\n\n", c.text("title"))
+
+	switch alert.Kind {
+	case KindUser:
+		fmt.Fprintf(&b, "**%s:** `%s` (UID `%d`)\n", c.text("user"), alert.UserStats.Username, alert.UserStats.UID)
+		fmt.Fprintf(&b, "**%s:** `%d`\n", c.text("processes"), alert.UserStats.ProcessCount)
+	case KindHost, KindDigest:
+		fmt.Fprintf(&b, "**%s:** `%s`\n", c.text("scope"), c.text("all_processes"))
+	case KindPort:
+		fmt.Fprintf(&b, "**%s:** `%d`\n", c.text("local_port"), alert.PortStats.Port)
+		fmt.Fprintf(&b, "**%s:** `%d`\n", c.text("processes"), alert.PortStats.ProcessCount)
+	case KindSubnet:
+		fmt.Fprintf(&b, "**%s:** `%s`\n", c.text("destination_cidrs"), strings.Join(alert.SubnetStats.CIDRs, ", "))
+		fmt.Fprintf(&b, "**%s:** `%d`\n", c.text("processes"), alert.SubnetStats.ProcessCount)
+	default:
+		fmt.Fprintf(&b, "**%s:** `%s` (PID `%d`)\n", c.text("process"), alert.ProcessStats.Comm, alert.ProcessStats.PID)
+		fmt.Fprintf(&b, "**%s:** `%s` (UID `%d`)\n", c.text("user"), alert.ProcessStats.Username, alert.ProcessStats.UID)
+	}
+
+	fmt.Fprintf(&b, "**%s:** `%.2f MB`\n", c.text("traffic_used"), toMB(alert.ProcessStats.TotalBytes))
+	if alert.ProcessStats.AvgPacketBytes > 0 {
+		fmt.Fprintf(&b, "**%s:** `%.0f B` (`%d` %s)\n", c.text("avg_packet_size"), alert.ProcessStats.AvgPacketBytes, alert.ProcessStats.TotalPackets, c.text("packets"))
+	}
+	if alert.ProcessStats.InternalBytes > 0 {
+		fmt.Fprintf(&b, "**%s:** `%.2f MB` / `%.2f MB`\n", c.text("external_split"), toMB(alert.ProcessStats.ExternalBytes), toMB(alert.ProcessStats.InternalBytes))
+	}
+	fmt.Fprintf(&b, "**%s:** `%s` (`%s`, `%s`)\n", c.text("rule"), alert.RuleName, alert.Direction, alert.Severity)
+	fmt.Fprintf(&b, "**%s:** `%s`\n", c.text("reason"), alert.Reason)
+	if alert.Repeat > 1 {
+		fmt.Fprintf(&b, "**%s:** `%d`\n", c.text("repeat"), alert.Repeat)
+	}
+	switch alert.Reason {
+	case ReasonRate:
+		fmt.Fprintf(&b, "**%s:** `%.2f KB/s`\n", c.text("rate_smoothed"), alert.ObservedRateBps/1024)
+		fmt.Fprintf(&b, "**%s:** `%.2f KB/s`\n", c.text("threshold"), alert.ThresholdRateBps/1024)
+	case ReasonAnomaly, ReasonGrowth:
+		fmt.Fprintf(&b, "**%s:** `%.2f KB/s`\n", c.text("rate"), alert.ObservedRateBps/1024)
+		fmt.Fprintf(&b, "**%s:** `%.2f KB/s` (± `%.2f KB/s`)\n", c.text("baseline"), alert.ProcessStats.BaselineRateBps/1024, alert.ProcessStats.BaselineStdDevBps/1024)
+		fmt.Fprintf(&b, "**%s:** `%.2f KB/s`\n", c.text("threshold"), alert.ThresholdRateBps/1024)
+	case ReasonFanOut:
+		fmt.Fprintf(&b, "**%s:** `%d`\n", c.text("remote_endpoints"), alert.ProcessStats.ConnectionCount)
+		fmt.Fprintf(&b, "**%s:** `%d`\n", c.text("threshold"), alert.ThresholdConnections)
+	case ReasonDigest, ReasonBlocklist:
+		// 摘要和禁止通信地址的警报没有阈值
+	case ReasonProcessExit:
+		fmt.Fprintf(&b, "**%s:** `%s`\n", c.text("lifetime"), alert.ProcessStats.ExitedAt.Sub(alert.ProcessStats.FirstSeen).Round(time.Second))
+	default:
+		fmt.Fprintf(&b, "**%s:** `%.2f MB`\n", c.text("threshold"), toMB(alert.ThresholdBytes))
+	}
+	if len(alert.TopProcesses) > 0 {
+		fmt.Fprintf(&b, "**%s:**\n", c.text("top_processes"))
+		for _, p := range alert.TopProcesses {
+			fmt.Fprintf(&b, "  • `%s` (PID `%d`) `%.2f MB`\n", p.Comm, p.PID, toMB(p.TotalBytes))
+		}
+	}
+	if len(alert.Destinations) > 0 {
+		heading := "top_destinations"
+		if alert.Reason == ReasonBlocklist {
+			heading = "blocked_destinations"
+		}
+		fmt.Fprintf(&b, "**%s:**\n", c.text(heading))
+		for _, d := range alert.Destinations {
+			host := d.Addr.String()
+			if d.Hostname != "" {
+				host = d.Hostname + " (" + host + ")"
+			}
+			fmt.Fprintf(&b, "  • `%s:%d` `%.2f MB`\n", host, d.Port, toMB(d.Bytes))
+		}
+	}
+	fmt.Fprintf(&b, "**%s:** `%s`\n\n", c.text("time"), alert.Timestamp.Format(time.RFC1123))
+	b.WriteString(alert.Detail)
+
+	return b.String()
+}
+
+// toMB 将字节数转换为 MB
+func toMB(bytes uint64) float64 {
+	return float64(bytes) / (1024 * 1024)
+}
+
+// FormatBytes 将字节数格式化为带单位（1024 进制）的可读字符串，例如 "1.50 MB"。
+// 警报消息、消息模板的 humanizeBytes 和命令行的 -top 视图都使用它，同一流量在各处显示一致
+func FormatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,49 @@
+// internal/alerter/history.go
+package alerter
+
+import "sync"
+
+// DefaultHistorySize 是未配置 history_size 时保留的警报数量
+const DefaultHistorySize = 100
+
+// History 是一个固定容量的环形缓冲区，保存最近的 N 条警报
+type History struct {
+	mu     sync.RWMutex
+	alerts []Alert
+	next   int
+	full   bool
+}
+
+// NewHistory 创建一个容量为 size 的警报历史记录
+func NewHistory(size int) *History {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &History{alerts: make([]Alert, size)}
+}
+
+// Add 记录一条警报，缓冲区已满时覆盖最旧的一条
+func (h *History) Add(alert Alert) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.alerts[h.next] = alert
+	h.next = (h.next + 1) % len(h.alerts)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// List 按时间顺序（从旧到新）返回所有记录的警报副本
+func (h *History) List() []Alert {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.full {
+		return append([]Alert(nil), h.alerts[:h.next]...)
+	}
+	list := make([]Alert, 0, len(h.alerts))
+	list = append(list, h.alerts[h.next:]...)
+	list = append(list, h.alerts[:h.next]...)
+	return list
+}
@@ -0,0 +1,42 @@
+// internal/alerter/history_test.go
+package alerter
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHistoryKeepsMostRecent(t *testing.T) {
+	const size = 3
+	h := NewHistory(size)
+	if got := h.List(); len(got) != 0 {
+		t.Fatalf("empty history returned %d alerts", len(got))
+	}
+
+	for i := 1; i <= 7; i++ {
+		h.Add(Alert{RuleName: fmt.Sprintf("rule-%d", i)})
+		got := h.List()
+		want := min(i, size)
+		if len(got) != want {
+			t.Fatalf("after %d alerts: got %d, want %d", i, len(got), want)
+		}
+		// 从旧到新排列，最后一条总是刚加入的警报
+		for j, a := range got {
+			if name := fmt.Sprintf("rule-%d", i-want+1+j); a.RuleName != name {
+				t.Fatalf("after %d alerts: alerts[%d] = %s, want %s", i, j, a.RuleName, name)
+			}
+		}
+	}
+}
+
+func TestHistoryListIsCopy(t *testing.T) {
+	h := NewHistory(2)
+	h.Add(Alert{RuleName: "a"})
+	h.Add(Alert{RuleName: "b"})
+
+	list := h.List()
+	list[0].RuleName = "changed"
+	if got := h.List()[0].RuleName; got != "a" {
+		t.Errorf("modifying the returned list changed the history: %s", got)
+	}
+}
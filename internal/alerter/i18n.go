@@ -0,0 +1,88 @@
+// internal/alerter/i18n.go
+package alerter
+
+import "traffic-guardian/internal/config"
+
+// catalog 将消息中的文本键映射为某种语言的文本
+type catalog map[string]string
+
+// catalogs 是每种语言的消息目录，键与 config.Locale* 一致。
+// 英文目录必须包含所有键，其他语言缺少的键回退到英文
+var catalogs = map[string]catalog{
+	config.LocaleEnglish: {
+		"title":                "Traffic Alert",
+		"user":                 "User",
+		"processes":            "Processes",
+		"process":              "Process",
+		"traffic_used":         "Traffic Used",
+		"external_split":       "External / Internal",
+		"avg_packet_size":      "Avg Packet Size",
+		"packets":              "packets",
+		"rule":                 "Rule",
+		"reason":               "Reason",
+		"repeat":               "Consecutive Alerts",
+		"rate_smoothed":        "Rate (smoothed)",
+		"rate":                 "Rate",
+		"baseline":             "Baseline",
+		"threshold":            "Threshold",
+		"remote_endpoints":     "Remote Endpoints",
+		"lifetime":             "Lifetime",
+		"top_destinations":     "Top Destinations",
+		"blocked_destinations": "Blocklisted Destinations",
+		"scope":                "Scope",
+		"all_processes":        "all processes",
+		"top_processes":        "Top Processes",
+		"local_port":           "Local Port",
+		"destination_cidrs":    "Destination Networks",
+		"time":                 "Time",
+		"ack":                  "Ack",
+		"acknowledged":         "Acknowledged for",
+		"ack_failed":           "Rule no longer exists",
+	},
+	config.LocaleChinese: {
+		"title":                "流量警报",
+		"user":                 "用户",
+		"processes":            "进程数",
+		"process":              "进程",
+		"traffic_used":         "已用流量",
+		"external_split":       "外部 / 内部",
+		"avg_packet_size":      "平均包长",
+		"packets":              "个数据包",
+		"rule":                 "规则",
+		"reason":               "原因",
+		"repeat":               "连续报警次数",
+		"rate_smoothed":        "速率（平滑）",
+		"rate":                 "速率",
+		"baseline":             "基线",
+		"threshold":            "阈值",
+		"remote_endpoints":     "对端数量",
+		"lifetime":             "存活时间",
+		"top_destinations":     "主要目的地",
+		"blocked_destinations": "命中黑名单的对端",
+		"scope":                "范围",
+		"all_processes":        "所有进程",
+		"top_processes":        "流量最多的进程",
+		"local_port":           "本地端口",
+		"destination_cidrs":    "目的网段",
+		"time":                 "时间",
+		"ack":                  "确认",
+		"acknowledged":         "已确认，静默",
+		"ack_failed":           "规则已不存在",
+	},
+}
+
+// catalogFor 返回指定语言的消息目录，未知的语言使用英文
+func catalogFor(locale string) catalog {
+	if c, ok := catalogs[locale]; ok {
+		return c
+	}
+	return catalogs[config.LocaleEnglish]
+}
+
+// text 返回键对应的文本，当前语言缺少该键时回退到英文
+func (c catalog) text(key string) string {
+	if s, ok := c[key]; ok {
+		return s
+	}
+	return catalogs[config.LocaleEnglish][key]
+}
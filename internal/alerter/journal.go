@@ -0,0 +1,105 @@
+// internal/alerter/journal.go
+package alerter
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+
+	"traffic-guardian/internal/config"
+)
+
+// JournalAlerter 通过 journald 的原生协议将警报写入 systemd journal。
+// 警报的各个字段以结构化的 TG_* 字段写入，可以用 journalctl TG_RULE=... 等方式查询
+type JournalAlerter struct {
+	log    *slog.Logger
+	cfg    config.JournalConfig
+	locale string
+}
+
+// NewJournalAlerter 创建一个新的 JournalAlerter 实例，locale 是 MESSAGE 字段使用的语言
+func NewJournalAlerter(log *slog.Logger, cfg config.JournalConfig, locale string) *JournalAlerter {
+	return &JournalAlerter{log: log, cfg: cfg, locale: locale}
+}
+
+// Name 实现了 Alerter 接口的 Name 方法
+func (j *JournalAlerter) Name() string {
+	return "journal"
+}
+
+// IsEnabled 检查此警报器是否被启用
+func (j *JournalAlerter) IsEnabled() bool {
+	return j.cfg.Enabled
+}
+
+// Send 实现了 Alerter 接口的 Send 方法，每条警报作为一个数据报发送给 journald
+func (j *JournalAlerter) Send(ctx context.Context, alert Alert) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unixgram", j.cfg.GetSocketPath())
+	if err != nil {
+		return fmt.Errorf("failed to connect to journald: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+
+	if _, err := conn.Write(j.entry(alert)); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	j.log.Debug("Alert written to journal", "rule", alert.RuleName, "pid", alert.ProcessStats.PID)
+	return nil
+}
+
+// Validate 检查 journald 的套接字是否存在
+func (j *JournalAlerter) Validate(ctx context.Context) error {
+	if _, err := os.Stat(j.cfg.GetSocketPath()); err != nil {
+		return fmt.Errorf("journald socket: %w", err)
+	}
+	return nil
+}
+
+// entry 按 journald 原生协议编码警报: 每个字段一行 "KEY=value"，
+// 值包含换行时改为 "KEY\n" + 小端序 uint64 长度 + 值 + "\n"
+func (j *JournalAlerter) entry(alert Alert) []byte {
+	var buf bytes.Buffer
+	writeField := func(name, value string) {
+		if !strings.Contains(value, "\n") {
+			buf.WriteString(name)
+			buf.WriteByte('=')
+			buf.WriteString(value)
+			buf.WriteByte('\n')
+			return
+		}
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+		binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+
+	writeField("MESSAGE", FormatMessage(alert, j.locale))
+	writeField("PRIORITY", journalPriority(alert.Severity))
+	writeField("SYSLOG_IDENTIFIER", j.cfg.GetIdentifier())
+	for _, f := range alertFields(alert) {
+		writeField(f.name, f.value)
+	}
+	return buf.Bytes()
+}
+
+// journalPriority 将严重级别映射为 syslog 优先级: critical 为 crit, warning 为 warning, 其他为 info
+func journalPriority(severity config.Severity) string {
+	switch severity {
+	case config.SeverityCritical:
+		return "2"
+	case config.SeverityWarning:
+		return "4"
+	default:
+		return "6"
+	}
+}
@@ -0,0 +1,76 @@
+// internal/alerter/resolver.go
+package alerter
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"traffic-guardian/internal/config"
+)
+
+// LookupAddrFunc 将 IP 地址反向解析为主机名，与 net.Resolver.LookupAddr 的签名一致
+type LookupAddrFunc func(ctx context.Context, addr string) ([]string, error)
+
+// resolvedName 是一条缓存的反向解析结果，解析失败时 name 为空
+type resolvedName struct {
+	name    string
+	expires time.Time
+}
+
+// Resolver 在发送警报前将对端 IP 反向解析为主机名，结果（包括失败）会被缓存
+type Resolver struct {
+	lookup  LookupAddrFunc
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu    sync.Mutex
+	cache map[netip.Addr]resolvedName
+}
+
+// NewResolver 创建一个使用系统 DNS 配置的 Resolver，lookup 为 nil 时使用 net.DefaultResolver
+func NewResolver(cfg config.ReverseDNS, lookup LookupAddrFunc) *Resolver {
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupAddr
+	}
+	return &Resolver{
+		lookup:  lookup,
+		timeout: cfg.GetTimeout(),
+		ttl:     cfg.GetCacheTTL(),
+		cache:   make(map[netip.Addr]resolvedName),
+	}
+}
+
+// Annotate 为警报中的每个对端填充主机名，解析失败或超时的对端保留 IP
+func (r *Resolver) Annotate(ctx context.Context, alert *Alert) {
+	for i := range alert.Destinations {
+		alert.Destinations[i].Hostname = r.resolve(ctx, alert.Destinations[i].Addr)
+	}
+}
+
+// resolve 返回地址的主机名，优先使用缓存
+func (r *Resolver) resolve(ctx context.Context, addr netip.Addr) string {
+	now := time.Now()
+	r.mu.Lock()
+	cached, ok := r.cache[addr]
+	r.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.name
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	var name string
+	if names, err := r.lookup(ctx, addr.String()); err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	}
+
+	r.mu.Lock()
+	r.cache[addr] = resolvedName{name: name, expires: now.Add(r.ttl)}
+	r.mu.Unlock()
+	return name
+}
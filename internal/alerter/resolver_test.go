@@ -0,0 +1,73 @@
+// internal/alerter/resolver_test.go
+package alerter
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/state"
+)
+
+func TestResolverAnnotate(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		lookups = make(map[string]int)
+	)
+	lookup := func(ctx context.Context, addr string) ([]string, error) {
+		mu.Lock()
+		lookups[addr]++
+		mu.Unlock()
+		switch addr {
+		case "198.51.100.1":
+			return []string{"mirror.example.com.", "alias.example.com."}, nil
+		case "198.51.100.2":
+			return nil, errors.New("no PTR record")
+		case "198.51.100.3":
+			// 解析超时
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, nil
+	}
+	r := NewResolver(config.ReverseDNS{Enabled: true, TimeoutMS: 20}, lookup)
+
+	alert := Alert{
+		Kind: KindProcess,
+		Destinations: []state.Destination{
+			{Addr: netip.MustParseAddr("198.51.100.1"), Port: 443, Bytes: 3 << 20},
+			{Addr: netip.MustParseAddr("198.51.100.2"), Port: 443, Bytes: 2 << 20},
+			{Addr: netip.MustParseAddr("198.51.100.3"), Port: 443, Bytes: 1 << 20},
+		},
+	}
+	r.Annotate(context.Background(), &alert)
+	want := []string{"mirror.example.com", "", ""}
+	for i, d := range alert.Destinations {
+		if d.Hostname != want[i] {
+			t.Errorf("Destinations[%d].Hostname = %q, want %q", i, d.Hostname, want[i])
+		}
+	}
+
+	message := FormatMessage(alert, "en")
+	for _, s := range []string{"mirror.example.com (198.51.100.1):443", "`198.51.100.2:443`", "`198.51.100.3:443`"} {
+		if !strings.Contains(message, s) {
+			t.Errorf("message does not contain %q:\n%s", s, message)
+		}
+	}
+
+	// 成功和失败的结果都被缓存
+	again := Alert{Destinations: append([]state.Destination(nil), alert.Destinations...)}
+	r.Annotate(context.Background(), &again)
+	for addr, n := range lookups {
+		if n != 1 {
+			t.Errorf("looked up %s %d times, want once", addr, n)
+		}
+	}
+	if again.Destinations[0].Hostname != "mirror.example.com" {
+		t.Errorf("cached Hostname = %q", again.Destinations[0].Hostname)
+	}
+}
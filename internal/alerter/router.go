@@ -0,0 +1,138 @@
+// internal/alerter/router.go
+package alerter
+
+import (
+	"sync"
+	"time"
+
+	"traffic-guardian/internal/config"
+)
+
+// Router 根据警报指定的目标或严重级别选择接收警报的警报器
+type Router struct {
+	names    []string
+	alerters map[string]Alerter
+	routes   map[config.Severity][]string
+
+	// status 记录每个警报器最近一次发送的结果
+	mu     sync.Mutex
+	status map[string]*Status
+}
+
+// Target 是一个带名称的警报器
+type Target struct {
+	Name string
+	Alerter
+}
+
+// Status 是一个警报器最近的发送结果，用于健康检查
+type Status struct {
+	Name        string    `json:"name"`
+	LastSuccess time.Time `json:"last_success"`
+	LastFailure time.Time `json:"last_failure"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// NewRouter 创建一个新的 Router，routes 未配置的严重级别会发送给所有已注册的警报器
+func NewRouter(routes map[config.Severity][]string) *Router {
+	return &Router{
+		alerters: make(map[string]Alerter),
+		routes:   routes,
+		status:   make(map[string]*Status),
+	}
+}
+
+// Register 以警报器的名称注册一个警报器，同名的警报器会被替换
+func (r *Router) Register(a Alerter) {
+	name := a.Name()
+	if _, ok := r.alerters[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.alerters[name] = a
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status[name] = &Status{Name: name}
+}
+
+// RecordResult 记录一次发送的结果
+func (r *Router) RecordResult(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, ok := r.status[name]
+	if !ok {
+		return
+	}
+	if err != nil {
+		st.LastFailure = time.Now()
+		st.LastError = err.Error()
+		return
+	}
+	st.LastSuccess = time.Now()
+}
+
+// Statuses 按注册顺序返回每个警报器最近的发送结果
+func (r *Router) Statuses() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	statuses := make([]Status, 0, len(r.names))
+	for _, name := range r.names {
+		statuses = append(statuses, *r.status[name])
+	}
+	return statuses
+}
+
+// Each 按注册顺序对每个已注册的警报器调用 fn
+func (r *Router) Each(fn func(name string, a Alerter)) {
+	for _, name := range r.names {
+		fn(name, r.alerters[name])
+	}
+}
+
+// Has 检查指定名称的警报器是否已注册
+func (r *Router) Has(name string) bool {
+	_, ok := r.alerters[name]
+	return ok
+}
+
+// Len 返回已注册的警报器数量
+func (r *Router) Len() int {
+	return len(r.alerters)
+}
+
+// UnknownRoutes 返回路由配置中引用了但没有注册（未启用或不存在）的警报器名称
+func (r *Router) UnknownRoutes() []string {
+	var unknown []string
+	seen := make(map[string]bool)
+	for _, names := range r.routes {
+		for _, name := range names {
+			if _, ok := r.alerters[name]; !ok && !seen[name] {
+				seen[name] = true
+				unknown = append(unknown, name)
+			}
+		}
+	}
+	return unknown
+}
+
+// Targets 返回应该接收此警报的警报器。警报指定了目标时只发送给这些警报器，
+// 否则按严重级别路由，严重级别没有配置路由时发送给所有已注册的警报器
+func (r *Router) Targets(alert Alert) []Target {
+	names := alert.Alerters
+	if len(names) == 0 {
+		var ok bool
+		if names, ok = r.routes[alert.Severity]; !ok {
+			names = r.names
+		}
+	}
+
+	targets := make([]Target, 0, len(names))
+	for _, name := range names {
+		if a, ok := r.alerters[name]; ok {
+			targets = append(targets, Target{Name: name, Alerter: a})
+		}
+	}
+	return targets
+}
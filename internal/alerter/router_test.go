@@ -0,0 +1,72 @@
+// internal/alerter/router_test.go
+package alerter
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"traffic-guardian/internal/config"
+)
+
+// fakeAlerter 记录收到的警报，Send 和 Validate 返回 err
+type fakeAlerter struct {
+	name string
+	err  error
+	sent []Alert
+}
+
+func (f *fakeAlerter) Name() string                   { return f.name }
+func (f *fakeAlerter) IsEnabled() bool                { return true }
+func (f *fakeAlerter) Validate(context.Context) error { return f.err }
+
+func (f *fakeAlerter) Send(_ context.Context, alert Alert) error {
+	f.sent = append(f.sent, alert)
+	return f.err
+}
+
+// targetNames 返回路由结果中的警报器名称
+func targetNames(targets []Target) []string {
+	names := make([]string, len(targets))
+	for i, t := range targets {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func TestRouterTargetsBySeverity(t *testing.T) {
+	r := NewRouter(map[config.Severity][]string{
+		config.SeverityCritical: {"pager", "chat"},
+		config.SeverityWarning:  {"chat"},
+	})
+	for _, name := range []string{"chat", "pager", "log"} {
+		r.Register(&fakeAlerter{name: name})
+	}
+
+	tests := []struct {
+		name  string
+		alert Alert
+		want  []string
+	}{
+		{"critical goes only to its routes", Alert{Severity: config.SeverityCritical}, []string{"pager", "chat"}},
+		{"warning goes only to its routes", Alert{Severity: config.SeverityWarning}, []string{"chat"}},
+		{"unrouted severity goes to every alerter", Alert{Severity: config.SeverityInfo}, []string{"chat", "pager", "log"}},
+		{"rule alerters override routing", Alert{Severity: config.SeverityCritical, Alerters: []string{"log"}}, []string{"log"}},
+		{"unknown alerters are skipped", Alert{Severity: config.SeverityCritical, Alerters: []string{"email", "log"}}, []string{"log"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := targetNames(r.Targets(tt.alert)); !slices.Equal(got, tt.want) {
+				t.Errorf("Targets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterUnknownRoutes(t *testing.T) {
+	r := NewRouter(map[config.Severity][]string{config.SeverityCritical: {"pager", "email"}})
+	r.Register(&fakeAlerter{name: "pager"})
+	if got := r.UnknownRoutes(); !slices.Equal(got, []string{"email"}) {
+		t.Errorf("UnknownRoutes = %v, want [email]", got)
+	}
+}
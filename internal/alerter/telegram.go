@@ -5,41 +5,56 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"time"
+	"net/url"
+	"regexp"
+	"strings"
 
 	"traffic-guardian/internal/config"
-	"traffic-guardian/internal/state"
 )
 
-// Alert 定义了警报事件的数据结构
-type Alert struct {
-	ProcessStats state.ProcessStats
-	Timestamp    time.Time
-}
+// telegramAPIBase 是 Telegram Bot API 的地址
+const telegramAPIBase = "https://api.telegram.org"
 
-// Alerter 是所有警报器都需要实现的接口
-type Alerter interface {
-	Send(ctx context.Context, alert Alert) error
-	IsEnabled() bool
-}
+// telegramTokenPattern 是 Bot Token 的格式: <bot id>:<secret>
+var telegramTokenPattern = regexp.MustCompile(`^[0-9]+:[A-Za-z0-9_-]+$`)
 
 // TelegramAlerter 通过 Telegram Bot 发送警报
 type TelegramAlerter struct {
 	log    *slog.Logger
 	cfg    config.TelegramConfig
 	client *http.Client
+	// tmpl 是自定义的消息模板，为 nil 时使用 FormatMessage；tmplErr 是模板解析错误
+	tmpl    *MessageTemplate
+	tmplErr error
+	// locale 是内置格式使用的语言
+	locale string
 }
 
-// NewTelegramAlerter 创建一个新的 TelegramAlerter 实例
-func NewTelegramAlerter(log *slog.Logger, cfg config.TelegramConfig) *TelegramAlerter {
-	return &TelegramAlerter{
+// NewTelegramAlerter 创建一个新的 TelegramAlerter 实例，timezone 用于消息模板中的时间格式化，
+// locale 是内置格式使用的语言。消息模板无效时记录错误并使用内置格式
+func NewTelegramAlerter(log *slog.Logger, cfg config.TelegramConfig, timezone, locale string) *TelegramAlerter {
+	t := &TelegramAlerter{
 		log:    log,
 		cfg:    cfg,
-		client: &http.Client{Timeout: 10 * time.Second},
+		client: &http.Client{},
+		locale: locale,
+	}
+	if cfg.MessageTemplate != "" {
+		t.tmpl, t.tmplErr = NewMessageTemplate("telegram", cfg.MessageTemplate, timezone)
+		if t.tmplErr != nil {
+			log.Error("Invalid telegram message template, using built-in format", "error", t.tmplErr)
+		}
 	}
+	return t
+}
+
+// Name 实现了 Alerter 接口的 Name 方法
+func (t *TelegramAlerter) Name() string {
+	return "telegram"
 }
 
 // IsEnabled 检查此警报器是否被启用
@@ -49,33 +64,31 @@ func (t *TelegramAlerter) IsEnabled() bool {
 
 // Send 实现了 Alerter 接口的 Send 方法
 func (t *TelegramAlerter) Send(ctx context.Context, alert Alert) error {
-	t.log.Info("Sending alert to Telegram", "pid", alert.ProcessStats.PID)
+	t.log.Info("Sending alert to Telegram", "rule", alert.RuleName, "pid", alert.ProcessStats.PID, "uid", alert.ProcessStats.UID)
+
+	// 发送超时由派生的上下文控制，http.Client 本身不设超时
+	ctx, cancel := context.WithTimeout(ctx, t.cfg.GetTimeout())
+	defer cancel()
 
 	// 格式化消息内容
-	message := fmt.Sprintf(
-		"🚨 **Traffic Alert** 🚨\n\n"+
-			"**Process ID:** `%d`\n"+
-			"**Traffic Used:** `%.2f MB`\n"+
-			"**Time:** `%s`\n\n"+
-			"The process has exceeded the configured traffic limit.",
-		alert.ProcessStats.PID,
-		float64(alert.ProcessStats.TotalBytes)/(1024*1024),
-		alert.Timestamp.Format(time.RFC1123),
-	)
+	message := t.format(alert)
 
 	// 构建 API 请求
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.cfg.BotToken)
-	payload := map[string]string{
+	endpoint := t.methodURL("sendMessage")
+	payload := map[string]any{
 		"chat_id":    t.cfg.ChatID,
 		"text":       message,
 		"parse_mode": "Markdown",
 	}
+	if keyboard := t.ackKeyboard(alert); keyboard != nil {
+		payload["reply_markup"] = keyboard
+	}
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal telegram payload: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		return fmt.Errorf("failed to create telegram request: %w", err)
 	}
@@ -84,7 +97,7 @@ func (t *TelegramAlerter) Send(ctx context.Context, alert Alert) error {
 	// 发送请求
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send telegram message: %w", err)
+		return fmt.Errorf("failed to send telegram message: %w", t.requestError(err))
 	}
 	defer resp.Body.Close()
 
@@ -95,3 +108,75 @@ func (t *TelegramAlerter) Send(ctx context.Context, alert Alert) error {
 	t.log.Info("Alert sent successfully", "pid", alert.ProcessStats.PID)
 	return nil
 }
+
+// Validate 检查 Bot Token 的格式，并通过 getChat 接口确认 Token 和 Chat ID 都有效
+func (t *TelegramAlerter) Validate(ctx context.Context) error {
+	if t.tmplErr != nil {
+		return fmt.Errorf("telegram message_template: %w", t.tmplErr)
+	}
+	if !telegramTokenPattern.MatchString(t.cfg.BotToken) {
+		return fmt.Errorf("telegram bot_token is malformed, expected <bot id>:<secret>")
+	}
+	if t.cfg.ChatID == "" {
+		return fmt.Errorf("telegram chat_id is empty")
+	}
+
+	jsonPayload, err := json.Marshal(map[string]string{"chat_id": t.cfg.ChatID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal telegram payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", t.methodURL("getChat"), bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return fmt.Errorf("failed to create telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach telegram API: %w", t.requestError(err))
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusNotFound:
+		return fmt.Errorf("telegram rejected bot_token: %s", resp.Status)
+	case http.StatusBadRequest, http.StatusForbidden:
+		return fmt.Errorf("telegram rejected chat_id %q: %s", t.cfg.ChatID, resp.Status)
+	default:
+		return fmt.Errorf("telegram API returned non-200 status: %s", resp.Status)
+	}
+}
+
+// requestError 去掉 http.Client 返回的 *url.Error 中的请求地址，只保留底层错误。
+// 地址中包含 Bot Token，错误会被写入日志并通过 /healthz 返回，不能带有凭据
+func (t *TelegramAlerter) requestError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	// 底层错误（例如重定向失败）仍可能引用地址
+	if t.cfg.BotToken != "" && strings.Contains(err.Error(), t.cfg.BotToken) {
+		return errors.New(strings.ReplaceAll(err.Error(), t.cfg.BotToken, "<redacted>"))
+	}
+	return err
+}
+
+// format 使用自定义模板格式化警报，未配置模板或执行失败时使用内置格式
+func (t *TelegramAlerter) format(alert Alert) string {
+	if t.tmpl == nil {
+		return FormatMessage(alert, t.locale)
+	}
+	message, err := t.tmpl.Render(alert)
+	if err != nil {
+		t.log.Error("Failed to render telegram message template, using built-in format", "error", err)
+		return FormatMessage(alert, t.locale)
+	}
+	return message
+}
+
+// methodURL 返回 Bot API 方法的完整地址
+func (t *TelegramAlerter) methodURL(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", telegramAPIBase, t.cfg.BotToken, method)
+}
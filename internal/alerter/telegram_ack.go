@@ -0,0 +1,248 @@
+// internal/alerter/telegram_ack.go
+package alerter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ackPrefix 是确认按钮回调数据的前缀，完整格式为 ack|<分钟>|<对象 ID>|<规则名称>
+	ackPrefix = "ack"
+	// maxCallbackData 是 Telegram 回调数据的最大字节数，规则名称过长时不附带按钮
+	maxCallbackData = 64
+	// ackPollTimeout 是 getUpdates 长轮询的等待时间
+	ackPollTimeout = 30 * time.Second
+	// ackRetryDelay 是轮询失败后重试前的等待时间
+	ackRetryDelay = 5 * time.Second
+)
+
+// AckFunc 确认一条规则对某个对象的警报，在 d 时间内不再报警；规则不存在时返回 false
+type AckFunc func(rule string, id uint32, d time.Duration) bool
+
+// alertObjectID 返回警报针对的对象在规则引擎中的 ID，与 Engine.Acknowledge 一致。
+// 退出摘要和流量摘要不对应可以确认的规则，返回 false
+func alertObjectID(alert Alert) (uint32, bool) {
+	switch alert.Kind {
+	case KindProcess:
+		if alert.Reason == ReasonProcessExit {
+			return 0, false
+		}
+		return alert.ProcessStats.PID, true
+	case KindUser:
+		return alert.UserStats.UID, true
+	case KindPort:
+		return uint32(alert.PortStats.Port), true
+	case KindHost, KindSubnet:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// telegramInlineButton 和 telegramInlineKeyboard 对应 Bot API 的 InlineKeyboardButton 和 InlineKeyboardMarkup
+type telegramInlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+type telegramInlineKeyboard struct {
+	InlineKeyboard [][]telegramInlineButton `json:"inline_keyboard"`
+}
+
+// ackKeyboard 返回警报消息的确认按钮，未配置 ack_minutes 或警报无法确认时返回 nil
+func (t *TelegramAlerter) ackKeyboard(alert Alert) *telegramInlineKeyboard {
+	if len(t.cfg.AckMinutes) == 0 {
+		return nil
+	}
+	id, ok := alertObjectID(alert)
+	if !ok {
+		return nil
+	}
+	c := catalogFor(t.locale)
+	row := make([]telegramInlineButton, 0, len(t.cfg.AckMinutes))
+	for _, m := range t.cfg.AckMinutes {
+		data := fmt.Sprintf("%s|%d|%d|%s", ackPrefix, m, id, alert.RuleName)
+		if len(data) > maxCallbackData {
+			t.log.Debug("Rule name is too long for acknowledgement buttons", "rule", alert.RuleName)
+			return nil
+		}
+		row = append(row, telegramInlineButton{Text: c.text("ack") + " " + formatAckDuration(m), CallbackData: data})
+	}
+	return &telegramInlineKeyboard{InlineKeyboard: [][]telegramInlineButton{row}}
+}
+
+// formatAckDuration 将分钟数格式化为按钮上的时长，例如 30m、1h、1d
+func formatAckDuration(minutes int) string {
+	switch {
+	case minutes%(24*60) == 0:
+		return fmt.Sprintf("%dd", minutes/(24*60))
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// parseAckData 解析确认按钮的回调数据
+func parseAckData(data string) (rule string, id uint32, d time.Duration, ok bool) {
+	parts := strings.SplitN(data, "|", 4)
+	if len(parts) != 4 || parts[0] != ackPrefix || parts[3] == "" {
+		return "", 0, 0, false
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes <= 0 {
+		return "", 0, 0, false
+	}
+	n, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	return parts[3], uint32(n), time.Duration(minutes) * time.Minute, true
+}
+
+// AckEnabled 检查是否需要运行 ListenAcks
+func (t *TelegramAlerter) AckEnabled() bool {
+	return t.cfg.Enabled && len(t.cfg.AckMinutes) > 0
+}
+
+// telegramUpdate 是 getUpdates 返回的更新中用到的字段
+type telegramUpdate struct {
+	UpdateID      int64 `json:"update_id"`
+	CallbackQuery *struct {
+		ID   string `json:"id"`
+		Data string `json:"data"`
+		From struct {
+			Username string `json:"username"`
+		} `json:"from"`
+		Message *struct {
+			MessageID int64 `json:"message_id"`
+			Chat      struct {
+				ID       int64  `json:"id"`
+				Username string `json:"username"`
+			} `json:"chat"`
+		} `json:"message"`
+	} `json:"callback_query"`
+}
+
+// ListenAcks 通过 getUpdates 长轮询接收确认按钮的点击并调用 ack，直到上下文被取消。
+// 只处理来自配置的 chat_id 的点击；确认后回复点击者并移除消息上的按钮
+func (t *TelegramAlerter) ListenAcks(ctx context.Context, ack AckFunc) {
+	t.log.Info("Listening for Telegram alert acknowledgements")
+	var offset int64
+	for {
+		updates, err := t.getUpdates(ctx, offset)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			t.log.Warn("Failed to poll Telegram updates", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ackRetryDelay):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = max(offset, u.UpdateID+1)
+			t.handleUpdate(ctx, u, ack)
+		}
+	}
+}
+
+// getUpdates 调用一次 getUpdates，只接收回调查询
+func (t *TelegramAlerter) getUpdates(ctx context.Context, offset int64) ([]telegramUpdate, error) {
+	ctx, cancel := context.WithTimeout(ctx, ackPollTimeout+t.cfg.GetTimeout())
+	defer cancel()
+
+	var result []telegramUpdate
+	err := t.call(ctx, "getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         int(ackPollTimeout / time.Second),
+		"allowed_updates": []string{"callback_query"},
+	}, &result)
+	return result, err
+}
+
+// handleUpdate 处理一次按钮点击
+func (t *TelegramAlerter) handleUpdate(ctx context.Context, u telegramUpdate, ack AckFunc) {
+	q := u.CallbackQuery
+	if q == nil || q.Message == nil {
+		return
+	}
+	if !t.fromConfiguredChat(q.Message.Chat.ID, q.Message.Chat.Username) {
+		t.log.Warn("Ignoring acknowledgement from an unexpected chat", "chat_id", q.Message.Chat.ID)
+		return
+	}
+	rule, id, d, ok := parseAckData(q.Data)
+	if !ok {
+		return
+	}
+
+	c := catalogFor(t.locale)
+	reply := c.text("ack_failed")
+	if ack(rule, id, d) {
+		reply = c.text("acknowledged") + " " + formatAckDuration(int(d/time.Minute))
+		t.log.Info("Alert acknowledged from Telegram", "rule", rule, "id", id, "duration", d, "user", q.From.Username)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.cfg.GetTimeout())
+	defer cancel()
+	if err := t.call(ctx, "answerCallbackQuery", map[string]any{"callback_query_id": q.ID, "text": reply}, nil); err != nil {
+		t.log.Warn("Failed to answer Telegram callback query", "error", err)
+	}
+	if err := t.call(ctx, "editMessageReplyMarkup", map[string]any{
+		"chat_id":      q.Message.Chat.ID,
+		"message_id":   q.Message.MessageID,
+		"reply_markup": telegramInlineKeyboard{InlineKeyboard: [][]telegramInlineButton{}},
+	}, nil); err != nil {
+		t.log.Warn("Failed to remove acknowledgement buttons", "error", err)
+	}
+}
+
+// fromConfiguredChat 检查消息是否来自 chat_id，chat_id 可以是数字 ID 或 @用户名
+func (t *TelegramAlerter) fromConfiguredChat(id int64, username string) bool {
+	if name, ok := strings.CutPrefix(t.cfg.ChatID, "@"); ok {
+		return strings.EqualFold(name, username)
+	}
+	return t.cfg.ChatID == strconv.FormatInt(id, 10)
+}
+
+// call 调用一个 Bot API 方法，result 不为 nil 时解码响应中的 result 字段
+func (t *TelegramAlerter) call(ctx context.Context, method string, params map[string]any, result any) error {
+	jsonPayload, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal telegram payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", t.methodURL(method), bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return fmt.Errorf("failed to create telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("telegram %s: %w", method, t.requestError(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram %s returned non-200 status: %s", method, resp.Status)
+	}
+	if result == nil {
+		return nil
+	}
+	var body struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	return json.Unmarshal(body.Result, result)
+}
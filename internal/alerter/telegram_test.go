@@ -0,0 +1,102 @@
+// internal/alerter/telegram_test.go
+package alerter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"traffic-guardian/internal/config"
+)
+
+// roundTripFunc 将函数用作 http.RoundTripper，用于在不访问网络的情况下模拟 Telegram API
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// newTestTelegram 创建一个由 status 模拟 API 响应的 TelegramAlerter，requests 记录请求的路径
+func newTestTelegram(cfg config.TelegramConfig, status int, requests *[]string) *TelegramAlerter {
+	cfg.Enabled = true
+	t := NewTelegramAlerter(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, "", "")
+	t.client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req.URL.Path)
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Body:       io.NopCloser(strings.NewReader(`{"ok":false}`)),
+			Request:    req,
+		}, nil
+	})}
+	return t
+}
+
+func TestTelegramValidate(t *testing.T) {
+	valid := config.TelegramConfig{BotToken: "123456:ABC-def_ghi", ChatID: "-1001"}
+	tests := []struct {
+		name      string
+		cfg       config.TelegramConfig
+		status    int
+		want      string
+		wantCalls int
+	}{
+		{"ok", valid, http.StatusOK, "", 1},
+		{"auth failure", valid, http.StatusUnauthorized, "rejected bot_token", 1},
+		{"unknown chat", valid, http.StatusBadRequest, `rejected chat_id "-1001"`, 1},
+		{"server error", valid, http.StatusBadGateway, "non-200 status", 1},
+		// 格式错误的 Token 和空的 Chat ID 不需要访问 API 就能发现
+		{"malformed token", config.TelegramConfig{BotToken: "not-a-token", ChatID: "-1001"}, http.StatusOK, "bot_token is malformed", 0},
+		{"missing chat", config.TelegramConfig{BotToken: valid.BotToken}, http.StatusOK, "chat_id is empty", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			err := newTestTelegram(tt.cfg, tt.status, &requests).Validate(context.Background())
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate error = %v, want it to mention %q", err, tt.want)
+			}
+			if len(requests) != tt.wantCalls {
+				t.Fatalf("made %d API requests, want %d", len(requests), tt.wantCalls)
+			}
+			if tt.wantCalls > 0 && requests[0] != "/bot"+tt.cfg.BotToken+"/getChat" {
+				t.Errorf("requested %s, want getChat", requests[0])
+			}
+		})
+	}
+}
+
+func TestTelegramTransportErrorsHideToken(t *testing.T) {
+	cfg := config.TelegramConfig{Enabled: true, BotToken: "123456:ABC-secret_token", ChatID: "-1001"}
+	tg := NewTelegramAlerter(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg, "", "")
+	tg.client = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("dial tcp: connection refused")
+	})}
+	r := NewRouter(nil)
+	r.Register(tg)
+
+	errs := map[string]error{
+		"Send":     tg.Send(context.Background(), Alert{RuleName: "egress"}),
+		"Validate": tg.Validate(context.Background()),
+		"call":     tg.call(context.Background(), "getUpdates", nil, nil),
+	}
+	for name, err := range errs {
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("%s error = %v, want the transport failure", name, err)
+			continue
+		}
+		if strings.Contains(err.Error(), cfg.BotToken) {
+			t.Errorf("%s error leaks the bot token: %v", name, err)
+		}
+		r.RecordResult(tg.Name(), err)
+		if st := r.Statuses()[0]; st.LastError == "" || strings.Contains(st.LastError, cfg.BotToken) {
+			t.Errorf("%s: status LastError = %q, want the error without the bot token", name, st.LastError)
+		}
+	}
+}
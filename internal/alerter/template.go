@@ -0,0 +1,88 @@
+// internal/alerter/template.go
+package alerter
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MessageTemplate 是以 Alert 为数据执行的自定义消息模板 (text/template)，
+// 除内置函数外还提供:
+//
+//	humanizeBytes  将字节数格式化为可读字符串，例如 {{ humanizeBytes .ProcessStats.TotalBytes }}
+//	formatDuration 格式化 time.Duration，精确到秒
+//	formatTime     在配置的时区中格式化时间，可选第二个参数为布局，默认为 RFC1123
+//	toMB           将字节数转换为 MB
+type MessageTemplate struct {
+	tmpl *template.Template
+}
+
+// NewMessageTemplate 解析一个消息模板，timezone 为空时使用本地时区
+func NewMessageTemplate(name, text, timezone string) (*MessageTemplate, error) {
+	loc := time.Local
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+		}
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs(loc)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template: %w", err)
+	}
+	return &MessageTemplate{tmpl: tmpl}, nil
+}
+
+// Render 以警报为数据执行模板
+func (t *MessageTemplate) Render(alert Alert) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, alert); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// templateFuncs 返回模板中可用的辅助函数
+func templateFuncs(loc *time.Location) template.FuncMap {
+	return template.FuncMap{
+		"humanizeBytes": humanizeBytes,
+		"formatDuration": func(d time.Duration) string {
+			return d.Round(time.Second).String()
+		},
+		"formatTime": func(t time.Time, layout ...string) string {
+			l := time.RFC1123
+			if len(layout) > 0 {
+				l = layout[0]
+			}
+			return t.In(loc).Format(l)
+		},
+		"toMB": toMB,
+	}
+}
+
+// humanizeBytes 将字节数（任意整数或浮点类型）按 FormatBytes 格式化为带单位的可读字符串，
+// 小数部分被舍去，负数只会出现在模板自行计算的差值中
+func humanizeBytes(v any) string {
+	var b float64
+	switch n := v.(type) {
+	case uint64:
+		return FormatBytes(n)
+	case uint32:
+		b = float64(n)
+	case int:
+		b = float64(n)
+	case int64:
+		b = float64(n)
+	case float64:
+		b = n
+	default:
+		return fmt.Sprint(v)
+	}
+	if b < 0 {
+		return "-" + FormatBytes(uint64(-b))
+	}
+	return FormatBytes(uint64(b))
+}
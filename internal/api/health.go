@@ -0,0 +1,99 @@
+// internal/api/health.go
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"traffic-guardian/internal/alerter"
+	"traffic-guardian/internal/collector"
+)
+
+// HealthResponse 是 /healthz 的返回结构
+type HealthResponse struct {
+	// Status 为 "ok" 或 "stale"，stale 时返回 503
+	Status    string           `json:"status"`
+	Collector CollectorHealth  `json:"collector"`
+	Engine    EngineHealth     `json:"engine"`
+	Alerters  []alerter.Status `json:"alerters"`
+}
+
+// CollectorHealth 描述事件来源的健康状态
+type CollectorHealth struct {
+	// Attached 仅在事件来源为 eBPF 采集器时设置
+	Attached  *bool     `json:"attached,omitempty"`
+	LastEvent time.Time `json:"last_event"`
+	Stale     bool      `json:"stale"`
+}
+
+// EngineHealth 描述规则引擎的健康状态
+type EngineHealth struct {
+	LastCheck time.Time `json:"last_check"`
+	// Paused 表示警报已通过 /pause 暂停
+	Paused bool `json:"paused"`
+}
+
+// RegisterCollector 注册 eBPF 采集器的附加状态，用于 /healthz
+func (s *Server) RegisterCollector(attached func() bool) {
+	s.collectorAttached = attached
+}
+
+// RegisterCollectorStats 注册采集器的吞吐量统计，用于 /collector/stats。事件来源不是 eBPF 采集器时不注册
+func (s *Server) RegisterCollectorStats(stats func() collector.Stats) {
+	s.collectorStats = stats
+}
+
+// handleCollectorStats 返回采集器的吞吐量统计: 事件速率、流量速率、丢弃的事件数和运行时间
+func (s *Server) handleCollectorStats(w http.ResponseWriter, r *http.Request) {
+	if s.collectorStats == nil {
+		writeJSON(w, http.StatusNotImplemented, errorResponse{Error: "collector statistics are not available for this event source"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.collectorStats())
+}
+
+// RegisterAlerters 注册警报器的发送结果，用于 /healthz
+func (s *Server) RegisterAlerters(router *alerter.Router) {
+	s.router = router
+}
+
+// health 汇总各组件的健康状态。尚未收到任何事件时，从服务创建时开始计算无事件时间
+func (s *Server) health(now time.Time) HealthResponse {
+	lastEvent := s.stateManager.LastEvent()
+	since := lastEvent
+	if since.IsZero() {
+		since = s.startedAt
+	}
+	stale := now.Sub(since) > s.healthStale
+
+	resp := HealthResponse{
+		Status: "ok",
+		Collector: CollectorHealth{
+			LastEvent: lastEvent,
+			Stale:     stale,
+		},
+		Engine:   EngineHealth{LastCheck: s.ruleEngine.LastCheck(), Paused: s.ruleEngine.Paused()},
+		Alerters: []alerter.Status{},
+	}
+	if stale {
+		resp.Status = "stale"
+	}
+	if s.collectorAttached != nil {
+		attached := s.collectorAttached()
+		resp.Collector.Attached = &attached
+	}
+	if s.router != nil {
+		resp.Alerters = s.router.Statuses()
+	}
+	return resp
+}
+
+// handleHealth 返回各组件的健康状态，采集器超过无事件时间时返回 503
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	resp := s.health(time.Now())
+	status := http.StatusOK
+	if resp.Collector.Stale {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp)
+}
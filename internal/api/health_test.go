@@ -0,0 +1,46 @@
+// internal/api/health_test.go
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestHealthFlipsWithLastEvent(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.API.HealthStaleSeconds = 60
+	s := newTestServer(t, cfg)
+	s.RegisterCollector(func() bool { return true })
+
+	// 刚启动时还没有事件，从服务创建时开始计算
+	var resp HealthResponse
+	if code := s.get(t, "/healthz", &resp); code != http.StatusOK || resp.Status != "ok" {
+		t.Fatalf("GET /healthz right after start = %d %q, want 200 ok", code, resp.Status)
+	}
+	if !resp.Collector.LastEvent.IsZero() || resp.Collector.Attached == nil || !*resp.Collector.Attached {
+		t.Errorf("collector = %+v, want attached with no events", resp.Collector)
+	}
+
+	s.startedAt = time.Now().Add(-2 * time.Minute)
+	resp = HealthResponse{}
+	if code := s.get(t, "/healthz", &resp); code != http.StatusServiceUnavailable || resp.Status != "stale" || !resp.Collector.Stale {
+		t.Fatalf("GET /healthz with no events for 2m = %d %+v, want 503 stale", code, resp)
+	}
+
+	s.feed(t, xmit(pidA, "curl", 100))
+	resp = HealthResponse{}
+	if code := s.get(t, "/healthz", &resp); code != http.StatusOK || resp.Status != "ok" {
+		t.Fatalf("GET /healthz after an event = %d %q, want 200 ok", code, resp.Status)
+	}
+	if resp.Collector.LastEvent.IsZero() {
+		t.Error("last_event is not set after an event")
+	}
+
+	// 最后一个事件超过无事件时间后再次变为 stale
+	if h := s.health(time.Now().Add(61 * time.Second)); h.Status != "stale" {
+		t.Errorf("health 61s after the last event = %q, want stale", h.Status)
+	}
+}
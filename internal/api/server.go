@@ -0,0 +1,405 @@
+// internal/api/server.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"traffic-guardian/internal/alerter"
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/engine"
+	"traffic-guardian/internal/metrics"
+	"traffic-guardian/internal/state"
+)
+
+// Server 提供用于查询和运维操作的本地 HTTP 控制 API
+type Server struct {
+	log          *slog.Logger
+	cfg          config.API
+	stateManager *state.Manager
+	ruleEngine   *engine.Engine
+	alerts       *alerter.History
+	metrics      *metrics.Metrics
+	pushInterval time.Duration
+	upgrader     websocket.Upgrader
+	// wsSlots 是一个信号量，用于限制 WebSocket 订阅者的数量
+	wsSlots chan struct{}
+
+	// 以下字段用于 /healthz
+	startedAt         time.Time
+	healthStale       time.Duration
+	collectorAttached func() bool
+	router            *alerter.Router
+
+	// collectorStats 返回采集器的吞吐量统计，由 RegisterCollectorStats 注册
+	collectorStats func() collector.Stats
+
+	// reload 重新加载并应用配置，由 RegisterReload 注册
+	reload func() ([]any, error)
+	// config 返回当前生效的配置，由 RegisterConfig 注册
+	config func() *config.Config
+}
+
+// ResetResponse 是重置进程计数器接口的返回结构
+type ResetResponse struct {
+	PID     uint32 `json:"pid"`
+	Existed bool   `json:"existed"`
+}
+
+// PauseResponse 是暂停和恢复警报接口的返回结构
+type PauseResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// errorResponse 是所有接口出错时统一的返回结构
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ReloadResponse 是重新加载配置接口的返回结构。成功时 Applied 是生效配置的摘要，
+// 配置无效时 Errors 列出每个问题，原来的配置继续生效
+type ReloadResponse struct {
+	Applied map[string]any `json:"applied,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Errors  []string       `json:"errors,omitempty"`
+}
+
+// ConfigResponse 是导出配置接口的返回结构，所有凭据都已隐藏。Config 与配置文件的结构一致，
+// Effective 是合并默认值之后的关键设置（与启动日志中的摘要相同），Rules 是实际生效的规则，包括由单一规则字段生成的规则
+type ConfigResponse struct {
+	Config    map[string]any          `json:"config"`
+	Effective map[string]any          `json:"effective"`
+	Rules     []config.RuleDefinition `json:"rules"`
+}
+
+// NewServer 创建一个新的 API Server 实例
+func NewServer(log *slog.Logger, cfg *config.Config, stateManager *state.Manager, ruleEngine *engine.Engine, alerts *alerter.History, m *metrics.Metrics) *Server {
+	maxClients := cfg.API.MaxWSClients
+	if maxClients <= 0 {
+		maxClients = 10
+	}
+	return &Server{
+		log:          log,
+		cfg:          cfg.API,
+		stateManager: stateManager,
+		ruleEngine:   ruleEngine,
+		alerts:       alerts,
+		metrics:      m,
+		pushInterval: cfg.Rules.GetCheckInterval(),
+		wsSlots:      make(chan struct{}, maxClients),
+		startedAt:    time.Now(),
+		healthStale:  cfg.API.GetHealthStale(),
+	}
+}
+
+// IsEnabled 检查 API 是否被启用
+func (s *Server) IsEnabled() bool {
+	return s.cfg.Enabled
+}
+
+// Handler 返回注册了所有路由的 http.Handler
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /collector/stats", s.handleCollectorStats)
+	mux.HandleFunc("GET /stats", s.handleStats)
+	mux.HandleFunc("GET /stats/{pid}", s.handleStat)
+	mux.HandleFunc("GET /stats/{pid}/destinations", s.handleDestinations)
+	mux.HandleFunc("POST /stats/{pid}/reset", s.handleReset)
+	mux.HandleFunc("GET /netns", s.handleNetNS)
+	mux.HandleFunc("GET /ports", s.handlePorts)
+	mux.HandleFunc("GET /subnets", s.handleSubnets)
+	mux.HandleFunc("GET /alerts", s.handleAlerts)
+	mux.HandleFunc("GET /rules", s.handleRules)
+	mux.HandleFunc("POST /pause", s.handlePause)
+	mux.HandleFunc("POST /resume", s.handleResume)
+	mux.HandleFunc("POST /reload", s.handleReload)
+	mux.HandleFunc("GET /config", s.handleConfig)
+	mux.HandleFunc("GET /ws", s.handleWS)
+	mux.Handle("GET /metrics", s.metrics.Handler())
+	return mux
+}
+
+// Start 启动 HTTP 服务，直到上下文被取消
+func (s *Server) Start(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.cfg.ListenAddress,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		// 让所有请求的上下文继承服务的上下文，Shutdown 不会关闭已被劫持的 WebSocket 连接
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	// 启动一个 goroutine 在后台处理关闭信号
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			s.log.Error("Failed to shut down API server", "error", err)
+		}
+	}()
+
+	s.log.Info("Starting API server", "address", s.cfg.ListenAddress)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	s.log.Info("API server stopped")
+	return nil
+}
+
+// snapshot 返回当前所有进程流量状态的快照，供 REST 和 WebSocket 接口共用
+func (s *Server) snapshot() []state.ProcessStats {
+	return s.stateManager.GetStats()
+}
+
+// handleStats 返回当前所有进程的流量状态
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.snapshot())
+}
+
+// handleStat 返回指定进程的流量状态
+func (s *Server) handleStat(w http.ResponseWriter, r *http.Request) {
+	pid, err := parsePID(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	stats, ok := s.stateManager.GetProcessStats(pid)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "pid not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleNetNS 返回按网络命名空间汇总的流量，用于按容器统计
+func (s *Server) handleNetNS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.stateManager.GetStatsByNetNS())
+}
+
+// handlePorts 返回按本端端口汇总的流量，用于统计每个服务端口提供的流量
+func (s *Server) handlePorts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.stateManager.GetStatsByLocalPort())
+}
+
+// handleSubnets 返回每条 cidr 规则的目的网段上的流量汇总
+func (s *Server) handleSubnets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.stateManager.GetStatsBySubnet())
+}
+
+// handleAlerts 按时间顺序返回最近的警报
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.alerts.List())
+}
+
+// handleRules 返回每条规则的配置、触发次数和最近一次触发时间
+func (s *Server) handleRules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.ruleEngine.RuleStatuses())
+}
+
+// handlePause 暂停发送警报，流量采集和规则检查照常进行，便于排查问题时避免警报刷屏
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.ruleEngine.Pause()
+	writeJSON(w, http.StatusOK, PauseResponse{Paused: true})
+}
+
+// handleResume 恢复发送警报
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.ruleEngine.Resume()
+	writeJSON(w, http.StatusOK, PauseResponse{Paused: false})
+}
+
+// RegisterReload 注册重新加载配置的函数，返回生效配置的摘要（slog 键值对），与 SIGHUP 共用
+func (s *Server) RegisterReload(reload func() ([]any, error)) {
+	s.reload = reload
+}
+
+// handleReload 重新读取并校验配置文件，校验通过后在运行中应用
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.reload == nil {
+		writeJSON(w, http.StatusNotImplemented, ReloadResponse{Error: "reload is not available"})
+		return
+	}
+
+	summary, err := s.reload()
+	if err != nil {
+		resp := ReloadResponse{Error: err.Error()}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				resp.Errors = append(resp.Errors, e.Error())
+			}
+		}
+		writeJSON(w, http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ReloadResponse{Applied: summaryMap(summary)})
+}
+
+// RegisterConfig 注册返回当前生效配置的函数，重新加载后返回新的规则
+func (s *Server) RegisterConfig(cfg func() *config.Config) {
+	s.config = cfg
+}
+
+// handleConfig 返回当前生效的配置，凭据已隐藏
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if s.config == nil {
+		writeJSON(w, http.StatusNotImplemented, errorResponse{Error: "config export is not available"})
+		return
+	}
+
+	cfg := s.config().Redacted()
+	doc, err := cfg.Document()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, ConfigResponse{
+		Config:    doc,
+		Effective: summaryMap(cfg.Summary()),
+		Rules:     cfg.Rules.GetDefinitions(),
+	})
+}
+
+// summaryMap 将 slog 键值对形式的配置摘要转换为映射
+func summaryMap(summary []any) map[string]any {
+	m := make(map[string]any, len(summary)/2)
+	for i := 0; i+1 < len(summary); i += 2 {
+		if key, ok := summary[i].(string); ok {
+			m[key] = summary[i+1]
+		}
+	}
+	return m
+}
+
+// handleWS 将连接升级为 WebSocket，并每个检查周期推送一次流量快照
+func (s *Server) handleWS(w http.ResponseWriter, r *http.Request) {
+	select {
+	case s.wsSlots <- struct{}{}:
+		defer func() { <-s.wsSlots }()
+	default:
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{Error: "too many websocket subscribers"})
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 失败时已经向客户端写入了错误响应
+		s.log.Warn("Failed to upgrade websocket connection", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	s.log.Debug("WebSocket subscriber connected", "remote", r.RemoteAddr)
+
+	// 持续读取客户端消息，以便及时发现断开连接
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(s.pushInterval)
+	defer ticker.Stop()
+
+	for {
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.WriteJSON(s.snapshot()); err != nil {
+			s.log.Debug("WebSocket subscriber disconnected", "remote", r.RemoteAddr, "error", err)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(time.Second))
+			return
+		case <-disconnected:
+			s.log.Debug("WebSocket subscriber disconnected", "remote", r.RemoteAddr)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 进程对端接口的 limit 参数的默认值和上限
+const (
+	defaultDestinationLimit = 5
+	maxDestinationLimit     = 100
+)
+
+// handleDestinations 返回指定进程在时间窗口内发送字节数最多的对端，数量由 limit 参数指定
+func (s *Server) handleDestinations(w http.ResponseWriter, r *http.Request) {
+	pid, err := parsePID(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	limit := defaultDestinationLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDestinationLimit {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("limit must be between 1 and %d", maxDestinationLimit)})
+			return
+		}
+		limit = n
+	}
+
+	if _, ok := s.stateManager.GetProcessStats(pid); !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "pid not found"})
+		return
+	}
+	dests := s.stateManager.TopDestinations(pid, limit)
+	if dests == nil {
+		// 进程在两次查询之间被清理，或者没有可解析的对端
+		dests = []state.Destination{}
+	}
+	writeJSON(w, http.StatusOK, dests)
+}
+
+// handleReset 清除指定进程的流量计数和警报冷却记录
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	pid, err := parsePID(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	existed := s.stateManager.Reset(pid)
+	s.ruleEngine.ClearAlert(pid)
+
+	writeJSON(w, http.StatusOK, ResetResponse{PID: pid, Existed: existed})
+}
+
+// parsePID 从请求路径中解析 PID
+func parsePID(r *http.Request) (uint32, error) {
+	pid, err := strconv.ParseUint(r.PathValue("pid"), 10, 32)
+	if err != nil {
+		return 0, errors.New("invalid pid")
+	}
+	return uint32(pid), nil
+}
+
+// writeJSON 将数据序列化为 JSON 并写入响应
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
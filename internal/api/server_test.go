@@ -0,0 +1,272 @@
+// internal/api/server_test.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/alerter"
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/engine"
+	"traffic-guardian/internal/metrics"
+	"traffic-guardian/internal/state"
+)
+
+// testServer 是一个使用 cfg 的 API 服务及其依赖的组件
+type testServer struct {
+	*Server
+	state   *state.Manager
+	engine  *engine.Engine
+	history *alerter.History
+	// alerts 接收规则引擎发出的警报
+	alerts chan alerter.Alert
+}
+
+// newTestServer 创建一个 API 服务，cfg 为 nil 时使用 config.DefaultConfig
+func newTestServer(t *testing.T, cfg *config.Config) *testServer {
+	t.Helper()
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := state.NewManager(log, cfg)
+	alerts := make(chan alerter.Alert, 100)
+	e := engine.NewEngine(log, cfg, m, alerts)
+	h := alerter.NewHistory(cfg.Alerter.HistorySize)
+	return &testServer{
+		Server:  NewServer(log, cfg, m, e, h, metrics.New()),
+		state:   m,
+		engine:  e,
+		history: h,
+		alerts:  alerts,
+	}
+}
+
+// get 请求 path 并将 JSON 响应解码到 v，返回状态码
+func (s *testServer) get(t *testing.T, path string, v any) int {
+	t.Helper()
+	return s.do(t, http.MethodGet, path, v)
+}
+
+// post 以 POST 请求 path 并将 JSON 响应解码到 v，返回状态码
+func (s *testServer) post(t *testing.T, path string, v any) int {
+	t.Helper()
+	return s.do(t, http.MethodPost, path, v)
+}
+
+// do 以 method 请求 path 并将 JSON 响应解码到 v，返回状态码
+func (s *testServer) do(t *testing.T, method, path string, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("%s %s: Content-Type = %q", method, path, ct)
+	}
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+// feed 通过状态管理器的主循环处理 events，返回时所有事件都已计入
+func (s *testServer) feed(t *testing.T, events ...collector.TrafficEvent) {
+	t.Helper()
+	ch := make(chan collector.TrafficEvent)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.state.Start(ctx, ch)
+	}()
+	for _, event := range events {
+		ch <- event
+	}
+	cancel()
+	<-done
+}
+
+// 测试事件使用大于 PID_MAX_LIMIT (4194304) 的 PID，保证不会被当作 /proc 中的内核线程丢弃
+const (
+	pidA uint32 = 5000001
+	pidB uint32 = 5000002
+)
+
+// xmit 构造一个对端地址无法解析的发送事件
+func xmit(pid uint32, comm string, n uint64) collector.TrafficEvent {
+	event := collector.TrafficEvent{PID: pid, UID: uint32(os.Getuid()), Len: n, Packets: 1}
+	copy(event.Comm[:], comm)
+	return event
+}
+
+func TestAlertsReturnsMostRecent(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Alerter.HistorySize = 2
+	s := newTestServer(t, cfg)
+	for i := 1; i <= 3; i++ {
+		s.history.Add(alerter.Alert{RuleName: fmt.Sprintf("rule-%d", i), Reason: alerter.ReasonCumulativeThreshold})
+	}
+
+	var alerts []alerter.Alert
+	if code := s.get(t, "/alerts", &alerts); code != http.StatusOK {
+		t.Fatalf("GET /alerts = %d", code)
+	}
+	if len(alerts) != 2 || alerts[0].RuleName != "rule-2" || alerts[1].RuleName != "rule-3" {
+		t.Errorf("GET /alerts = %+v, want rule-2 and rule-3", alerts)
+	}
+}
+
+func TestStatsExposeDeltaSinceLastTick(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Rules.CheckIntervalSeconds = 1
+	s := newTestServer(t, cfg)
+
+	// 状态管理器按检查间隔采样，这里使用真实的定时器
+	ch := make(chan collector.TrafficEvent)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.state.Start(ctx, ch)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// waitForSample 轮询 /stats，直到进程有了第 n 个速率样本
+	waitForSample := func(n int) state.ProcessStats {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			var stats []state.ProcessStats
+			if code := s.get(t, "/stats", &stats); code != http.StatusOK {
+				t.Fatalf("GET /stats = %d", code)
+			}
+			if len(stats) == 1 && stats[0].RateSamples >= n {
+				if stats[0].RateSamples > n {
+					t.Fatalf("missed a tick: %d samples, want %d", stats[0].RateSamples, n)
+				}
+				return stats[0]
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for rate sample %d: %+v", n, stats)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	ch <- xmit(pidA, "curl", 3000)
+	first := waitForSample(1)
+	ch <- xmit(pidA, "curl", 1000)
+	ch <- xmit(pidA, "curl", 500)
+	second := waitForSample(2)
+
+	if first.DeltaBytes != 3000 || second.DeltaBytes != 1500 || second.TotalBytes != 4500 {
+		t.Errorf("delta_bytes = %d then %d (total %d), want 3000 then 1500 (total 4500)", first.DeltaBytes, second.DeltaBytes, second.TotalBytes)
+	}
+	// 两次采样之间大约相隔一个检查间隔
+	if second.RateBps < 1500/1.5 || second.RateBps > 1500/0.5 {
+		t.Errorf("rate_bps = %v, want about 1500 over a 1s tick", second.RateBps)
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Rules.Definitions = []config.RuleDefinition{{Name: "egress", Type: config.RuleTypeTraffic, ThresholdMB: 1}}
+	s := newTestServer(t, cfg)
+	s.feed(t, xmit(pidA, "curl", 2<<20))
+
+	var pause PauseResponse
+	if code := s.post(t, "/pause", &pause); code != http.StatusOK || !pause.Paused {
+		t.Fatalf("POST /pause = %d %+v", code, pause)
+	}
+	var health HealthResponse
+	s.get(t, "/healthz", &health)
+	if !health.Engine.Paused {
+		t.Error("/healthz does not report the engine as paused")
+	}
+
+	// 暂停期间规则照常检查，但不发送警报
+	s.engine.CheckRules()
+	if n := len(s.alerts); n != 0 {
+		t.Fatalf("sent %d alerts while paused", n)
+	}
+	if st, ok := s.state.GetProcessStats(pidA); !ok || st.TotalBytes != 2<<20 {
+		t.Errorf("state while paused: tracked = %v, total = %d", ok, st.TotalBytes)
+	}
+
+	if code := s.post(t, "/resume", &pause); code != http.StatusOK || pause.Paused {
+		t.Fatalf("POST /resume = %d %+v", code, pause)
+	}
+	health = HealthResponse{}
+	s.get(t, "/healthz", &health)
+	if health.Engine.Paused {
+		t.Error("/healthz still reports the engine as paused")
+	}
+
+	// 暂停期间被丢弃的警报没有进入冷却期，恢复后立即发送
+	s.engine.CheckRules()
+	if n := len(s.alerts); n != 1 {
+		t.Fatalf("sent %d alerts after resume, want 1", n)
+	}
+	if a := <-s.alerts; a.RuleName != "egress" || a.ProcessStats.PID != pidA {
+		t.Errorf("alert = rule %q for PID %d, want egress for %d", a.RuleName, a.ProcessStats.PID, pidA)
+	}
+}
+
+func TestConfigRedactsBotToken(t *testing.T) {
+	const token = "123456789:AAHsecretTelegramTokenXyz9"
+	cfg := config.DefaultConfig()
+	cfg.Alerter.Telegram.Enabled = true
+	cfg.Alerter.Telegram.BotToken = token
+	s := newTestServer(t, cfg)
+	if code := s.get(t, "/config", nil); code != http.StatusNotImplemented {
+		t.Errorf("GET /config before RegisterConfig = %d, want %d", code, http.StatusNotImplemented)
+	}
+	s.RegisterConfig(func() *config.Config { return cfg })
+
+	var raw json.RawMessage
+	if code := s.get(t, "/config", &raw); code != http.StatusOK {
+		t.Fatalf("GET /config = %d", code)
+	}
+	if strings.Contains(string(raw), "AAHsecret") {
+		t.Fatalf("response leaks the bot token: %s", raw)
+	}
+	var resp struct {
+		Config struct {
+			Alerter struct {
+				Telegram struct {
+					Enabled  bool   `json:"enabled"`
+					BotToken string `json:"bot_token"`
+				} `json:"telegram"`
+			} `json:"alerter"`
+			Rules map[string]any `json:"rules"`
+		} `json:"config"`
+		Effective map[string]any          `json:"effective"`
+		Rules     []config.RuleDefinition `json:"rules"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if tg := resp.Config.Alerter.Telegram; !tg.Enabled || tg.BotToken != "****Xyz9" {
+		t.Errorf("telegram = %+v, want enabled with the token masked to its last 4 characters", tg)
+	}
+	if len(resp.Config.Rules) == 0 || len(resp.Effective) == 0 || len(resp.Rules) == 0 {
+		t.Errorf("response is missing the config document, effective summary or rule definitions: %s", raw)
+	}
+	if cfg.Alerter.Telegram.BotToken != token {
+		t.Error("redacting the export modified the running config")
+	}
+}
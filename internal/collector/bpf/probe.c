@@ -4,11 +4,49 @@
 #include "vmlinux.h"
 #include <bpf/bpf_helpers.h>
 #include <bpf/bpf_tracing.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
+
+// vmlinux.h 不包含宏定义，这里补充需要用到的协议常量
+#define ETH_P_IP 0x0800
+#define ETH_P_IPV6 0x86DD
+#define IPPROTO_TCP 6
+#define IPPROTO_UDP 17
+
+// traffic_event.kind 的取值
+#define EVENT_XMIT 0
+#define EVENT_EXIT 1
 
 // 定义发送给用户空间的数据结构
 struct traffic_event {
     u32 pid;
+    u32 uid;
     u64 len;
+    char comm[16];
+    // 对端地址，IPv4 只使用前 4 个字节
+    u8 raddr[16];
+    // 本端和对端端口，主机字节序，非 TCP/UDP 时为 0
+    u16 lport;
+    u16 rport;
+    // 地址族: 4 表示 IPv4, 6 表示 IPv6, 0 表示无法解析
+    u8 family;
+    // 事件类型: EVENT_XMIT 表示发送了一个数据包, EVENT_EXIT 表示进程退出（只有 pid、uid 和 comm 有效）
+    u8 kind;
+    u8 pad[2];
+    // 进程所在网络命名空间的 inode 号，与 /proc/<pid>/ns/net 一致，用于区分容器
+    u32 netns;
+    // 进程在其所在（最内层）PID 命名空间中的 PID，以及该命名空间的 inode 号，与 /proc/<pid>/ns/pid 一致。
+    // pid 取自初始 PID 命名空间，运行在容器中的用户空间需要用 ns_pid 访问同一命名空间中的进程
+    u32 ns_pid;
+    u32 pidns;
+    // 事件代表的数据包数量: 通常为 1，采样时为采样率；退出事件中为 0
+    u32 packets;
+};
+
+// l4_ports 是 TCP 和 UDP 头部共同的端口字段
+struct l4_ports {
+    __be16 source;
+    __be16 dest;
 };
 
 // 使用 BPF_MAP_TYPE_PERF_EVENT_ARRAY 定义一个 perf buffer map
@@ -19,6 +57,214 @@ struct {
     __uint(value_size, sizeof(u32));
 } events SEC(".maps");
 
+// include_pids 和 include_comms 是需要跟踪的进程白名单，由用户空间根据配置填充
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 1024);
+    __type(key, u32);
+    __type(value, u8);
+} include_pids SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 1024);
+    __type(key, char[16]);
+    __type(value, u8);
+} include_comms SEC(".maps");
+
+// filter_config 只有一个元素，非 0 表示启用白名单过滤
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, u32);
+} filter_config SEC(".maps");
+
+// map 模式下按进程聚合的键，用于无法使用 perf buffer 的环境
+struct traffic_key {
+    u32 pid;
+    u32 uid;
+    char comm[16];
+};
+
+// traffic_value 是一个进程累计发送的字节数和数据包数量
+struct traffic_value {
+    u64 bytes;
+    u64 packets;
+};
+
+// traffic_totals 是 map 模式下（以及 perf 模式下小于 min_event_config 的数据包）每个进程累计发送的流量，
+// 由用户空间定时读取。使用 LRU 以便已退出进程的条目被自动淘汰
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_PERCPU_HASH);
+    __uint(max_entries, 10240);
+    __type(key, struct traffic_key);
+    __type(value, struct traffic_value);
+} traffic_totals SEC(".maps");
+
+// collect_mode 只有一个元素: 0 表示通过 perf buffer 发送事件, 1 表示累加到 traffic_totals
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, u32);
+} collect_mode SEC(".maps");
+
+// sample_config 只有一个元素: 大于 1 时 perf 模式下每个 CPU 只发送每 N 个数据包中的一个，长度乘以 N
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, u32);
+} sample_config SEC(".maps");
+
+// min_event_config 只有一个元素: 大于 0 时 perf 模式下小于此长度的数据包不发送事件，只累加到 traffic_totals
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, u32);
+} min_event_config SEC(".maps");
+
+// sample_counter 是每个 CPU 上已经过的数据包计数，用于按 sample_config 采样
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __uint(max_entries, 1);
+    __type(key, u32);
+    __type(value, u64);
+} sample_counter SEC(".maps");
+
+// accumulate 将数据包长度和数量累加到当前进程在 traffic_totals 中的计数
+static __always_inline void accumulate(struct traffic_event *event) {
+    struct traffic_key key = {
+        .pid = event->pid,
+        .uid = event->uid,
+    };
+    __builtin_memcpy(key.comm, event->comm, sizeof(key.comm));
+
+    struct traffic_value *total = bpf_map_lookup_elem(&traffic_totals, &key);
+    if (total) {
+        // per-CPU map 的值只会被当前 CPU 修改，不需要原子操作
+        total->bytes += event->len;
+        total->packets += event->packets;
+        return;
+    }
+    struct traffic_value value = {
+        .bytes = event->len,
+        .packets = event->packets,
+    };
+    bpf_map_update_elem(&traffic_totals, &key, &value, BPF_NOEXIST);
+}
+
+// is_small 检查数据包是否小于 min_event_config，未配置时总是返回 false
+static __always_inline bool is_small(struct traffic_event *event) {
+    u32 key = 0;
+    u32 *min_bytes = bpf_map_lookup_elem(&min_event_config, &key);
+    return min_bytes && event->len < *min_bytes;
+}
+
+// current_netns 返回当前进程所在网络命名空间的 inode 号
+static __always_inline u32 current_netns(void) {
+    struct task_struct *task = (struct task_struct *)bpf_get_current_task();
+    return BPF_CORE_READ(task, nsproxy, net_ns, ns.inum);
+}
+
+// fill_pidns 填充当前进程在其最内层 PID 命名空间中的 PID 和该命名空间的 inode 号
+static __always_inline void fill_pidns(struct traffic_event *event) {
+    struct task_struct *task = (struct task_struct *)bpf_get_current_task();
+    struct pid *pid = BPF_CORE_READ(task, group_leader, thread_pid);
+    unsigned int level = BPF_CORE_READ(pid, level);
+    struct upid upid;
+    if (bpf_core_read(&upid, sizeof(upid), &pid->numbers[level]) < 0) {
+        return;
+    }
+    event->ns_pid = upid.nr;
+    event->pidns = BPF_CORE_READ(upid.ns, ns.inum);
+}
+
+// is_map_mode 检查是否使用 map 模式采集
+static __always_inline bool is_map_mode(void) {
+    u32 key = 0;
+    u32 *mode = bpf_map_lookup_elem(&collect_mode, &key);
+    return mode && *mode == 1;
+}
+
+// sample 按 sample_config 决定是否发送当前数据包，被选中的数据包的长度和数量按采样率放大，
+// 使累计流量的期望值保持不变。未启用采样时总是返回 true
+static __always_inline bool sample(struct traffic_event *event) {
+    u32 key = 0;
+    u32 *rate = bpf_map_lookup_elem(&sample_config, &key);
+    if (!rate || *rate <= 1) {
+        return true;
+    }
+    u64 *count = bpf_map_lookup_elem(&sample_counter, &key);
+    if (!count) {
+        return true;
+    }
+    // per-CPU map 的值只会被当前 CPU 修改，不需要原子操作
+    *count += 1;
+    if (*count % *rate != 0) {
+        return false;
+    }
+    event->len *= *rate;
+    event->packets = *rate;
+    return true;
+}
+
+// is_included 检查当前进程是否在白名单中，未启用白名单时所有进程都会被跟踪
+static __always_inline bool is_included(u32 pid) {
+    u32 key = 0;
+    u32 *enabled = bpf_map_lookup_elem(&filter_config, &key);
+    if (!enabled || *enabled == 0) {
+        return true;
+    }
+
+    if (bpf_map_lookup_elem(&include_pids, &pid)) {
+        return true;
+    }
+
+    char comm[16] = {};
+    bpf_get_current_comm(&comm, sizeof(comm));
+    return bpf_map_lookup_elem(&include_comms, &comm) != NULL;
+}
+
+// fill_endpoint 从发送的 skb 中解析对端地址和端口
+static __always_inline void fill_endpoint(struct sk_buff *skb, struct traffic_event *event) {
+    unsigned char *head = BPF_CORE_READ(skb, head);
+    u16 network_header = BPF_CORE_READ(skb, network_header);
+    u16 transport_header = BPF_CORE_READ(skb, transport_header);
+    u16 protocol = bpf_ntohs(BPF_CORE_READ(skb, protocol));
+    u8 l4proto = 0;
+
+    if (protocol == ETH_P_IP) {
+        struct iphdr iph;
+        if (bpf_probe_read_kernel(&iph, sizeof(iph), head + network_header) < 0) {
+            return;
+        }
+        __builtin_memcpy(event->raddr, &iph.daddr, 4);
+        event->family = 4;
+        l4proto = iph.protocol;
+    } else if (protocol == ETH_P_IPV6) {
+        struct ipv6hdr ip6h;
+        if (bpf_probe_read_kernel(&ip6h, sizeof(ip6h), head + network_header) < 0) {
+            return;
+        }
+        __builtin_memcpy(event->raddr, &ip6h.daddr, 16);
+        event->family = 6;
+        l4proto = ip6h.nexthdr;
+    } else {
+        return;
+    }
+
+    if (l4proto == IPPROTO_TCP || l4proto == IPPROTO_UDP) {
+        struct l4_ports ports;
+        if (bpf_probe_read_kernel(&ports, sizeof(ports), head + transport_header) == 0) {
+            event->lport = bpf_ntohs(ports.source);
+            event->rport = bpf_ntohs(ports.dest);
+        }
+    }
+}
+
 // SEC("tp/net/net_dev_xmit") 将此函数附加到 net_dev_xmit tracepoint
 // 当内核将一个数据包交给网络设备发送时，此 tracepoint 会被触发
 SEC("tp/net/net_dev_xmit")
@@ -31,8 +277,46 @@ int handle_net_dev_xmit(struct trace_event_raw_net_dev_xmit *ctx) {
     u64 id = bpf_get_current_pid_tgid();
     event.pid = id >> 32;
 
+    // 在内核中丢弃不在白名单中的进程，避免无用事件进入用户空间
+    if (!is_included(event.pid)) {
+        return 0;
+    }
+
+    // 获取当前进程的 UID
+    // bpf_get_current_uid_gid() 返回一个64位数，高32位是 GID，低32位是 UID
+    u64 uid_gid = bpf_get_current_uid_gid();
+    event.uid = (u32)uid_gid;
+
+    // 获取当前进程的名称
+    bpf_get_current_comm(&event.comm, sizeof(event.comm));
+
     // 从 tracepoint 上下文中获取数据包的长度
     event.len = (u64)ctx->len;
+    event.packets = 1;
+
+    // 获取当前进程所在的网络命名空间和 PID 命名空间
+    event.netns = current_netns();
+    fill_pidns(&event);
+
+    // map 模式下只按进程累加，不解析对端
+    if (is_map_mode()) {
+        accumulate(&event);
+        return 0;
+    }
+
+    // 小数据包只在内核中累加，避免大量控制报文占满 perf buffer，累计流量保持精确
+    if (is_small(&event)) {
+        accumulate(&event);
+        return 0;
+    }
+
+    // 启用采样时跳过未被选中的数据包，不再解析对端
+    if (!sample(&event)) {
+        return 0;
+    }
+
+    // 解析数据包的目的地址和端口
+    fill_endpoint((struct sk_buff *)ctx->skbaddr, &event);
 
     // 将事件数据提交到 perf buffer
     bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
@@ -40,5 +324,33 @@ int handle_net_dev_xmit(struct trace_event_raw_net_dev_xmit *ctx) {
     return 0;
 }
 
+// SEC("tp/sched/sched_process_exit") 将此函数附加到 sched_process_exit tracepoint，
+// 每个线程退出时都会触发，只在线程组的主线程退出（即进程结束）时上报
+SEC("tp/sched/sched_process_exit")
+int handle_sched_process_exit(struct trace_event_raw_sched_process_template *ctx) {
+    u64 id = bpf_get_current_pid_tgid();
+    u32 pid = id >> 32;
+    if (pid != (u32)id) {
+        return 0;
+    }
+    if (!is_included(pid)) {
+        return 0;
+    }
+    // map 模式下不使用 perf buffer，进程退出由 LRU 淘汰处理
+    if (is_map_mode()) {
+        return 0;
+    }
+
+    struct traffic_event event = {};
+    event.pid = pid;
+    event.uid = (u32)bpf_get_current_uid_gid();
+    bpf_get_current_comm(&event.comm, sizeof(event.comm));
+    event.kind = EVENT_EXIT;
+    fill_pidns(&event);
+
+    bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event));
+    return 0;
+}
+
 // 许可证声明，对于 eBPF 程序是必需的
 char LICENSE[] SEC("license") = "GPL";
\ No newline at end of file
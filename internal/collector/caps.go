@@ -0,0 +1,74 @@
+// internal/collector/caps.go
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 加载 eBPF 程序和附加 tracepoint 所需的 Linux capability 编号
+const (
+	capSysAdmin = 21
+	capPerfmon  = 38
+	capBPF      = 39
+)
+
+// checkCapabilities 检查当前进程是否拥有加载 eBPF 程序所需的权限。
+// 需要 CAP_SYS_ADMIN，或者（内核 5.8+）同时拥有 CAP_BPF 和 CAP_PERFMON。
+func checkCapabilities() error {
+	capEff, err := readEffectiveCaps("/proc/self/status")
+	if err != nil {
+		// 无法判断时不阻止启动，交由后续的加载过程报错
+		return nil
+	}
+
+	has := func(capability uint) bool { return capEff&(1<<capability) != 0 }
+	if has(capSysAdmin) || (has(capBPF) && has(capPerfmon)) {
+		return nil
+	}
+
+	var missing []string
+	if !has(capBPF) {
+		missing = append(missing, "CAP_BPF")
+	}
+	if !has(capPerfmon) {
+		missing = append(missing, "CAP_PERFMON")
+	}
+	return fmt.Errorf("insufficient privileges to load eBPF programs: missing %s (or CAP_SYS_ADMIN); "+
+		"run as root, or grant capabilities with: sudo setcap cap_bpf,cap_perfmon+ep %s",
+		strings.Join(missing, ", "), executablePath())
+}
+
+// readEffectiveCaps 从 /proc/<pid>/status 中解析 CapEff 字段
+func readEffectiveCaps(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !ok {
+			continue
+		}
+		return strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("CapEff not found in %s", path)
+}
+
+// executablePath 返回当前可执行文件的路径，用于生成 setcap 提示
+func executablePath() string {
+	path, err := os.Executable()
+	if err != nil {
+		return "/path/to/traffic-guardian"
+	}
+	return path
+}
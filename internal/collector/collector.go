@@ -6,62 +6,266 @@ import (
 	"context"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"log/slog"
+	"net/netip"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/perf"
+
+	"traffic-guardian/internal/config"
 )
 
 // 【最终修正】使用标准的 bpf2go 命令。它会自动找到 /sys/kernel/btf/vmlinux 并生成 vmlinux.h
 //go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -target bpf bpf ./bpf/probe.c -- -O2 -g -Wall
 
-// TrafficEvent mirrors the struct in probe.c
+// TrafficEvent mirrors the struct in probe.c, see checkEventLayout
 type TrafficEvent struct {
-	PID uint32
-	Len uint64
+	PID  uint32
+	UID  uint32
+	Len  uint64
+	Comm [commLen]byte
+	// RemoteAddr 是对端地址，IPv4 只使用前 4 个字节
+	RemoteAddr [16]byte
+	LocalPort  uint16
+	RemotePort uint16
+	// Family 是地址族: 4 表示 IPv4, 6 表示 IPv6, 0 表示无法解析
+	Family uint8
+	// Kind 是事件类型，见 EventXmit 和 EventExit
+	Kind uint8
+	_    [2]byte
+	// NetNS 是进程所在网络命名空间的 inode 号，与 /proc/<pid>/ns/net 一致，map 模式下为 0
+	NetNS uint32
+	// NSPID 是进程在其最内层 PID 命名空间中的 PID，PIDNS 是该命名空间的 inode 号；PID 取自初始 PID 命名空间。
+	// map 模式和旧版本的录制文件中为 0
+	NSPID uint32
+	PIDNS uint32
+	// Packets 是事件代表的数据包数量，采样时为采样率，从 traffic_totals 读取的事件中为两次读取之间的增量。
+	// 退出事件和旧版本的录制文件中为 0，表示未知
+	Packets uint32
+}
+
+// TrafficEvent.Kind 的取值，与 probe.c 中的 EVENT_* 一致
+const (
+	// EventXmit 表示进程发送了 Len 字节的数据包
+	EventXmit uint8 = 0
+	// EventExit 表示进程已退出，此时只有 PID、UID 和 Comm 有效
+	EventExit uint8 = 1
+)
+
+// IsExit 检查事件是否表示进程退出
+func (e *TrafficEvent) IsExit() bool {
+	return e.Kind == EventExit
+}
+
+// Remote 返回事件的对端地址，无法解析时返回无效的 netip.Addr
+func (e *TrafficEvent) Remote() netip.Addr {
+	switch e.Family {
+	case 4:
+		return netip.AddrFrom4([4]byte(e.RemoteAddr[:4]))
+	case 6:
+		return netip.AddrFrom16(e.RemoteAddr)
+	default:
+		return netip.Addr{}
+	}
+}
+
+// CommString 返回去掉结尾 '\0' 的进程名
+func (e *TrafficEvent) CommString() string {
+	if i := bytes.IndexByte(e.Comm[:], 0); i >= 0 {
+		return string(e.Comm[:i])
+	}
+	return string(e.Comm[:])
 }
 
+// byteOrderFor 根据配置返回解码事件使用的字节序，内核按主机字节序写入事件，因此默认为 native
+func byteOrderFor(name string) binary.ByteOrder {
+	switch name {
+	case "little":
+		return binary.LittleEndian
+	case "big":
+		return binary.BigEndian
+	default:
+		return binary.NativeEndian
+	}
+}
+
+// eventSize 是 TrafficEvent 在二进制编码下的大小
+var eventSize = binary.Size(TrafficEvent{})
+
+// errShortRecord 表示 perf 记录的长度小于 TrafficEvent 的大小
+var errShortRecord = errors.New("short perf record")
+
 // Collector 负责管理 eBPF 程序
 type Collector struct {
 	log        *slog.Logger
+	cfg        config.Collector
 	eventsChan chan<- TrafficEvent
+	byteOrder  binary.ByteOrder
+
+	// objs 在采集器运行期间指向已加载的 eBPF 对象，用于运行时更新白名单
+	mu   sync.Mutex
+	objs *bpfObjects
+
+	// shortRecords 统计因长度不足而被丢弃的 perf 记录数，lostSamples 统计内核因 perf 缓冲区写满而丢弃的事件数
+	shortRecords atomic.Uint64
+	shortLogOnce sync.Once
+	lostSamples  atomic.Uint64
+
+	// throughput 统计发送到状态管理器的事件，startedAt 是 Start 被调用的时间 (Unix 纳秒)，见 Stats
+	throughput throughput
+	startedAt  atomic.Int64
+
+	// attached 表示 eBPF 程序当前是否已附加到 tracepoint
+	attached atomic.Bool
+
+	// recorder 在配置了 record_path 时将事件写入文件。min_event_bytes 大于 0 时 perf 读取和 traffic_totals 轮询
+	// 在不同的 goroutine 中调用 emit，因此使用原子指针
+	recorder atomic.Pointer[Recorder]
+
+	// pinned 是固定到 pin_path 的 maps 名称，退出时移除
+	pinned []string
+
+	// readSpec 读取外部的 eBPF 对象文件，embeddedSpec 读取 bpf2go 嵌入的对象，assign 将对象加载到内核。
+	// 测试中替换它们以便在没有编译好的探针和内核权限时检查加载顺序
+	readSpec     func(path string) (*ebpf.CollectionSpec, error)
+	embeddedSpec func() (*ebpf.CollectionSpec, error)
+	assign       func(spec *ebpf.CollectionSpec, objs *bpfObjects, opts *ebpf.CollectionOptions) error
 }
 
 // New 创建一个新的 Collector 实例
-func New(log *slog.Logger, eventsChan chan<- TrafficEvent) *Collector {
+func New(log *slog.Logger, cfg config.Collector, eventsChan chan<- TrafficEvent) *Collector {
 	return &Collector{
 		log:        log,
+		cfg:        cfg,
 		eventsChan: eventsChan,
+		byteOrder:  byteOrderFor(cfg.ByteOrder),
+
+		readSpec:     ebpf.LoadCollectionSpec,
+		embeddedSpec: loadBpf,
+		assign: func(spec *ebpf.CollectionSpec, objs *bpfObjects, opts *ebpf.CollectionOptions) error {
+			return spec.LoadAndAssign(objs, opts)
+		},
 	}
 }
 
 // Start 启动 eBPF 采集器
 func (c *Collector) Start(ctx context.Context) error {
 	c.log.Info("Starting eBPF collector")
+	c.startedAt.Store(time.Now().UnixNano())
+
+	// 在加载前检查权限，给出比加载失败更明确的提示
+	if err := checkCapabilities(); err != nil {
+		return err
+	}
 
-	// 加载 eBPF 程序和 maps (由 bpf2go 生成)
+	// 加载 eBPF 程序和 maps (默认使用 bpf2go 嵌入的对象)
 	objs := bpfObjects{}
-	if err := loadBpfObjects(&objs, nil); err != nil {
+	if err := c.loadObjects(&objs); err != nil {
 		return err
 	}
+	defer c.unpinMaps()
 	defer objs.Close()
 
+	// 根据配置填充进程白名单
+	if err := applyInclude(&objs, c.cfg.Include); err != nil {
+		return err
+	}
+	if !c.cfg.Include.IsEmpty() {
+		c.log.Info("Process include filter enabled", "pids", c.cfg.Include.PIDs, "comms", c.cfg.Include.Comms)
+	}
+	c.mu.Lock()
+	c.objs = &objs
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.objs = nil
+		c.mu.Unlock()
+	}()
+
+	// 设置采集模式，必须在附加之前完成
+	if err := applyMode(&objs, c.cfg.GetMode()); err != nil {
+		return err
+	}
+	if err := applySampleRate(&objs, c.cfg.SampleRate); err != nil {
+		return err
+	}
+	if c.cfg.SampleRate > 1 && c.cfg.GetMode() == config.CollectorModePerf {
+		c.log.Info("Sampling packets", "rate", c.cfg.SampleRate)
+	}
+	if err := applyMinEventBytes(&objs, c.cfg.MinEventBytes); err != nil {
+		return err
+	}
+
 	// 将 eBPF 程序附加到 tracepoint
 	tp, err := link.Tracepoint("net", "net_dev_xmit", objs.HandleNetDevXmit, nil)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to attach net_dev_xmit tracepoint: %w", c.conflictError(err))
 	}
 	defer tp.Close()
+	c.attached.Store(true)
+	defer c.attached.Store(false)
 
 	c.log.Info("eBPF program attached successfully")
 
-	// 创建一个 perf event reader 来从内核读取数据
-	rd, err := perf.NewReader(objs.Events, os.Getpagesize())
+	// 进程退出事件只用于及时结束进程的统计，附加失败时不影响流量采集
+	exitTp, err := link.Tracepoint("sched", "sched_process_exit", objs.HandleSchedProcessExit, nil)
+	if err != nil {
+		c.log.Warn("Failed to attach process exit tracepoint, exited processes will age out instead", "error", c.conflictError(err))
+	} else {
+		defer exitTp.Close()
+	}
+
+	if c.cfg.RecordPath != "" {
+		rec, err := NewRecorder(c.cfg.RecordPath)
+		if err != nil {
+			return err
+		}
+		c.recorder.Store(rec)
+		defer func() {
+			c.recorder.Store(nil)
+			if err := rec.Close(); err != nil {
+				c.log.Error("Failed to close record file", "error", err)
+			}
+		}()
+		c.log.Info("Recording events", "path", c.cfg.RecordPath)
+	}
+
+	if c.cfg.GetMode() == config.CollectorModeMap {
+		return c.pollMap(ctx, &objs)
+	}
+
+	// 小于 min_event_bytes 的数据包在内核中累加，与 map 模式一样定时读取；objs 关闭前必须等待读取结束
+	if c.cfg.MinEventBytes > 0 {
+		c.log.Info("Accumulating small packets in the kernel", "min_event_bytes", c.cfg.MinEventBytes)
+		pollCtx, stopPoll := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.pollTotals(pollCtx, &objs)
+		}()
+		defer wg.Wait()
+		defer stopPoll()
+	}
+	return c.readPerf(ctx, &objs)
+}
+
+// readPerf 通过 perf buffer 逐个读取内核发送的事件
+func (c *Collector) readPerf(ctx context.Context, objs *bpfObjects) error {
+	// 创建一个 perf event reader 来从内核读取数据，每个 CPU 的缓冲区大小为配置的页数
+	perCPUBuffer := c.cfg.GetPerfBufferPages() * os.Getpagesize()
+	rd, err := perf.NewReader(objs.Events, perCPUBuffer)
 	if err != nil {
 		return err
 	}
 	defer rd.Close()
+	c.log.Info("Perf reader created", "per_cpu_buffer_bytes", perCPUBuffer)
 
 	// 启动一个 goroutine 在后台处理关闭信号
 	go func() {
@@ -73,7 +277,6 @@ func (c *Collector) Start(ctx context.Context) error {
 	c.log.Info("Waiting for eBPF events...")
 
 	// 主循环，读取和处理事件
-	var event TrafficEvent
 	for {
 		record, err := rd.Read()
 		if err != nil {
@@ -84,14 +287,97 @@ func (c *Collector) Start(ctx context.Context) error {
 			c.log.Error("Error reading from perf reader", "error", err)
 			continue
 		}
+		if !c.handleRecord(ctx, record) {
+			return nil
+		}
+	}
+}
 
-		// 解析数据
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &event); err != nil {
-			c.log.Error("Error parsing event data", "error", err)
-			continue
+// handleRecord 解析一条 perf 记录并发送其中的事件，无法解析的记录被计数并丢弃。
+// 下游停止消费时返回 false
+func (c *Collector) handleRecord(ctx context.Context, record perf.Record) bool {
+	// 缓冲区写满时内核丢弃事件，读取器返回一条只有丢弃数量的记录
+	if record.LostSamples > 0 {
+		c.lostSamples.Add(record.LostSamples)
+		return true
+	}
+
+	// 解析数据
+	event, err := decodeEvent(record.RawSample, c.byteOrder)
+	if errors.Is(err, errShortRecord) {
+		c.shortRecords.Add(1)
+		// 只记录一次，避免每个事件都刷屏
+		c.shortLogOnce.Do(func() {
+			c.log.Error("Perf record shorter than TrafficEvent, probe and userspace struct layouts may differ",
+				"record_bytes", len(record.RawSample), "expected_bytes", eventSize)
+		})
+		return true
+	}
+	if err != nil {
+		c.log.Error("Error parsing event data", "error", err)
+		return true
+	}
+	return c.emit(ctx, event)
+}
+
+// emit 录制事件（如果启用）并发送到 channel。下游停止消费时随上下文退出，避免阻塞关闭，
+// 此时返回 false
+func (c *Collector) emit(ctx context.Context, event TrafficEvent) bool {
+	if rec := c.recorder.Load(); rec != nil {
+		// 两个 goroutine 同时写入失败时只有一个停止录制并记录日志
+		if err := rec.Write(event); err != nil && c.recorder.CompareAndSwap(rec, nil) {
+			c.log.Error("Failed to record event, recording stopped", "error", err)
 		}
+	}
+
+	select {
+	case c.eventsChan <- event:
+		c.throughput.add(time.Now(), event.Len)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Attached 检查 eBPF 程序当前是否已附加到 tracepoint
+func (c *Collector) Attached() bool {
+	return c.attached.Load()
+}
+
+// ShortRecords 返回因长度不足而被丢弃的 perf 记录数
+func (c *Collector) ShortRecords() uint64 {
+	return c.shortRecords.Load()
+}
+
+// decodeEvent 按指定字节序将 perf 记录的原始数据解析为 TrafficEvent
+func decodeEvent(raw []byte, order binary.ByteOrder) (TrafficEvent, error) {
+	var event TrafficEvent
+	if len(raw) < eventSize {
+		return event, fmt.Errorf("%w: got %d bytes, want %d", errShortRecord, len(raw), eventSize)
+	}
+	if err := binary.Read(bytes.NewReader(raw), order, &event); err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+// UpdateInclude 在运行时替换进程白名单，例如在重新加载配置后调用。
+// 更新失败时恢复原来的白名单，不会留下只更新了一半的 map
+func (c *Collector) UpdateInclude(include config.Include) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-		// 将事件发送到 channel
-		c.eventsChan <- event
+	if c.objs == nil {
+		// 采集器尚未启动，新的白名单会在启动时生效
+		c.cfg.Include = include
+		return nil
+	}
+	if err := applyInclude(c.objs, include); err != nil {
+		if restoreErr := applyInclude(c.objs, c.cfg.Include); restoreErr != nil {
+			c.log.Error("Failed to restore the previous process include filter", "error", restoreErr)
+		}
+		return err
 	}
+	c.cfg.Include = include
+	return nil
 }
@@ -0,0 +1,107 @@
+// internal/collector/collector_test.go
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf/perf"
+
+	"traffic-guardian/internal/config"
+)
+
+// newTestCollector 创建一个不加载 eBPF 的 Collector，日志写入 logs
+func newTestCollector(cfg config.Collector, logs *bytes.Buffer) (*Collector, chan TrafficEvent) {
+	ch := make(chan TrafficEvent, 16)
+	return New(slog.New(slog.NewTextHandler(logs, nil)), cfg, ch), ch
+}
+
+// encodeEvent 以指定字节序编码事件，与内核写入 perf 缓冲区的布局一致
+func encodeEvent(t *testing.T, event TrafficEvent, order binary.ByteOrder) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, order, event); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// sampleEvent 返回一个各字段都不为零的事件
+func sampleEvent() TrafficEvent {
+	event := TrafficEvent{PID: 4242, UID: 1000, Len: 1500, LocalPort: 34567, RemotePort: 443, Family: 4, NetNS: 4026531840, Packets: 1}
+	copy(event.Comm[:], "curl")
+	copy(event.RemoteAddr[:], []byte{198, 51, 100, 7})
+	return event
+}
+
+func TestTruncatedRecordIsCountedAndLoggedOnce(t *testing.T) {
+	var logs bytes.Buffer
+	c, ch := newTestCollector(config.Collector{}, &logs)
+	raw := encodeEvent(t, sampleEvent(), binary.NativeEndian)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if !c.handleRecord(ctx, perf.Record{RawSample: raw[:eventSize-4]}) {
+			t.Fatal("handleRecord stopped on a truncated record")
+		}
+	}
+	if got := c.ShortRecords(); got != 3 {
+		t.Errorf("ShortRecords = %d, want 3", got)
+	}
+	if n := strings.Count(logs.String(), "Perf record shorter than TrafficEvent"); n != 1 {
+		t.Errorf("mismatch logged %d times, want once:\n%s", n, logs.String())
+	}
+	if len(ch) != 0 {
+		t.Fatalf("truncated records emitted %d events", len(ch))
+	}
+
+	// 完整的记录照常发送
+	c.handleRecord(ctx, perf.Record{RawSample: raw})
+	if len(ch) != 1 {
+		t.Fatalf("full record emitted %d events, want 1", len(ch))
+	}
+	if got := <-ch; got != sampleEvent() {
+		t.Errorf("decoded %+v, want %+v", got, sampleEvent())
+	}
+}
+
+func TestDecodeEventByteOrder(t *testing.T) {
+	want := sampleEvent()
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		raw := encodeEvent(t, want, order)
+		got, err := decodeEvent(raw, order)
+		if err != nil {
+			t.Fatalf("%v: %v", order, err)
+		}
+		if got != want {
+			t.Errorf("%v round trip = %+v, want %+v", order, got, want)
+		}
+	}
+
+	// 用错误的字节序解码会得到错误的值，默认的 native 必须与主机字节序一致
+	raw := encodeEvent(t, want, binary.BigEndian)
+	if got, _ := decodeEvent(raw, binary.LittleEndian); got.PID == want.PID {
+		t.Error("decoding big-endian data as little-endian returned the original PID")
+	}
+	raw = encodeEvent(t, want, binary.NativeEndian)
+	if got, _ := decodeEvent(raw, byteOrderFor("")); got != want {
+		t.Errorf("default byte order decoded %+v, want %+v", got, want)
+	}
+}
+
+func TestByteOrderFor(t *testing.T) {
+	for name, want := range map[string]binary.ByteOrder{
+		"little": binary.LittleEndian,
+		"big":    binary.BigEndian,
+		"native": binary.NativeEndian,
+		"":       binary.NativeEndian,
+	} {
+		if got := byteOrderFor(name); got != want {
+			t.Errorf("byteOrderFor(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
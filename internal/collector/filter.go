@@ -0,0 +1,76 @@
+// internal/collector/filter.go
+package collector
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+
+	"traffic-guardian/internal/config"
+)
+
+// commLen 是内核中进程名 (task->comm) 的最大长度，包含结尾的 '\0'
+const commLen = 16
+
+// applyInclude 将进程白名单写入 eBPF maps，并删除不再需要的旧条目
+func applyInclude(objs *bpfObjects, include config.Include) error {
+	if err := syncSet(objs.IncludePids, include.PIDs); err != nil {
+		return fmt.Errorf("failed to update include_pids map: %w", err)
+	}
+
+	comms := make([][commLen]byte, 0, len(include.Comms))
+	for _, name := range include.Comms {
+		if len(name) >= commLen {
+			return fmt.Errorf("comm %q is longer than %d characters", name, commLen-1)
+		}
+		var comm [commLen]byte
+		copy(comm[:], name)
+		comms = append(comms, comm)
+	}
+	if err := syncSet(objs.IncludeComms, comms); err != nil {
+		return fmt.Errorf("failed to update include_comms map: %w", err)
+	}
+
+	var enabled uint32
+	if !include.IsEmpty() {
+		enabled = 1
+	}
+	if err := objs.FilterConfig.Put(uint32(0), enabled); err != nil {
+		return fmt.Errorf("failed to update filter_config map: %w", err)
+	}
+	return nil
+}
+
+// syncSet 使一个以 K 为键、u8 为值的 hash map 恰好包含 keys 中的键
+func syncSet[K comparable](m *ebpf.Map, keys []K) error {
+	want := make(map[K]bool, len(keys))
+	for _, k := range keys {
+		want[k] = true
+		if err := m.Put(k, uint8(1)); err != nil {
+			return err
+		}
+	}
+
+	var (
+		key   K
+		value uint8
+		stale []K
+	)
+	iter := m.Iterate()
+	for iter.Next(&key, &value) {
+		if !want[key] {
+			stale = append(stale, key)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	for _, k := range stale {
+		if err := m.Delete(k); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// internal/collector/layout.go
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// eventStructName 是探针中事件结构体的名称
+const eventStructName = "traffic_event"
+
+// expectedEventSize 是 probe.c 中 struct traffic_event 的大小，修改两边的结构体时必须同步更新
+const expectedEventSize = 72
+
+// checkEventLayout 检查 TrafficEvent 与探针中 struct traffic_event 的大小是否一致。
+// TrafficEvent 依赖手写的填充字段与 C 的布局对齐，不一致时 decodeEvent 会静默地解析出错误的字段，
+// 因此在加载探针前直接失败。探针带有 BTF 时以 BTF 中的大小为准，否则只检查 Go 一侧
+func (c *Collector) checkEventLayout(spec *ebpf.CollectionSpec) error {
+	if got := unsafe.Sizeof(TrafficEvent{}); got != expectedEventSize || eventSize != expectedEventSize {
+		return fmt.Errorf("TrafficEvent layout mismatch: in-memory size %d, encoded size %d, want %d", got, eventSize, expectedEventSize)
+	}
+
+	if spec == nil || spec.Types == nil {
+		c.log.Debug("eBPF spec has no BTF, skipping probe layout check")
+		return nil
+	}
+	var st *btf.Struct
+	if err := spec.Types.TypeByName(eventStructName, &st); err != nil {
+		if errors.Is(err, btf.ErrNotFound) {
+			c.log.Debug("struct traffic_event not found in probe BTF, skipping probe layout check")
+			return nil
+		}
+		return fmt.Errorf("failed to look up struct %s in probe BTF: %w", eventStructName, err)
+	}
+	if st.Size != expectedEventSize {
+		return fmt.Errorf("probe struct %s is %d bytes but TrafficEvent is %d bytes, rebuild the probe or update TrafficEvent",
+			eventStructName, st.Size, expectedEventSize)
+	}
+	return nil
+}
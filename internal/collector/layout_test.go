@@ -0,0 +1,58 @@
+// internal/collector/layout_test.go
+package collector
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestTrafficEventSize(t *testing.T) {
+	if got := unsafe.Sizeof(TrafficEvent{}); got != expectedEventSize {
+		t.Errorf("unsafe.Sizeof(TrafficEvent{}) = %d, want %d", got, expectedEventSize)
+	}
+	if eventSize != expectedEventSize {
+		t.Errorf("binary.Size(TrafficEvent{}) = %d, want %d; TrafficEvent has implicit padding", eventSize, expectedEventSize)
+	}
+}
+
+// specWithEvent 返回一个 BTF 中只包含指定大小的 struct traffic_event 的 CollectionSpec
+func specWithEvent(t *testing.T, size uint32) *ebpf.CollectionSpec {
+	t.Helper()
+	b, err := btf.NewBuilder([]btf.Type{&btf.Struct{Name: eventStructName, Size: size}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, err := b.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ebpf.CollectionSpec{Types: types}
+}
+
+func TestCheckEventLayout(t *testing.T) {
+	var logs bytes.Buffer
+	c, _ := newTestCollector(config.Collector{}, &logs)
+
+	if err := c.checkEventLayout(specWithEvent(t, expectedEventSize)); err != nil {
+		t.Errorf("matching probe layout: %v", err)
+	}
+	err := c.checkEventLayout(specWithEvent(t, expectedEventSize-8))
+	if err == nil || !strings.Contains(err.Error(), "rebuild the probe") {
+		t.Errorf("smaller probe struct: err = %v, want a layout mismatch", err)
+	}
+
+	// 没有 BTF 的探针只检查 Go 一侧
+	if err := c.checkEventLayout(&ebpf.CollectionSpec{}); err != nil {
+		t.Errorf("probe without BTF: %v", err)
+	}
+	if err := c.checkEventLayout(nil); err != nil {
+		t.Errorf("nil spec: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+// internal/collector/load.go
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+// loadObjects 加载 eBPF 程序和 maps。配置了 bpf_object_path 时从外部 .o 文件加载，
+// 便于调试单独编译的探针；未配置或加载失败时回退到 bpf2go 嵌入的对象。
+// 加载前会检查探针中 traffic_event 的布局是否与 TrafficEvent 一致
+func (c *Collector) loadObjects(objs *bpfObjects) error {
+	if path := c.cfg.BPFObjectPath; path != "" {
+		err := c.loadObjectsFromFile(objs, path)
+		if err == nil {
+			c.log.Info("Loaded eBPF objects from file", "path", path)
+			return nil
+		}
+		c.log.Warn("Failed to load eBPF objects from file, falling back to embedded objects", "path", path, "error", err)
+	}
+
+	spec, err := c.embeddedSpec()
+	if err != nil {
+		return fmt.Errorf("failed to load embedded eBPF spec: %w", err)
+	}
+	if err := c.checkEventLayout(spec); err != nil {
+		return err
+	}
+	opts, err := c.pinOptions(spec)
+	if err != nil {
+		return err
+	}
+	return c.conflictError(c.assign(spec, objs, opts))
+}
+
+// loadObjectsFromFile 从 ELF 文件加载 eBPF 对象，文件中的程序和 maps 名称必须与探针一致
+func (c *Collector) loadObjectsFromFile(objs *bpfObjects, path string) error {
+	spec, err := c.readSpec(path)
+	if err != nil {
+		return fmt.Errorf("failed to read eBPF object file: %w", err)
+	}
+	if err := c.checkEventLayout(spec); err != nil {
+		return err
+	}
+	opts, err := c.pinOptions(spec)
+	if err != nil {
+		return err
+	}
+	if err := c.assign(spec, objs, opts); err != nil {
+		return fmt.Errorf("failed to load eBPF object file: %w", c.conflictError(err))
+	}
+	return nil
+}
+
+// pinOptions 在配置了 pin_path 时将探针的 maps 设置为按名称固定到该目录，目录不存在时创建。
+// 目录中已有同名且定义一致的 map（例如上次运行留下的）会被复用。未配置时返回 nil，maps 不会被固定
+func (c *Collector) pinOptions(spec *ebpf.CollectionSpec) (*ebpf.CollectionOptions, error) {
+	dir := c.cfg.PinPath
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create BPF pin directory %s: %w", dir, err)
+	}
+	c.pinned = c.pinned[:0]
+	for name, m := range spec.Maps {
+		// .rodata 等由编译器生成的 maps 不固定
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		m.Pinning = ebpf.PinByName
+		c.pinned = append(c.pinned, name)
+	}
+	return &ebpf.CollectionOptions{Maps: ebpf.MapOptions{PinPath: dir}}, nil
+}
+
+// conflictError 为与同一主机上其他 eBPF 工具冲突导致的加载或附加失败补充说明，其他错误原样返回
+func (c *Collector) conflictError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ebpf.ErrMapIncompatible):
+		return fmt.Errorf("a map pinned in %s does not match the probe, the directory may be shared with another eBPF tool; set collector.pin_path to a directory used only by traffic-guardian: %w", c.cfg.PinPath, err)
+	case errors.Is(err, os.ErrExist):
+		return fmt.Errorf("eBPF object already exists, it may belong to another eBPF tool on this host: %w", err)
+	}
+	return err
+}
+
+// unpinMaps 在采集器退出时移除固定到 pin_path 的 maps，避免下次启动复用过期的聚合结果
+func (c *Collector) unpinMaps() {
+	for _, name := range c.pinned {
+		if err := os.Remove(filepath.Join(c.cfg.PinPath, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.log.Warn("Failed to unpin eBPF map", "map", name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// internal/collector/load_test.go
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestLoadObjectsSource(t *testing.T) {
+	fileSpec, embedded := &ebpf.CollectionSpec{}, &ebpf.CollectionSpec{}
+	tests := []struct {
+		name      string
+		path      string
+		readErr   error
+		assignErr map[*ebpf.CollectionSpec]error
+		want      *ebpf.CollectionSpec
+		wantErr   string
+		wantLog   string
+	}{
+		{name: "embedded by default", want: embedded},
+		{name: "file", path: "/opt/probe.o", want: fileSpec, wantLog: "Loaded eBPF objects from file"},
+		{name: "unreadable file falls back", path: "/opt/probe.o", readErr: errors.New("not an ELF file"), want: embedded, wantLog: "not an ELF file"},
+		{name: "file rejected by the kernel falls back", path: "/opt/probe.o",
+			assignErr: map[*ebpf.CollectionSpec]error{fileSpec: errors.New("verifier error")}, want: embedded, wantLog: "verifier error"},
+		{name: "embedded failure is fatal",
+			assignErr: map[*ebpf.CollectionSpec]error{embedded: errors.New("permission denied")}, want: embedded, wantErr: "permission denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			c, _ := newTestCollector(config.Collector{BPFObjectPath: tt.path}, &logs)
+			var read []string
+			c.readSpec = func(path string) (*ebpf.CollectionSpec, error) {
+				read = append(read, path)
+				return fileSpec, tt.readErr
+			}
+			c.embeddedSpec = func() (*ebpf.CollectionSpec, error) { return embedded, nil }
+			var loaded *ebpf.CollectionSpec
+			c.assign = func(spec *ebpf.CollectionSpec, _ *bpfObjects, _ *ebpf.CollectionOptions) error {
+				loaded = spec
+				return tt.assignErr[spec]
+			}
+
+			err := c.loadObjects(&bpfObjects{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadObjects = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("loadObjects: %v", err)
+			}
+			if loaded != tt.want {
+				t.Errorf("loaded the wrong spec (file = %v)", loaded == fileSpec)
+			}
+			if tt.path == "" && len(read) != 0 {
+				t.Errorf("read %v without bpf_object_path", read)
+			}
+			if tt.path != "" && (len(read) != 1 || read[0] != tt.path) {
+				t.Errorf("read %v, want %s", read, tt.path)
+			}
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("logs do not mention %q:\n%s", tt.wantLog, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// internal/collector/mapmode.go
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"traffic-guardian/internal/config"
+)
+
+// trafficKey mirrors struct traffic_key in probe.c
+type trafficKey struct {
+	PID  uint32
+	UID  uint32
+	Comm [commLen]byte
+}
+
+// trafficValue mirrors struct traffic_value in probe.c
+type trafficValue struct {
+	Bytes   uint64
+	Packets uint64
+}
+
+// applyMode 将采集模式写入 collect_mode map
+func applyMode(objs *bpfObjects, mode string) error {
+	var value uint32
+	if mode == config.CollectorModeMap {
+		value = 1
+	}
+	if err := objs.CollectMode.Put(uint32(0), value); err != nil {
+		return fmt.Errorf("failed to update collect_mode map: %w", err)
+	}
+	return nil
+}
+
+// applySampleRate 将采样率写入 sample_config map，0 和 1 表示不采样
+func applySampleRate(objs *bpfObjects, rate int) error {
+	if err := objs.SampleConfig.Put(uint32(0), uint32(max(rate, 1))); err != nil {
+		return fmt.Errorf("failed to update sample_config map: %w", err)
+	}
+	return nil
+}
+
+// applyMinEventBytes 将 min_event_bytes 写入 min_event_config map，0 表示所有数据包都发送事件
+func applyMinEventBytes(objs *bpfObjects, minBytes int) error {
+	if err := objs.MinEventConfig.Put(uint32(0), uint32(max(minBytes, 0))); err != nil {
+		return fmt.Errorf("failed to update min_event_config map: %w", err)
+	}
+	return nil
+}
+
+// pollMap 定时读取 traffic_totals 中每个进程的累计流量，将两次读取之间的增量作为事件发送。
+// 用于禁用了 bpf_perf_event_output 的环境；此模式下事件不包含对端地址和端口
+func (c *Collector) pollMap(ctx context.Context, objs *bpfObjects) error {
+	c.log.Info("Polling eBPF traffic map", "interval", c.cfg.GetMapPollInterval())
+	c.pollTotals(ctx, objs)
+	c.log.Info("eBPF collector stopped")
+	return nil
+}
+
+// pollTotals 每隔 map_poll_interval 读取一次 traffic_totals，直到上下文被取消
+func (c *Collector) pollTotals(ctx context.Context, objs *bpfObjects) {
+	ticker := time.NewTicker(c.cfg.GetMapPollInterval())
+	defer ticker.Stop()
+
+	// last 是上一次读取时每个键的累计流量
+	last := make(map[trafficKey]trafficValue)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.readTotals(ctx, objs, last); err != nil {
+				c.log.Error("Error reading traffic map", "error", err)
+			}
+		}
+	}
+}
+
+// readTotals 遍历 traffic_totals，汇总每个键在所有 CPU 上的值，并为增加的流量发送事件。
+// 条目被 LRU 淘汰后重新出现时累计值会从零开始，此时整个值都视为增量
+func (c *Collector) readTotals(ctx context.Context, objs *bpfObjects, last map[trafficKey]trafficValue) error {
+	var (
+		key    trafficKey
+		perCPU []trafficValue
+	)
+	seen := make(map[trafficKey]bool, len(last))
+	iter := objs.TrafficTotals.Iterate()
+	for iter.Next(&key, &perCPU) {
+		var total trafficValue
+		for _, v := range perCPU {
+			total.Bytes += v.Bytes
+			total.Packets += v.Packets
+		}
+		seen[key] = true
+
+		prev := last[key]
+		last[key] = total
+		delta := trafficValue{Bytes: total.Bytes - prev.Bytes, Packets: total.Packets - prev.Packets}
+		if total.Bytes < prev.Bytes || total.Packets < prev.Packets {
+			delta = total
+		}
+		if delta.Bytes == 0 {
+			continue
+		}
+
+		event := TrafficEvent{
+			PID:     key.PID,
+			UID:     key.UID,
+			Len:     delta.Bytes,
+			Comm:    key.Comm,
+			Packets: uint32(delta.Packets),
+		}
+		if !c.emit(ctx, event) {
+			return nil
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	// 删除已被淘汰的键
+	for k := range last {
+		if !seen[k] {
+			delete(last, k)
+		}
+	}
+	return nil
+}
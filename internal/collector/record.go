@@ -0,0 +1,200 @@
+// internal/collector/record.go
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// 录制文件的格式:
+//
+//	header: "TGEV" + uint16 版本号
+//	record: int64 录制时间 (Unix 纳秒，版本 2 起) + uint32 负载长度 + 负载 (以小端序编码的 TrafficEvent)
+//
+// 所有整数均为小端序。负载长度允许大于当前的 TrafficEvent，以便新版本追加字段后旧版本仍能回放；
+// 负载比当前的 TrafficEvent 短时（由追加字段之前的版本录制），缺少的字段视为零。
+// 版本 1 的文件没有录制时间，只能尽快回放
+const (
+	recordMagic   = "TGEV"
+	recordVersion = uint16(2)
+	// recordVersionUntimed 是没有录制时间的旧版本
+	recordVersionUntimed = uint16(1)
+)
+
+// recordOrder 是录制文件使用的字节序，与主机字节序无关，保证文件可以跨机器回放
+var recordOrder = binary.LittleEndian
+
+// Recorder 将采集到的原始事件写入文件，供之后通过 FileSource 回放
+type Recorder struct {
+	mu  sync.Mutex
+	f   *os.File
+	w   *bufio.Writer
+	buf bytes.Buffer
+}
+
+// NewRecorder 创建（或截断）录制文件并写入文件头
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create record file: %w", err)
+	}
+	r := &Recorder{f: f, w: bufio.NewWriter(f)}
+	if _, err := r.w.WriteString(recordMagic); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := binary.Write(r.w, recordOrder, recordVersion); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write 追加一条事件，录制时间为当前时间
+func (r *Recorder) Write(event TrafficEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.buf.Reset()
+	if err := binary.Write(&r.buf, recordOrder, &event); err != nil {
+		return err
+	}
+	if err := binary.Write(r.w, recordOrder, time.Now().UnixNano()); err != nil {
+		return err
+	}
+	if err := binary.Write(r.w, recordOrder, uint32(r.buf.Len())); err != nil {
+		return err
+	}
+	_, err := r.w.Write(r.buf.Bytes())
+	return err
+}
+
+// Close 写出缓冲区中的数据并关闭文件
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.w.Flush(); err != nil {
+		r.f.Close()
+		return err
+	}
+	return r.f.Close()
+}
+
+// 确保 FileSource 实现了 Source 接口
+var _ Source = (*FileSource)(nil)
+
+// FileSource 按顺序回放 Recorder 录制的事件，绕过 eBPF，用于调试和复现问题
+type FileSource struct {
+	path       string
+	eventsChan chan<- TrafficEvent
+	// speed 是相对于录制时的回放速度，例如 1 为原速、10 为十倍速；不大于 0 时尽快回放
+	speed float64
+}
+
+// NewFileSource 创建一个新的 FileSource 实例，speed 是回放速度，不大于 0 时尽快回放
+func NewFileSource(path string, eventsChan chan<- TrafficEvent, speed float64) *FileSource {
+	return &FileSource{path: path, eventsChan: eventsChan, speed: speed}
+}
+
+// Start 发送文件中的所有事件，然后阻塞直到上下文被取消。设置了回放速度时按录制时间的间隔
+// （除以 speed）发送，使时间窗口、速率等规则看到与录制时相同的流量节奏
+func (f *FileSource) Start(ctx context.Context) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to open record file: %w", err)
+	}
+	defer file.Close()
+	r := bufio.NewReader(file)
+
+	version, err := readRecordHeader(r)
+	if err != nil {
+		return err
+	}
+
+	var first int64
+	start := time.Now()
+	for {
+		recorded, event, err := readRecord(r, version)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if f.speed > 0 && recorded != 0 {
+			if first == 0 {
+				first = recorded
+			}
+			offset := time.Duration(float64(recorded-first) / f.speed)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(wait):
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case f.eventsChan <- event:
+		}
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// readRecordHeader 检查文件头并返回文件的版本
+func readRecordHeader(r io.Reader) (uint16, error) {
+	var header [len(recordMagic) + 2]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, fmt.Errorf("failed to read record file header: %w", err)
+	}
+	if string(header[:len(recordMagic)]) != recordMagic {
+		return 0, errors.New("not a traffic-guardian record file")
+	}
+	v := recordOrder.Uint16(header[len(recordMagic):])
+	if v != recordVersion && v != recordVersionUntimed {
+		return 0, fmt.Errorf("unsupported record file version %d", v)
+	}
+	return v, nil
+}
+
+// readRecord 读取一条事件及其录制时间（Unix 纳秒，旧版本的文件中为 0），文件正好结束时返回 io.EOF
+func readRecord(r io.Reader, version uint16) (int64, TrafficEvent, error) {
+	var recorded int64
+	if version != recordVersionUntimed {
+		if err := binary.Read(r, recordOrder, &recorded); err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, TrafficEvent{}, io.EOF
+			}
+			return 0, TrafficEvent{}, fmt.Errorf("truncated record file: %w", err)
+		}
+	}
+	var size uint32
+	if err := binary.Read(r, recordOrder, &size); err != nil {
+		if version == recordVersionUntimed && errors.Is(err, io.EOF) {
+			return 0, TrafficEvent{}, io.EOF
+		}
+		return 0, TrafficEvent{}, fmt.Errorf("truncated record file: %w", err)
+	}
+	// 旧版本录制的负载比当前的 TrafficEvent 短，缺少的字段补零
+	payload := make([]byte, max(int(size), eventSize))
+	if _, err := io.ReadFull(r, payload[:size]); err != nil {
+		return 0, TrafficEvent{}, fmt.Errorf("truncated record file: %w", err)
+	}
+	event, err := decodeEvent(payload, recordOrder)
+	return recorded, event, err
+}
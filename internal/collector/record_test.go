@@ -0,0 +1,82 @@
+// internal/collector/record_test.go
+package collector
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordEvents 将 events 录制到临时文件并返回文件路径
+func recordEvents(t *testing.T, events []TrafficEvent) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "events.tgev")
+	rec, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		if err := rec.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// replay 尽快回放录制文件，返回回放出的所有事件
+func replay(t *testing.T, path string) []TrafficEvent {
+	t.Helper()
+	ch := make(chan TrafficEvent, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewFileSource(path, ch, 0).Start(ctx) }()
+
+	var got []TrafficEvent
+	for {
+		select {
+		case e := <-ch:
+			got = append(got, e)
+			continue
+		case err := <-done:
+			t.Fatalf("FileSource returned before the context was cancelled: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+		break
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("FileSource: %v", err)
+	}
+	return got
+}
+
+func TestRecordReplay(t *testing.T) {
+	var events []TrafficEvent
+	for i := range 20 {
+		e := sampleEvent()
+		e.PID = uint32(4000 + i%3)
+		e.Len = uint64(100 * (i + 1))
+		events = append(events, e)
+	}
+	exit := TrafficEvent{PID: 4001, Kind: EventExit}
+	events = append(events, exit)
+
+	got := replay(t, recordEvents(t, events))
+	if len(got) != len(events) {
+		t.Fatalf("replayed %d events, want %d", len(got), len(events))
+	}
+	totals := make(map[uint32]uint64)
+	for i := range events {
+		if got[i] != events[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], events[i])
+		}
+		totals[got[i].PID] += got[i].Len
+	}
+	if totals[4000] != 100+400+700+1000+1300+1600+1900 {
+		t.Errorf("replayed totals = %v", totals)
+	}
+}
@@ -0,0 +1,80 @@
+// internal/collector/sample_test.go
+package collector
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// sampleStream 按 probe.c 中 sample() 的算法对数据包长度采样：每个 CPU 各自计数，
+// 每 rate 个数据包发送一个，长度乘以 rate。packets[i] 在 CPU i%cpus 上发送，返回发送的事件长度
+func sampleStream(packets []uint64, cpus int, rate uint64) []uint64 {
+	counters := make([]uint64, cpus)
+	var sent []uint64
+	for i, n := range packets {
+		if rate <= 1 {
+			sent = append(sent, n)
+			continue
+		}
+		cpu := i % cpus
+		counters[cpu]++
+		if counters[cpu]%rate != 0 {
+			continue
+		}
+		sent = append(sent, n*rate)
+	}
+	return sent
+}
+
+func totalLen(lens []uint64) uint64 {
+	var total uint64
+	for _, n := range lens {
+		total += n
+	}
+	return total
+}
+
+func TestSampleScaling(t *testing.T) {
+	// 长度在 64 到 1500 字节之间均匀分布的数据包流
+	rng := rand.New(rand.NewSource(1))
+	mixed := make([]uint64, 200000)
+	for i := range mixed {
+		mixed[i] = uint64(64 + rng.Intn(1437))
+	}
+	uniform := make([]uint64, 12000)
+	for i := range uniform {
+		uniform[i] = 1500
+	}
+
+	tests := []struct {
+		name      string
+		packets   []uint64
+		cpus      int
+		rate      uint64
+		tolerance float64
+	}{
+		{"disabled", mixed, 4, 0, 0},
+		{"rate 1", mixed, 4, 1, 0},
+		// 每个 CPU 上的数据包数量是采样率的整数倍，长度相同时总量精确
+		{"uniform", uniform, 4, 10, 0},
+		{"mixed rate 10", mixed, 4, 10, 0.02},
+		{"mixed rate 100", mixed, 8, 100, 0.05},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := sampleStream(tt.packets, tt.cpus, tt.rate)
+			want, got := totalLen(tt.packets), totalLen(sent)
+			if diff := math.Abs(float64(got)-float64(want)) / float64(want); diff > tt.tolerance {
+				t.Errorf("scaled total = %d, want %d within %.0f%% (off by %.2f%%)", got, want, tt.tolerance*100, diff*100)
+			}
+			wantEvents := len(tt.packets)
+			if tt.rate > 1 {
+				wantEvents /= int(tt.rate)
+			}
+			if len(sent) != wantEvents {
+				t.Errorf("sent %d events, want %d", len(sent), wantEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// internal/collector/source.go
+package collector
+
+import "context"
+
+// Source 是流量事件来源的抽象，实现者需要将事件写入构造时传入的 channel，
+// 并在上下文被取消后返回
+type Source interface {
+	Start(ctx context.Context) error
+}
+
+// 确保 Collector 实现了 Source 接口
+var _ Source = (*Collector)(nil)
+
+// FakeSource 是一个内存中的事件来源，按顺序发送预先编排好的事件，
+// 用于在没有 eBPF/root 权限的环境下驱动整条处理流水线
+type FakeSource struct {
+	eventsChan chan<- TrafficEvent
+	events     []TrafficEvent
+}
+
+// NewFakeSource 创建一个新的 FakeSource 实例
+func NewFakeSource(eventsChan chan<- TrafficEvent, events []TrafficEvent) *FakeSource {
+	return &FakeSource{
+		eventsChan: eventsChan,
+		events:     events,
+	}
+}
+
+// Start 依次发送所有编排好的事件，然后阻塞直到上下文被取消
+func (f *FakeSource) Start(ctx context.Context) error {
+	for _, event := range f.events {
+		select {
+		case <-ctx.Done():
+			return nil
+		case f.eventsChan <- event:
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
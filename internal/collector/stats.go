@@ -0,0 +1,98 @@
+// internal/collector/stats.go
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// rateWindow 是计算事件速率和流量速率的滑动窗口长度，按秒分桶
+const rateWindow = 10 * time.Second
+
+// Stats 是采集器自启动以来的吞吐量统计，用于判断采集器是否跟得上内核产生事件的速度。
+// 探针只采集发送流量，因此只有 TX 字节数
+type Stats struct {
+	// UptimeSeconds 是采集器启动以来的时间（单位: 秒），尚未启动时为 0
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	// Events 和 TXBytes 是发送到状态管理器的事件数和其中的字节数之和，不包括退出事件的字节数（为 0）
+	Events  uint64 `json:"events"`
+	TXBytes uint64 `json:"tx_bytes"`
+	// EventsPerSecond 和 TXBytesPerSecond 是最近 10 秒内的平均速率
+	EventsPerSecond  float64 `json:"events_per_second"`
+	TXBytesPerSecond float64 `json:"tx_bytes_per_second"`
+	// LostSamples 是 perf 缓冲区写满时内核丢弃的事件数，持续增长说明需要增大 perf_buffer_pages 或配置 sample_rate；
+	// ShortRecords 是因长度不足而被丢弃的 perf 记录数
+	LostSamples  uint64 `json:"lost_samples"`
+	ShortRecords uint64 `json:"short_records"`
+}
+
+// rateBucket 是一秒内的事件数和字节数
+type rateBucket struct {
+	second int64
+	events uint64
+	bytes  uint64
+}
+
+// throughput 统计事件的总数和最近 rateWindow 内每秒的事件数与字节数
+type throughput struct {
+	mu      sync.Mutex
+	events  uint64
+	bytes   uint64
+	buckets [int(rateWindow / time.Second)]rateBucket
+}
+
+// add 在 now 所在的一秒内计入一个事件
+func (t *throughput) add(now time.Time, bytes uint64) {
+	sec := now.Unix()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.events++
+	t.bytes += bytes
+	b := &t.buckets[sec%int64(len(t.buckets))]
+	if b.second != sec {
+		*b = rateBucket{second: sec}
+	}
+	b.events++
+	b.bytes += bytes
+}
+
+// snapshot 返回事件总数、字节总数，以及 now 之前 rateWindow 内（不包括当前未结束的一秒）的平均速率
+func (t *throughput) snapshot(now time.Time) (events, bytes uint64, eventRate, byteRate float64) {
+	sec := now.Unix()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var windowEvents, windowBytes uint64
+	for _, b := range t.buckets {
+		if b.second < sec && b.second >= sec-int64(len(t.buckets)) {
+			windowEvents += b.events
+			windowBytes += b.bytes
+		}
+	}
+	seconds := float64(len(t.buckets))
+	return t.events, t.bytes, float64(windowEvents) / seconds, float64(windowBytes) / seconds
+}
+
+// Stats 返回采集器的吞吐量统计
+func (c *Collector) Stats() Stats {
+	now := time.Now()
+	events, bytes, eventRate, byteRate := c.throughput.snapshot(now)
+	s := Stats{
+		Events:           events,
+		TXBytes:          bytes,
+		EventsPerSecond:  eventRate,
+		TXBytesPerSecond: byteRate,
+		LostSamples:      c.lostSamples.Load(),
+		ShortRecords:     c.shortRecords.Load(),
+	}
+	if started := c.startedAt.Load(); started != 0 {
+		s.UptimeSeconds = now.Sub(time.Unix(0, started)).Seconds()
+	}
+	return s
+}
+
+// LostSamples 返回 perf 缓冲区写满时内核丢弃的事件数
+func (c *Collector) LostSamples() uint64 {
+	return c.lostSamples.Load()
+}
@@ -0,0 +1,60 @@
+// internal/collector/stats_test.go
+package collector
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf/perf"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestThroughput(t *testing.T) {
+	var tp throughput
+	start := time.Unix(1700000000, 0)
+
+	// 30 秒前的事件已经移出窗口，但仍计入总数
+	tp.add(start.Add(-30*time.Second), 5000)
+	// 过去 3 秒每秒 100 个 1000 字节的事件
+	for sec := range 3 {
+		for range 100 {
+			tp.add(start.Add(time.Duration(sec)*time.Second), 1000)
+		}
+	}
+	// 当前尚未结束的一秒不计入速率
+	now := start.Add(3 * time.Second)
+	tp.add(now, 9000)
+
+	events, total, eventRate, byteRate := tp.snapshot(now)
+	if events != 302 || total != 314000 {
+		t.Errorf("totals = %d events, %d bytes; want 302, 314000", events, total)
+	}
+	// 窗口内的 300 个事件按 10 秒平均
+	if eventRate != 30 || byteRate != 30000 {
+		t.Errorf("rates = %v events/s, %v B/s; want 30, 30000", eventRate, byteRate)
+	}
+
+	// 窗口过去之后速率归零
+	if _, _, eventRate, byteRate := tp.snapshot(now.Add(time.Minute)); eventRate != 0 || byteRate != 0 {
+		t.Errorf("rates a minute later = %v events/s, %v B/s; want 0", eventRate, byteRate)
+	}
+}
+
+func TestLostSamplesAreCounted(t *testing.T) {
+	var logs bytes.Buffer
+	c, ch := newTestCollector(config.Collector{}, &logs)
+	c.handleRecord(context.Background(), perf.Record{LostSamples: 7})
+	c.handleRecord(context.Background(), perf.Record{LostSamples: 5})
+	if got := c.LostSamples(); got != 12 {
+		t.Errorf("LostSamples = %d, want 12", got)
+	}
+	if s := c.Stats(); s.LostSamples != 12 || s.Events != 0 || s.UptimeSeconds != 0 {
+		t.Errorf("Stats = %+v, want 12 lost samples, no events and no uptime before Start", s)
+	}
+	if c.ShortRecords() != 0 || len(ch) != 0 {
+		t.Errorf("lost-sample records counted as short (%d) or emitted (%d)", c.ShortRecords(), len(ch))
+	}
+}
@@ -0,0 +1,69 @@
+// internal/config/adaptive.go
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// AdaptiveInterval 定义了规则检查间隔的自适应调整：接近阈值的进程较多时缩短间隔以更快响应，
+// 没有接近阈值的进程时延长间隔以减少无用的检查，间隔始终在 [MinIntervalSeconds, MaxIntervalSeconds] 之内
+type AdaptiveInterval struct {
+	Enabled bool `yaml:"enabled"`
+	// MinIntervalSeconds 和 MaxIntervalSeconds 是检查间隔的上下限，默认为 check_interval_seconds 的 1/4 和 4 倍
+	MinIntervalSeconds int `yaml:"min_interval_seconds"`
+	MaxIntervalSeconds int `yaml:"max_interval_seconds"`
+	// NearThresholdPercent 是视为"接近阈值"的流量或速率占阈值的百分比，默认为 80
+	NearThresholdPercent int `yaml:"near_threshold_percent"`
+	// BusyProcesses 是缩短间隔所需的接近阈值的进程数量，默认为 3
+	BusyProcesses int `yaml:"busy_processes"`
+}
+
+// 未配置自适应检查间隔的参数时使用的默认值
+const (
+	DefaultNearThresholdPercent = 80
+	DefaultBusyProcesses        = 3
+)
+
+// GetBounds 是一个辅助函数，返回检查间隔的上下限，未配置时根据基础间隔 base 计算
+func (a *AdaptiveInterval) GetBounds(base time.Duration) (min, max time.Duration) {
+	min, max = base/4, base*4
+	if a.MinIntervalSeconds > 0 {
+		min = time.Duration(a.MinIntervalSeconds) * time.Second
+	}
+	if a.MaxIntervalSeconds > 0 {
+		max = time.Duration(a.MaxIntervalSeconds) * time.Second
+	}
+	return min, max
+}
+
+// GetNearThresholdRatio 是一个辅助函数，将 near_threshold_percent 转换为比例，未配置时使用默认值
+func (a *AdaptiveInterval) GetNearThresholdRatio() float64 {
+	if a.NearThresholdPercent <= 0 {
+		return DefaultNearThresholdPercent / 100.0
+	}
+	return float64(a.NearThresholdPercent) / 100
+}
+
+// GetBusyProcesses 是一个辅助函数，返回缩短间隔所需的接近阈值的进程数量，未配置时使用默认值
+func (a *AdaptiveInterval) GetBusyProcesses() int {
+	if a.BusyProcesses <= 0 {
+		return DefaultBusyProcesses
+	}
+	return a.BusyProcesses
+}
+
+// validate 检查上下限是否包含基础间隔 base，以及百分比是否合法
+func (a *AdaptiveInterval) validate(base time.Duration) error {
+	if a.MinIntervalSeconds < 0 || a.MaxIntervalSeconds < 0 || a.BusyProcesses < 0 {
+		return fmt.Errorf("min_interval_seconds, max_interval_seconds and busy_processes must not be negative")
+	}
+	if a.NearThresholdPercent < 0 || a.NearThresholdPercent > 100 {
+		return fmt.Errorf("near_threshold_percent must be in (0, 100]")
+	}
+	min, max := a.GetBounds(base)
+	if min <= 0 || min > base || max < base {
+		return fmt.Errorf("interval bounds [%s, %s] must contain check_interval_seconds (%s)", min, max, base)
+	}
+	return nil
+}
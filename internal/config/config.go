@@ -2,17 +2,96 @@
 package config
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"traffic-guardian/internal/match"
 )
 
 // Config 结构体完整地映射了 config.yaml 文件的结构
 type Config struct {
-	LogLevel string  `yaml:"log_level"`
-	Rules    Rules   `yaml:"rules"`
-	Alerter  Alerter `yaml:"alerter"`
+	LogLevel string `yaml:"log_level"`
+	// LogOutput 是日志的输出目标: stdout（默认）、stderr、syslog 或文件路径，文件按大小轮转，收到 SIGHUP 时重新打开
+	LogOutput string `yaml:"log_output"`
+	// LogMaxSizeMB 和 LogMaxBackups 是日志文件轮转的大小阈值（默认 100 MB）和保留的备份数量（默认 3 个）
+	LogMaxSizeMB  int `yaml:"log_max_size_mb"`
+	LogMaxBackups int `yaml:"log_max_backups"`
+	// ShutdownTimeoutSeconds 是退出时等待每个组件停止的最长时间，超时的组件被放弃，默认为 30 秒
+	ShutdownTimeoutSeconds int       `yaml:"shutdown_timeout_seconds"`
+	Rules                  Rules     `yaml:"rules"`
+	Alerter                Alerter   `yaml:"alerter"`
+	API                    API       `yaml:"api"`
+	Collector              Collector `yaml:"collector"`
+	Monitor                Monitor   `yaml:"monitor"`
+	History                History   `yaml:"history"`
+	Metrics                Metrics   `yaml:"metrics"`
+}
+
+// 未配置日志文件轮转参数时使用的默认值
+const (
+	DefaultLogMaxSizeMB  = 100
+	DefaultLogMaxBackups = 3
+)
+
+// GetLogMaxSizeBytes 是一个辅助函数，返回日志文件轮转的大小阈值，未配置时使用默认值
+func (c *Config) GetLogMaxSizeBytes() int64 {
+	size := c.LogMaxSizeMB
+	if size <= 0 {
+		size = DefaultLogMaxSizeMB
+	}
+	return int64(size) * 1024 * 1024
+}
+
+// GetLogMaxBackups 是一个辅助函数，返回保留的日志备份数量，未配置时使用默认值
+func (c *Config) GetLogMaxBackups() int {
+	if c.LogMaxBackups <= 0 {
+		return DefaultLogMaxBackups
+	}
+	return c.LogMaxBackups
+}
+
+// DefaultShutdownTimeout 是未配置 shutdown_timeout_seconds 时使用的默认值
+const DefaultShutdownTimeout = 30 * time.Second
+
+// GetShutdownTimeout 是一个辅助函数，返回退出时等待每个组件停止的最长时间，未配置时使用默认值
+func (c *Config) GetShutdownTimeout() time.Duration {
+	if c.ShutdownTimeoutSeconds <= 0 {
+		return DefaultShutdownTimeout
+	}
+	return time.Duration(c.ShutdownTimeoutSeconds) * time.Second
+}
+
+// Direction 表示流量的方向
+type Direction string
+
+const (
+	// DirectionTX 表示发送（出站）流量
+	DirectionTX Direction = "tx"
+	// DirectionRX 表示接收（入站）流量
+	DirectionRX Direction = "rx"
+)
+
+// Severity 表示警报的严重级别
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Raise 返回提升 n 级后的严重级别，最高为 critical
+func (s Severity) Raise(n int) Severity {
+	levels := []Severity{SeverityInfo, SeverityWarning, SeverityCritical}
+	i := slices.Index(levels, s)
+	if i < 0 {
+		return s
+	}
+	return levels[min(i+n, len(levels)-1)]
 }
 
 // Rules 定义了流量监控和警报的规则
@@ -21,11 +100,222 @@ type Rules struct {
 	TimeWindowMinutes    int `yaml:"time_window_minutes"`
 	CheckIntervalSeconds int `yaml:"check_interval_seconds"`
 	AlertCooldownMinutes int `yaml:"alert_cooldown_minutes"`
+	// PerUserThresholdMB 是单个用户所有进程流量之和的阈值，0 表示不启用
+	PerUserThresholdMB int `yaml:"per_user_threshold_mb"`
+	// HostEgressThresholdMB 是所有进程流量之和（整台主机的出口流量）的阈值，0 表示不启用。
+	// 与上面的单一规则字段不同，配置了 definitions 时它仍然生效，见 HostEgressRuleName
+	HostEgressThresholdMB int `yaml:"host_egress_threshold_mb"`
+	// BlocklistCIDRs 是禁止通信的网段或地址（例如威胁情报源），任意进程与其中的地址通信时发出 critical 警报，
+	// 与流量大小无关。通常通过 blocklist_cidrs_file 从文件读取，重新加载配置时更新，见 BlocklistRuleName
+	BlocklistCIDRs []string `yaml:"blocklist_cidrs"`
+	// Severity 是规则触发时警报的严重级别: info, warning, critical，默认为 warning
+	Severity Severity `yaml:"severity"`
+	// MatchComms 限定流量阈值规则只作用于进程名匹配的进程，为空时作用于所有进程
+	MatchComms []string `yaml:"match_comms"`
+	// Definitions 是同时生效的命名规则列表；为空时根据上面的单一规则字段生成
+	Definitions []RuleDefinition `yaml:"definitions"`
+	// EWMAAlpha 是速率指数加权移动平均的平滑系数 (0, 1]，越小越平滑，默认为 0.3
+	EWMAAlpha float64 `yaml:"ewma_alpha"`
+	// Anomaly 是未配置 definitions 时的突增检测规则
+	Anomaly Anomaly `yaml:"anomaly"`
+	// GracePeriodSeconds 是进程开始被跟踪后不触发进程规则的时长，用于忽略启动时的流量突发，0 表示不启用
+	GracePeriodSeconds int `yaml:"grace_period_seconds"`
+	// MuteWindows 是静默时间段，期间规则照常检查但不发送警报（例如夜间备份）
+	MuteWindows []MuteWindow `yaml:"mute_windows"`
+	// ExitReport 在被跟踪的进程退出时发送一条包含其最终流量的摘要
+	ExitReport ExitReport `yaml:"exit_report"`
+	// Digest 定期发送一条列出流量最多的进程的摘要，与阈值无关
+	Digest Digest `yaml:"digest"`
+	// AdaptiveInterval 在负载变化时自动调整规则检查间隔
+	AdaptiveInterval AdaptiveInterval `yaml:"adaptive_interval"`
+	// CheckJitterPercent 让规则检查和状态清理的间隔在每个周期随机增减最多这个百分比（0 到 50），
+	// 使大量主机上的实例错开检查时间，避免同时向 Telegram API 发送警报。0 表示不启用
+	CheckJitterPercent int `yaml:"check_jitter_percent"`
+	// AuditLog 不为空时，每一次规则评估（对象、流量或速率、阈值和判断结果）都以 JSON 行的形式写入此处，
+	// 用于调优阈值: stdout、stderr 或文件路径（按 log_max_size_mb 轮转）。评估次数与进程数成正比，生产环境中不建议开启
+	AuditLog string `yaml:"audit_log"`
+	// InternalCIDRs 是内部网络的地址段，发往这些地址的流量计为内部流量，
+	// traffic / per_user 规则默认只统计外部流量，见 RuleDefinition.Scope
+	InternalCIDRs []string `yaml:"internal_cidrs"`
+	// DefaultsFile 是按进程名提供默认阈值的表文件，表项作为 traffic 规则追加在显式规则之后，见 CommDefault。
+	// 相对路径相对于配置它的配置文件所在目录
+	DefaultsFile string `yaml:"defaults_file"`
+
+	// commDefaults 是从 DefaultsFile 加载的默认阈值表
+	commDefaults []CommDefault
+}
+
+// ExitReport 定义了进程退出摘要，用于不会持续到触发阈值规则的短时大流量进程
+type ExitReport struct {
+	Enabled bool `yaml:"enabled"`
+	// MinTotalMB 是发送摘要所需的最低累计流量（单位: MB），0 表示所有退出的进程都发送
+	MinTotalMB int `yaml:"min_total_mb"`
+	// Severity 是摘要的严重级别，默认为 info
+	Severity Severity `yaml:"severity"`
+	// Alerters 是摘要的目标警报器名称，为空时按严重级别路由
+	Alerters []string `yaml:"alerters"`
+}
+
+// ExitReportRuleName 是进程退出摘要在警报中使用的规则名称
+const ExitReportRuleName = "process_exit"
+
+// GetMinTotalBytes 是一个辅助函数，将摘要的最低累计流量从MB转换为Bytes
+func (x *ExitReport) GetMinTotalBytes() uint64 {
+	return uint64(x.MinTotalMB) * 1024 * 1024
+}
+
+// GetSeverity 是一个辅助函数，返回摘要的严重级别，未配置时默认为 info
+func (x *ExitReport) GetSeverity() Severity {
+	if x.Severity == "" {
+		return SeverityInfo
+	}
+	return x.Severity
+}
+
+// Digest 定义了定期发送的流量摘要，用于在没有触发规则时也了解主机上的主要流量来源
+type Digest struct {
+	Enabled bool `yaml:"enabled"`
+	// IntervalMinutes 是发送摘要的间隔（单位: 分钟），默认为 60
+	IntervalMinutes int `yaml:"interval_minutes"`
+	// TopN 是摘要中列出的进程数量，默认为 10
+	TopN int `yaml:"top_n"`
+	// Severity 是摘要的严重级别，默认为 info
+	Severity Severity `yaml:"severity"`
+	// Alerters 是摘要的目标警报器名称，为空时按严重级别路由
+	Alerters []string `yaml:"alerters"`
+}
+
+// DigestRuleName 是流量摘要在警报中使用的规则名称
+const DigestRuleName = "digest"
+
+const (
+	// DefaultDigestInterval 是未配置 interval_minutes 时发送摘要的间隔
+	DefaultDigestInterval = time.Hour
+	// DefaultDigestTopN 是未配置 top_n 时摘要中列出的进程数量
+	DefaultDigestTopN = 10
+)
+
+// GetInterval 是一个辅助函数，返回发送摘要的间隔，未配置时使用默认值
+func (d *Digest) GetInterval() time.Duration {
+	if d.IntervalMinutes <= 0 {
+		return DefaultDigestInterval
+	}
+	return time.Duration(d.IntervalMinutes) * time.Minute
+}
+
+// GetTopN 是一个辅助函数，返回摘要中列出的进程数量，未配置时使用默认值
+func (d *Digest) GetTopN() int {
+	if d.TopN <= 0 {
+		return DefaultDigestTopN
+	}
+	return d.TopN
+}
+
+// GetSeverity 是一个辅助函数，返回摘要的严重级别，未配置时默认为 info
+func (d *Digest) GetSeverity() Severity {
+	if d.Severity == "" {
+		return SeverityInfo
+	}
+	return d.Severity
+}
+
+// Anomaly 定义了基于统计的突增检测：当进程最新的速率样本超过其
+// EWMA 均值 + Sigma × EWMA 标准差时报警，与固定阈值无关
+type Anomaly struct {
+	// Sigma 是标准差倍数，0 表示不启用
+	Sigma float64 `yaml:"sigma"`
+	// WarmupSamples 是开始检测前每个进程需要积累的基线样本数，默认为 5
+	WarmupSamples int `yaml:"warmup_samples"`
+	// MinRateKBps 是报警所需的最低速率（单位: KB/s），避免几乎空闲的进程因微小波动报警
+	MinRateKBps int `yaml:"min_rate_kb_per_second"`
+}
+
+// Monitor 定义了状态管理器按进程名过滤事件的规则，模式语法见 match 包
+type Monitor struct {
+	// Include 不为空时，只统计进程名匹配其中任意模式的进程
+	Include []string `yaml:"include"`
+	// Exclude 中匹配的进程不会被统计，优先于 Include
+	Exclude []string `yaml:"exclude"`
+	// Allowlist 中的目的地址和端口被视为"免费"流量，不计入 TotalBytes
+	Allowlist Allowlist `yaml:"allowlist"`
+	// KeepKernelThreads 为 true 时统计 PID 0 和内核线程的流量；默认丢弃，因为这类流量无法归属到用户空间进程
+	KeepKernelThreads bool `yaml:"keep_kernel_threads"`
+	// BatchSize 大于 1 时，状态管理器在一次加锁中处理最多这么多个已经到达的事件，用于降低高事件速率下的锁开销。
+	// 只合并 channel 中已有的事件，不会为了凑满一批而等待，0 或 1 表示逐个处理
+	BatchSize int `yaml:"batch_size"`
+}
+
+// Allowlist 定义了不计入流量统计的目的地，CIDR 或端口匹配其一即被排除
+type Allowlist struct {
+	CIDRs []string `yaml:"cidrs"`
+	Ports []uint16 `yaml:"ports"`
 }
 
 // Alerter 定义了所有可能的警报渠道
 type Alerter struct {
 	Telegram TelegramConfig `yaml:"telegram"`
+	Exec     ExecConfig     `yaml:"exec"`
+	Journal  JournalConfig  `yaml:"journal"`
+	File     FileConfig     `yaml:"file"`
+	// Routing 将严重级别映射到接收该级别警报的警报器名称，未配置的级别发送给所有警报器
+	Routing map[Severity][]string `yaml:"routing"`
+	// HistorySize 是内存中保留的最近警报数量，可通过 API 的 /alerts 查询
+	HistorySize int `yaml:"history_size"`
+	// ValidateOnStartup 为 true 时在启动时检查每个已启用警报器的连通性和凭据
+	ValidateOnStartup bool `yaml:"validate_on_startup"`
+	// AbortOnInvalid 为 true 时，启动自检失败会中止启动；否则只记录错误
+	AbortOnInvalid bool `yaml:"abort_on_invalid"`
+	// ReverseDNS 将警报中的对端 IP 反向解析为主机名
+	ReverseDNS ReverseDNS `yaml:"reverse_dns"`
+	// Timezone 是消息模板中 formatTime 使用的 IANA 时区，例如 "Asia/Shanghai"，为空时使用本地时区
+	Timezone string `yaml:"timezone"`
+	// Locale 是内置消息格式使用的语言: en 或 zh，默认为 en。自定义消息模板不受影响
+	Locale string `yaml:"locale"`
+}
+
+// 内置消息格式支持的语言
+const (
+	LocaleEnglish = "en"
+	LocaleChinese = "zh"
+)
+
+// GetLocale 是一个辅助函数，返回内置消息格式使用的语言，未配置时使用英文
+func (a *Alerter) GetLocale() string {
+	if a.Locale == "" {
+		return LocaleEnglish
+	}
+	return a.Locale
+}
+
+// ReverseDNS 定义了警报中对端 IP 的反向解析，解析在警报处理器中进行，不会阻塞规则引擎
+type ReverseDNS struct {
+	Enabled bool `yaml:"enabled"`
+	// TimeoutMS 是单次解析的超时时间，默认为 500 毫秒
+	TimeoutMS int `yaml:"timeout_ms"`
+	// CacheTTLMinutes 是解析结果（包括失败）的缓存时间，默认为 60 分钟
+	CacheTTLMinutes int `yaml:"cache_ttl_minutes"`
+}
+
+// 未配置反向解析的超时和缓存时间时使用的默认值
+const (
+	DefaultReverseDNSTimeout  = 500 * time.Millisecond
+	DefaultReverseDNSCacheTTL = time.Hour
+)
+
+// GetTimeout 是一个辅助函数，返回单次解析的超时时间，未配置时使用默认值
+func (d *ReverseDNS) GetTimeout() time.Duration {
+	if d.TimeoutMS <= 0 {
+		return DefaultReverseDNSTimeout
+	}
+	return time.Duration(d.TimeoutMS) * time.Millisecond
+}
+
+// GetCacheTTL 是一个辅助函数，返回解析结果的缓存时间，未配置时使用默认值
+func (d *ReverseDNS) GetCacheTTL() time.Duration {
+	if d.CacheTTLMinutes <= 0 {
+		return DefaultReverseDNSCacheTTL
+	}
+	return time.Duration(d.CacheTTLMinutes) * time.Minute
 }
 
 // TelegramConfig 定义了 Telegram 警报器的具体配置
@@ -33,22 +323,467 @@ type TelegramConfig struct {
 	Enabled  bool   `yaml:"enabled"`
 	BotToken string `yaml:"bot_token"`
 	ChatID   string `yaml:"chat_id"`
+	// MessageTemplate 是自定义的消息模板 (Go text/template)，以警报为数据执行，为空时使用内置格式
+	MessageTemplate string `yaml:"message_template"`
+	// TimeoutSeconds 是单次发送允许的最长时间，超时视为发送失败，默认为 10 秒
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+	// AckMinutes 不为空时，警报消息附带对应时长（单位: 分钟）的确认按钮，点击后在该时长内不再对同一对象报警。
+	// 按钮通过 getUpdates 长轮询接收，Bot 不能同时配置 webhook 或被其他程序轮询；只接受来自 ChatID 的确认
+	AckMinutes []int `yaml:"ack_minutes"`
+}
+
+// DefaultAlerterTimeout 是未配置警报器 timeout_seconds 时单次发送的超时时间
+const DefaultAlerterTimeout = 10 * time.Second
+
+// GetTimeout 是一个辅助函数，返回单次发送的超时时间，未配置时使用默认值
+func (t *TelegramConfig) GetTimeout() time.Duration {
+	if t.TimeoutSeconds <= 0 {
+		return DefaultAlerterTimeout
+	}
+	return time.Duration(t.TimeoutSeconds) * time.Second
+}
+
+// ExecConfig 定义了外部命令警报器的配置，警报以 JSON 写入命令的 stdin，常用字段同时以 TG_* 环境变量传递
+type ExecConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Command 是要执行的命令，不经过 shell；Args 是传给命令的参数
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	// TimeoutSeconds 是单次执行允许的最长时间，超时后命令被杀死并视为发送失败，默认为 10 秒
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+}
+
+// GetTimeout 是一个辅助函数，返回单次执行的超时时间，未配置时使用默认值
+func (x *ExecConfig) GetTimeout() time.Duration {
+	if x.TimeoutSeconds <= 0 {
+		return DefaultAlerterTimeout
+	}
+	return time.Duration(x.TimeoutSeconds) * time.Second
+}
+
+// JournalConfig 定义了 systemd journal 警报器的配置，警报通过 journald 原生协议以结构化字段写入
+type JournalConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Identifier 是写入 journal 的 SYSLOG_IDENTIFIER，默认为 "traffic-guardian"
+	Identifier string `yaml:"identifier"`
+	// SocketPath 是 journald 原生协议的套接字路径，默认为 /run/systemd/journal/socket
+	SocketPath string `yaml:"socket_path"`
+}
+
+// 未配置 journal 警报器的标识符和套接字路径时使用的默认值
+const (
+	DefaultJournalIdentifier = "traffic-guardian"
+	DefaultJournalSocket     = "/run/systemd/journal/socket"
+)
+
+// GetIdentifier 是一个辅助函数，返回写入 journal 的标识符，未配置时使用默认值
+func (j *JournalConfig) GetIdentifier() string {
+	if j.Identifier == "" {
+		return DefaultJournalIdentifier
+	}
+	return j.Identifier
+}
+
+// GetSocketPath 是一个辅助函数，返回 journald 的套接字路径，未配置时使用默认值
+func (j *JournalConfig) GetSocketPath() string {
+	if j.SocketPath == "" {
+		return DefaultJournalSocket
+	}
+	return j.SocketPath
+}
+
+// FileConfig 定义了文件警报器的配置，每条警报以一行 JSON 追加到文件
+type FileConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+	// MaxSizeMB 和 MaxBackups 控制文件轮转，未配置时与日志文件相同，默认为 100MB 和 3 个备份
+	MaxSizeMB  int `yaml:"max_size_mb"`
+	MaxBackups int `yaml:"max_backups"`
+	// Fsync 为 true 时每条警报写入后都同步到磁盘，掉电时不丢失已发送的警报
+	Fsync bool `yaml:"fsync"`
+}
+
+// GetMaxSizeBytes 是一个辅助函数，返回警报文件轮转的大小阈值，未配置时使用默认值
+func (f *FileConfig) GetMaxSizeBytes() int64 {
+	size := f.MaxSizeMB
+	if size <= 0 {
+		size = DefaultLogMaxSizeMB
+	}
+	return int64(size) * 1024 * 1024
+}
+
+// GetMaxBackups 是一个辅助函数，返回保留的警报文件备份数量，未配置时使用默认值
+func (f *FileConfig) GetMaxBackups() int {
+	if f.MaxBackups <= 0 {
+		return DefaultLogMaxBackups
+	}
+	return f.MaxBackups
+}
+
+// API 定义了本地控制 API 的配置
+type API struct {
+	Enabled       bool   `yaml:"enabled"`
+	ListenAddress string `yaml:"listen_address"`
+	// MaxWSClients 限制同时连接 /ws 的订阅者数量
+	MaxWSClients int `yaml:"max_ws_clients"`
+	// HealthStaleSeconds 是 /healthz 允许的最长无事件时间，超过后返回 503，默认为 300 秒
+	HealthStaleSeconds int `yaml:"health_stale_seconds"`
+}
+
+// DefaultHealthStale 是未配置 health_stale_seconds 时使用的默认值
+const DefaultHealthStale = 5 * time.Minute
+
+// GetHealthStale 是一个辅助函数，返回 /healthz 允许的最长无事件时间，未配置时使用默认值
+func (a *API) GetHealthStale() time.Duration {
+	if a.HealthStaleSeconds <= 0 {
+		return DefaultHealthStale
+	}
+	return time.Duration(a.HealthStaleSeconds) * time.Second
+}
+
+// Metrics 定义了 Prometheus 指标的导出方式，/metrics 端点由 API 提供
+type Metrics struct {
+	Pushgateway Pushgateway `yaml:"pushgateway"`
+	// MaxComms 是 comm_bytes 指标导出的进程名数量上限，其余的汇总到 comm="other"，默认为 50
+	MaxComms int `yaml:"max_comms"`
+}
+
+// DefaultMaxComms 是未配置 max_comms 时使用的默认值
+const DefaultMaxComms = 50
+
+// GetMaxComms 是一个辅助函数，返回 comm_bytes 指标导出的进程名数量上限，未配置时使用默认值
+func (m *Metrics) GetMaxComms() int {
+	if m.MaxComms <= 0 {
+		return DefaultMaxComms
+	}
+	return m.MaxComms
+}
+
+// Pushgateway 定义了运行结束时将指标推送到 Prometheus Pushgateway 的配置
+type Pushgateway struct {
+	// URL 是 Pushgateway 的地址，为空表示不推送
+	URL string `yaml:"url"`
+	// Job 是推送时使用的 job 名称，默认为 traffic_guardian
+	Job string `yaml:"job"`
+}
+
+// DefaultPushgatewayJob 是未配置 job 时使用的名称
+const DefaultPushgatewayJob = "traffic_guardian"
+
+// IsEnabled 检查是否配置了 Pushgateway
+func (p *Pushgateway) IsEnabled() bool {
+	return p.URL != ""
+}
+
+// GetJob 是一个辅助函数，返回推送时使用的 job 名称，未配置时使用默认值
+func (p *Pushgateway) GetJob() string {
+	if p.Job == "" {
+		return DefaultPushgatewayJob
+	}
+	return p.Job
+}
+
+// History 定义了将进程流量快照写入本地 sqlite 数据库的配置
+type History struct {
+	// DBPath 是数据库文件路径，为空表示不启用
+	DBPath string `yaml:"db_path"`
+	// SampleIntervalSeconds 是写入快照的间隔，默认为 60 秒
+	SampleIntervalSeconds int `yaml:"sample_interval_seconds"`
+	// RetentionDays 是样本的保留天数，默认为 7 天
+	RetentionDays int `yaml:"retention_days"`
+}
+
+// IsEnabled 检查是否启用了历史数据库
+func (h *History) IsEnabled() bool {
+	return h.DBPath != ""
+}
+
+// 未配置 history 的采样间隔和保留天数时使用的默认值
+const (
+	DefaultHistorySampleInterval = 60 * time.Second
+	DefaultHistoryRetentionDays  = 7
+)
+
+// GetSampleInterval 是一个辅助函数，返回写入快照的间隔，未配置时使用默认值
+func (h *History) GetSampleInterval() time.Duration {
+	if h.SampleIntervalSeconds <= 0 {
+		return DefaultHistorySampleInterval
+	}
+	return time.Duration(h.SampleIntervalSeconds) * time.Second
+}
+
+// GetRetention 是一个辅助函数，返回样本的保留时长，未配置时使用默认值
+func (h *History) GetRetention() time.Duration {
+	days := h.RetentionDays
+	if days <= 0 {
+		days = DefaultHistoryRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// Collector 定义了 eBPF 采集器的配置
+type Collector struct {
+	// PerfBufferPages 是每个 CPU 的 perf buffer 大小（单位: 内存页）
+	PerfBufferPages int `yaml:"perf_buffer_pages"`
+	// Include 是需要跟踪的进程白名单，为空时跟踪所有进程
+	Include Include `yaml:"include"`
+	// ByteOrder 是解码内核事件时使用的字节序: native, little, big，默认为 native
+	ByteOrder string `yaml:"byte_order"`
+	// BPFObjectPath 是外部编译的探针 .o 文件路径，为空时使用嵌入的对象
+	BPFObjectPath string `yaml:"bpf_object_path"`
+	// Mode 是内核向用户空间传递流量的方式: perf (逐个事件，默认) 或 map (按进程聚合后定时读取)
+	Mode string `yaml:"mode"`
+	// MapPollIntervalMS 是 map 模式下读取聚合结果的间隔，默认为 1000 毫秒
+	MapPollIntervalMS int `yaml:"map_poll_interval_ms"`
+	// RecordPath 不为空时将采集到的事件录制到此文件，之后可以用 -replay 回放
+	RecordPath string `yaml:"record_path"`
+	// SelfCheck 在启动后确认事件确实从探针到达了用户空间
+	SelfCheck SelfCheck `yaml:"self_check"`
+	// SampleRate 大于 1 时，perf 模式下每个 CPU 只发送每 N 个数据包中的一个，长度乘以 N 以保持累计流量近似不变。
+	// 用于事件量极大的主机；对端数量、主要目的地和按端口统计因此变为近似值。map 模式在内核中精确累加，不受影响
+	SampleRate int `yaml:"sample_rate"`
+	// MinEventBytes 大于 0 时，perf 模式下小于此长度的数据包不发送事件，而是在内核中按进程累加并每隔
+	// map_poll_interval_ms 读取一次，累计流量保持精确，但这部分流量没有对端地址和端口（计为外部流量）
+	MinEventBytes int `yaml:"min_event_bytes"`
+	// EnrichComm 为 true 时，第一次见到进程时从 /proc 读取未被截断的进程名（内核只保留 15 个字符），
+	// 用于 monitor 过滤、规则的 match_comms 和显示。eBPF 中的 include.comms 白名单仍然使用截断后的名称
+	EnrichComm bool `yaml:"enrich_comm"`
+	// PinPath 不为空时将探针的 maps 按名称固定 (pin) 到此 bpffs 目录，便于 bpftool 等工具查看。
+	// 该目录应由本程序独占，避免与同一主机上其他 eBPF 工具的同名 maps 冲突；为空表示不固定
+	PinPath string `yaml:"pin_path"`
+}
+
+// SelfCheck 定义了采集器的启动自检：产生少量本地流量，并等待状态管理器在超时前收到事件
+type SelfCheck struct {
+	Enabled bool `yaml:"enabled"`
+	// TimeoutSeconds 是等待事件的最长时间，默认为 10 秒
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+}
+
+// DefaultSelfCheckTimeout 是未配置 self_check.timeout_seconds 时使用的默认值
+const DefaultSelfCheckTimeout = 10 * time.Second
+
+// GetTimeout 是一个辅助函数，返回自检等待事件的最长时间，未配置时使用默认值
+func (s *SelfCheck) GetTimeout() time.Duration {
+	if s.TimeoutSeconds <= 0 {
+		return DefaultSelfCheckTimeout
+	}
+	return time.Duration(s.TimeoutSeconds) * time.Second
+}
+
+// 采集器支持的模式
+const (
+	CollectorModePerf = "perf"
+	CollectorModeMap  = "map"
+)
+
+// DefaultMapPollInterval 是未配置 map_poll_interval_ms 时使用的默认值
+const DefaultMapPollInterval = time.Second
+
+// GetMode 是一个辅助函数，返回采集模式，未配置时默认为 perf
+func (c *Collector) GetMode() string {
+	if c.Mode == "" {
+		return CollectorModePerf
+	}
+	return c.Mode
+}
+
+// GetMapPollInterval 是一个辅助函数，返回 map 模式下的读取间隔，未配置时使用默认值
+func (c *Collector) GetMapPollInterval() time.Duration {
+	if c.MapPollIntervalMS <= 0 {
+		return DefaultMapPollInterval
+	}
+	return time.Duration(c.MapPollIntervalMS) * time.Millisecond
+}
+
+// Include 定义了采集器的进程白名单，PID 或进程名匹配其一即被跟踪
+type Include struct {
+	PIDs  []uint32 `yaml:"pids"`
+	Comms []string `yaml:"comms"`
+}
+
+// IsEmpty 检查白名单是否为空
+func (i *Include) IsEmpty() bool {
+	return len(i.PIDs) == 0 && len(i.Comms) == 0
+}
+
+// DefaultPerfBufferPages 是未配置 perf_buffer_pages 时使用的默认值
+const DefaultPerfBufferPages = 64
+
+// GetPerfBufferPages 是一个辅助函数，返回每个 CPU 的 perf buffer 页数，未配置时使用默认值
+func (c *Collector) GetPerfBufferPages() int {
+	if c.PerfBufferPages <= 0 {
+		return DefaultPerfBufferPages
+	}
+	return c.PerfBufferPages
 }
 
-// LoadConfig 从指定路径读取并解析 YAML 配置文件
+// LoadConfig 从指定路径读取并解析 YAML 配置文件，文件中的 include 会被展开
 func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+	return LoadConfigs([]string{path})
+}
+
+// DefaultConfig 返回没有配置文件时使用的基础配置: info 级别日志，单个进程 1 小时内超过 1 GB 时报警，
+// 不启用任何警报器（警报只写入日志和历史记录），其余字段使用各自的默认值
+func DefaultConfig() *Config {
+	return &Config{
+		LogLevel: "info",
+		Rules: Rules{
+			TrafficThresholdMB:   1024,
+			TimeWindowMinutes:    60,
+			CheckIntervalSeconds: 30,
+			AlertCooldownMinutes: 60,
+			Severity:             SeverityWarning,
+		},
+	}
+}
+
+// Validate 检查配置是否合法，返回所有发现的问题
+func (c *Config) Validate() error {
+	var errs []error
+
+	patterns := map[string][]string{
+		"rules.match_comms": c.Rules.MatchComms,
+		"monitor.include":   c.Monitor.Include,
+		"monitor.exclude":   c.Monitor.Exclude,
+	}
+	for i, d := range c.Rules.Definitions {
+		patterns[fmt.Sprintf("rules.definitions[%d].match_comms", i)] = d.MatchComms
+	}
+	for field, list := range patterns {
+		if _, err := match.CompileAll(list); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+	}
+
+	if _, err := match.ParsePrefixes(c.Monitor.Allowlist.CIDRs); err != nil {
+		errs = append(errs, fmt.Errorf("monitor.allowlist.cidrs: %w", err))
+	}
+	if _, err := match.ParsePrefixes(c.Rules.InternalCIDRs); err != nil {
+		errs = append(errs, fmt.Errorf("rules.internal_cidrs: %w", err))
+	}
+
+	switch c.Collector.ByteOrder {
+	case "", "native", "little", "big":
+	default:
+		errs = append(errs, fmt.Errorf("collector.byte_order: unknown value %q (want native, little or big)", c.Collector.ByteOrder))
+	}
+
+	switch c.Collector.Mode {
+	case "", CollectorModePerf, CollectorModeMap:
+	default:
+		errs = append(errs, fmt.Errorf("collector.mode: unknown value %q (want %s or %s)", c.Collector.Mode, CollectorModePerf, CollectorModeMap))
+	}
+
+	if c.Collector.SampleRate < 0 {
+		errs = append(errs, fmt.Errorf("collector.sample_rate: must not be negative"))
+	}
+	if c.Collector.MinEventBytes < 0 {
+		errs = append(errs, fmt.Errorf("collector.min_event_bytes: must not be negative"))
 	}
 
-	var cfg Config
-	err = yaml.Unmarshal(data, &cfg)
-	if err != nil {
-		return nil, err
+	if c.Collector.PinPath != "" && !filepath.IsAbs(c.Collector.PinPath) {
+		errs = append(errs, fmt.Errorf("collector.pin_path: must be an absolute path, got %q", c.Collector.PinPath))
 	}
 
-	return &cfg, nil
+	// 时间窗口和检查间隔没有默认值，为 0 时状态无法过期、检查定时器无法创建
+	if c.Rules.TimeWindowMinutes <= 0 {
+		errs = append(errs, fmt.Errorf("rules.time_window_minutes: must be positive, got %d", c.Rules.TimeWindowMinutes))
+	}
+	if c.Rules.CheckIntervalSeconds <= 0 {
+		errs = append(errs, fmt.Errorf("rules.check_interval_seconds: must be positive, got %d", c.Rules.CheckIntervalSeconds))
+	}
+
+	if c.Rules.EWMAAlpha < 0 || c.Rules.EWMAAlpha > 1 {
+		errs = append(errs, fmt.Errorf("rules.ewma_alpha: must be in (0, 1], got %v", c.Rules.EWMAAlpha))
+	}
+
+	a := c.Rules.Anomaly
+	if a.Sigma < 0 || a.WarmupSamples < 0 || a.MinRateKBps < 0 {
+		errs = append(errs, fmt.Errorf("rules.anomaly: sigma, warmup_samples and min_rate_kb_per_second must not be negative"))
+	}
+
+	if c.Alerter.Timezone != "" {
+		if _, err := time.LoadLocation(c.Alerter.Timezone); err != nil {
+			errs = append(errs, fmt.Errorf("alerter.timezone: %w", err))
+		}
+	}
+
+	switch c.Alerter.Locale {
+	case "", LocaleEnglish, LocaleChinese:
+	default:
+		errs = append(errs, fmt.Errorf("alerter.locale: unknown value %q (want %s or %s)", c.Alerter.Locale, LocaleEnglish, LocaleChinese))
+	}
+
+	if c.Rules.HostEgressThresholdMB < 0 {
+		errs = append(errs, fmt.Errorf("rules.host_egress_threshold_mb: must not be negative"))
+	}
+	if _, err := match.ParsePrefixes(c.Rules.BlocklistCIDRs); err != nil {
+		errs = append(errs, fmt.Errorf("rules.blocklist_cidrs: %w", err))
+	}
+
+	if c.Monitor.BatchSize < 0 {
+		errs = append(errs, fmt.Errorf("monitor.batch_size: must not be negative"))
+	}
+
+	if c.Alerter.Exec.Enabled && c.Alerter.Exec.Command == "" {
+		errs = append(errs, fmt.Errorf("alerter.exec.command: required when the exec alerter is enabled"))
+	}
+	for _, m := range c.Alerter.Telegram.AckMinutes {
+		if m <= 0 {
+			errs = append(errs, fmt.Errorf("alerter.telegram.ack_minutes: must be positive, got %d", m))
+			break
+		}
+	}
+	if c.Alerter.File.Enabled && c.Alerter.File.Path == "" {
+		errs = append(errs, fmt.Errorf("alerter.file.path: required when the file alerter is enabled"))
+	}
+
+	if c.Rules.ExitReport.MinTotalMB < 0 {
+		errs = append(errs, fmt.Errorf("rules.exit_report.min_total_mb: must not be negative"))
+	}
+	switch c.Rules.ExitReport.Severity {
+	case "", SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		errs = append(errs, fmt.Errorf("rules.exit_report: unknown severity %q", c.Rules.ExitReport.Severity))
+	}
+
+	if c.Rules.Digest.IntervalMinutes < 0 || c.Rules.Digest.TopN < 0 {
+		errs = append(errs, fmt.Errorf("rules.digest: interval_minutes and top_n must not be negative"))
+	}
+	switch c.Rules.Digest.Severity {
+	case "", SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		errs = append(errs, fmt.Errorf("rules.digest: unknown severity %q", c.Rules.Digest.Severity))
+	}
+
+	if c.Rules.AdaptiveInterval.Enabled {
+		if err := c.Rules.AdaptiveInterval.validate(c.Rules.GetCheckInterval()); err != nil {
+			errs = append(errs, fmt.Errorf("rules.adaptive_interval: %w", err))
+		}
+	}
+
+	if c.Rules.CheckJitterPercent < 0 || c.Rules.CheckJitterPercent > MaxCheckJitterPercent {
+		errs = append(errs, fmt.Errorf("rules.check_jitter_percent: must be between 0 and %d", MaxCheckJitterPercent))
+	}
+
+	if c.Rules.GracePeriodSeconds < 0 {
+		errs = append(errs, fmt.Errorf("rules.grace_period_seconds: must not be negative"))
+	}
+
+	if c.History.SampleIntervalSeconds < 0 || c.History.RetentionDays < 0 {
+		errs = append(errs, fmt.Errorf("history: sample_interval_seconds and retention_days must not be negative"))
+	}
+
+	for i, w := range c.Rules.MuteWindows {
+		if err := w.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rules.mute_windows[%d]: %w", i, err))
+		}
+	}
+
+	errs = append(errs, c.Rules.validateDefinitions()...)
+
+	return errors.Join(errs...)
 }
 
 // GetTrafficThresholdBytes 是一个辅助函数，将MB转换为Bytes
@@ -56,6 +791,45 @@ func (r *Rules) GetTrafficThresholdBytes() uint64 {
 	return uint64(r.TrafficThresholdMB) * 1024 * 1024
 }
 
+// GetPerUserThresholdBytes 是一个辅助函数，将每用户阈值从MB转换为Bytes
+func (r *Rules) GetPerUserThresholdBytes() uint64 {
+	return uint64(r.PerUserThresholdMB) * 1024 * 1024
+}
+
+// GetSeverity 是一个辅助函数，返回规则的严重级别，未配置时默认为 warning
+func (r *Rules) GetSeverity() Severity {
+	if r.Severity == "" {
+		return SeverityWarning
+	}
+	return r.Severity
+}
+
+// DefaultEWMAAlpha 是未配置 ewma_alpha 时使用的平滑系数
+const DefaultEWMAAlpha = 0.3
+
+// GetEWMAAlpha 是一个辅助函数，返回速率 EWMA 的平滑系数，未配置时使用默认值
+func (r *Rules) GetEWMAAlpha() float64 {
+	if r.EWMAAlpha <= 0 || r.EWMAAlpha > 1 {
+		return DefaultEWMAAlpha
+	}
+	return r.EWMAAlpha
+}
+
+// IsMuted 检查 t 是否落在任意一个静默时间段内
+func (r *Rules) IsMuted(t time.Time) bool {
+	for i := range r.MuteWindows {
+		if r.MuteWindows[i].Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetGracePeriod 是一个辅助函数，将秒转换为 time.Duration
+func (r *Rules) GetGracePeriod() time.Duration {
+	return time.Duration(r.GracePeriodSeconds) * time.Second
+}
+
 // GetTimeWindow 是一个辅助函数，将分钟转换为 time.Duration
 func (r *Rules) GetTimeWindow() time.Duration {
 	return time.Duration(r.TimeWindowMinutes) * time.Minute
@@ -66,6 +840,18 @@ func (r *Rules) GetCheckInterval() time.Duration {
 	return time.Duration(r.CheckIntervalSeconds) * time.Second
 }
 
+// MaxCheckJitterPercent 是 check_jitter_percent 允许的最大值，保证抖动后的间隔至少是原来的一半
+const MaxCheckJitterPercent = 50
+
+// Jitter 返回在 d 上随机增减最多 percent% 之后的间隔，percent 不大于 0 时原样返回
+func Jitter(d time.Duration, percent int) time.Duration {
+	if percent <= 0 || d <= 0 {
+		return d
+	}
+	spread := int64(d) * int64(percent) / 100
+	return d + time.Duration(rand.Int64N(2*spread+1)-spread)
+}
+
 // GetAlertCooldown 是一个辅助函数，将分钟转换为 time.Duration
 func (r *Rules) GetAlertCooldown() time.Duration {
 	return time.Duration(r.AlertCooldownMinutes) * time.Minute
@@ -0,0 +1,33 @@
+// internal/config/config_test.go
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigRejectsInvalidPatterns(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "config.yaml", `
+monitor:
+  include: ["nginx*"]
+  exclude: ["re:("]
+rules:`+minimalRules+`
+  definitions:
+    - name: "bad"
+      type: "traffic"
+      threshold_mb: 10
+      match_comms: ["worker[0-9"]
+`)
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatal("LoadConfig succeeded, want pattern errors")
+	}
+	for _, want := range []string{"monitor.exclude", "rules.definitions[0].match_comms"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "monitor.include") {
+		t.Errorf("valid monitor.include reported as invalid: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+// internal/config/defaults.go
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"traffic-guardian/internal/match"
+)
+
+// CommDefault 是默认阈值表中的一项，为进程名匹配 Comm 的进程提供一条 traffic 规则
+type CommDefault struct {
+	// Comm 是进程名或 match 包支持的模式
+	Comm            string   `yaml:"comm"`
+	ThresholdMB     int      `yaml:"threshold_mb"`
+	Severity        Severity `yaml:"severity"`
+	CooldownMinutes int      `yaml:"cooldown_minutes"`
+}
+
+// DefaultsRulePrefix 是由默认阈值表生成的规则名称的前缀
+const DefaultsRulePrefix = "default:"
+
+// defaultsFile 是默认阈值表文件的结构
+type defaultsFile struct {
+	Defaults []CommDefault `yaml:"defaults"`
+}
+
+// LoadCommDefaults 读取并校验默认阈值表文件，返回所有问题
+func LoadCommDefaults(path string) ([]CommDefault, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file defaultsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+	for i, d := range file.Defaults {
+		field := fmt.Sprintf("%s: defaults[%d]", path, i)
+		if d.Comm == "" {
+			errs = append(errs, fmt.Errorf("%s: comm is required", field))
+		} else if _, err := match.Compile(d.Comm); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		} else if seen[d.Comm] {
+			errs = append(errs, fmt.Errorf("%s: duplicate comm %q", field, d.Comm))
+		}
+		seen[d.Comm] = true
+		if d.ThresholdMB <= 0 {
+			errs = append(errs, fmt.Errorf("%s: threshold_mb must be positive", field))
+		}
+		if d.CooldownMinutes < 0 {
+			errs = append(errs, fmt.Errorf("%s: cooldown_minutes must not be negative", field))
+		}
+		switch d.Severity {
+		case "", SeverityInfo, SeverityWarning, SeverityCritical:
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown severity %q", field, d.Severity))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return file.Defaults, nil
+}
+
+// resolveDefaultsFile 将文档中相对的 rules.defaults_file 转换为相对于 dir（引用它的配置文件所在目录）的路径，
+// 与 include 和 X_file 引用一致，合并多个文件之后就无法知道它来自哪个文件
+func resolveDefaultsFile(doc any, dir string) {
+	m, _ := doc.(map[string]any)
+	rules, _ := m["rules"].(map[string]any)
+	path, ok := rules["defaults_file"].(string)
+	if !ok || path == "" || filepath.IsAbs(path) {
+		return
+	}
+	rules["defaults_file"] = filepath.Join(dir, path)
+}
+
+// loadDefaults 在配置了 defaults_file 时加载默认阈值表
+func (r *Rules) loadDefaults() error {
+	if r.DefaultsFile == "" {
+		return nil
+	}
+	defaults, err := LoadCommDefaults(r.DefaultsFile)
+	if err != nil {
+		return fmt.Errorf("rules.defaults_file: %w", err)
+	}
+	r.commDefaults = defaults
+	return nil
+}
+
+// mergeCommDefaults 将默认阈值表生成的规则追加到 defs 之后。显式配置的规则优先:
+// 同名的 traffic 规则，或者 match_comms 中包含相同进程名的 traffic 规则存在时，对应的表项被跳过
+func mergeCommDefaults(defs []RuleDefinition, defaults []CommDefault) []RuleDefinition {
+	for _, d := range defaults {
+		name := DefaultsRulePrefix + d.Comm
+		overridden := slices.ContainsFunc(defs, func(def RuleDefinition) bool {
+			return def.Type == RuleTypeTraffic && (def.Name == name || slices.Contains(def.MatchComms, d.Comm))
+		})
+		if overridden {
+			continue
+		}
+		defs = append(defs, RuleDefinition{
+			Name:            name,
+			Type:            RuleTypeTraffic,
+			ThresholdMB:     d.ThresholdMB,
+			Severity:        d.Severity,
+			CooldownMinutes: d.CooldownMinutes,
+			MatchComms:      []string{d.Comm},
+		})
+	}
+	return defs
+}
@@ -0,0 +1,114 @@
+// internal/config/defaults_test.go
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFile 在 dir 中写入一个文件并返回其路径，必要时创建子目录
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// minimalRules 是通过校验所需的最少规则字段
+const minimalRules = `
+  time_window_minutes: 60
+  check_interval_seconds: 30
+`
+
+func TestCommDefaultsExplicitRulesWin(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "defaults.yaml", `
+defaults:
+  - comm: "rsync"
+    threshold_mb: 20480
+  - comm: "backup*"
+    threshold_mb: 4096
+    severity: "info"
+  - comm: "curl"
+    threshold_mb: 100
+`)
+	path := writeFile(t, dir, "config.yaml", `
+rules:`+minimalRules+`
+  defaults_file: "defaults.yaml"
+  definitions:
+    - name: "rsync-quota"
+      type: "traffic"
+      threshold_mb: 1024
+      match_comms: ["rsync"]
+    - name: "default:curl"
+      type: "traffic"
+      threshold_mb: 10
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]RuleDefinition)
+	var names []string
+	for _, d := range cfg.Rules.GetDefinitions() {
+		got[d.Name] = d
+		names = append(names, d.Name)
+	}
+	want := []string{"rsync-quota", "default:curl", "default:backup*"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("rules = %v, want %v", names, want)
+	}
+	if d := got["default:curl"]; d.ThresholdMB != 10 {
+		t.Errorf("default:curl threshold = %d MB, want the explicit 10 MB", d.ThresholdMB)
+	}
+	if d := got["default:backup*"]; d.ThresholdMB != 4096 || d.Severity != SeverityInfo || d.MatchComms[0] != "backup*" {
+		t.Errorf("default:backup* = %+v, want the table entry", d)
+	}
+}
+
+func TestDefaultsFileRelativeToConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "conf/defaults.yaml", "defaults:\n  - comm: \"rsync\"\n    threshold_mb: 2048\n")
+	path := writeFile(t, dir, "conf/config.yaml", "rules:"+minimalRules+"  defaults_file: \"defaults.yaml\"\n")
+
+	// 测试的工作目录是包目录，相对路径只有相对于配置文件才能找到
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs := cfg.Rules.GetDefinitions()
+	if len(defs) != 1 || defs[0].Name != "default:rsync" || defs[0].ThresholdMB != 2048 {
+		t.Errorf("rules = %+v, want only default:rsync from conf/defaults.yaml", defs)
+	}
+}
+
+func TestCommDefaultsValidation(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "defaults.yaml", `
+defaults:
+  - comm: ""
+    threshold_mb: 100
+  - comm: "rsync"
+    threshold_mb: 0
+  - comm: "rsync"
+    threshold_mb: 10
+    severity: "loud"
+`)
+	_, err := LoadCommDefaults(path)
+	if err == nil {
+		t.Fatal("LoadCommDefaults succeeded, want errors")
+	}
+	for _, want := range []string{"comm is required", "threshold_mb must be positive", `duplicate comm "rsync"`, `unknown severity "loud"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// internal/config/export.go
+package config
+
+import "gopkg.in/yaml.v3"
+
+// Redacted 返回配置的副本，Bot Token 等凭据只保留末尾几个字符，用于通过 API 导出配置
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Alerter.Telegram.BotToken = maskSecret(c.Alerter.Telegram.BotToken)
+	return &redacted
+}
+
+// Document 返回以 YAML 键名表示的配置，与配置文件的结构一致，可以直接编码为 JSON。
+// 未配置的字段为零值，实际使用的默认值见 Summary 和 GetDefinitions
+func (c *Config) Document() (map[string]any, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
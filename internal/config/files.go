@@ -0,0 +1,186 @@
+// internal/config/files.go
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileSuffix 是引用外部文件的字段后缀: 字段 X 的值可以通过 X_file 从文件读取，
+// 例如 bot_token_file 读取 Docker secrets 等挂载的凭据，monitor.exclude_file 读取较长的进程名列表
+const fileSuffix = "_file"
+
+// gzipMagic 是 gzip 数据的前两个字节
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// readConfigFile 读取配置文件或被引用的文件，gzip 压缩的文件（按文件头识别）自动解压
+func readConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer zr.Close()
+	data, err = io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
+
+// expandFileRefs 按 Config 的结构遍历一个配置文档，将其中的 X_file 替换为从文件读取的 X。
+// 字符串字段取文件的全部内容（去掉末尾的换行），列表字段每个非空行是一个元素（# 开头的行是注释），
+// 并追加在同一文件中内联的元素之后。相对路径相对于 dir，即引用它的配置文件所在目录
+func expandFileRefs(doc any, dir string) error {
+	return expandNode(doc, reflect.TypeOf(Config{}), dir, "")
+}
+
+// expandNode 递归处理文档中的一个节点，t 是节点对应的 Go 类型，field 是用于错误信息的字段路径
+func expandNode(node any, t reflect.Type, dir, field string) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch n := node.(type) {
+	case map[string]any:
+		switch t.Kind() {
+		case reflect.Struct:
+			return expandStruct(n, t, dir, field)
+		case reflect.Map:
+			for k, v := range n {
+				if err := expandNode(v, t.Elem(), dir, joinField(field, k)); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if t.Kind() != reflect.Slice {
+			return nil
+		}
+		for i, item := range n {
+			if err := expandNode(item, t.Elem(), dir, fmt.Sprintf("%s[%d]", field, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expandStruct 处理结构体对应的映射，按键的顺序展开 X_file 并递归处理其他字段
+func expandStruct(n map[string]any, t reflect.Type, dir, field string) error {
+	fields := yamlFields(t)
+	keys := make([]string, 0, len(n))
+	for k := range n {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if ft, ok := fields[key]; ok {
+			if err := expandNode(n[key], ft, dir, joinField(field, key)); err != nil {
+				return err
+			}
+			continue
+		}
+		name, ok := strings.CutSuffix(key, fileSuffix)
+		if !ok {
+			continue
+		}
+		ft, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if err := expandFile(n, key, name, ft, dir, joinField(field, key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandFile 读取 n[key] 引用的文件，将内容写入 n[name] 并删除 n[key]
+func expandFile(n map[string]any, key, name string, t reflect.Type, dir, field string) error {
+	path, ok := n[key].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("%s: expected a file path", field)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	data, err := readConfigFile(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	delete(n, key)
+
+	switch {
+	case t.Kind() == reflect.String:
+		if _, inline := n[name]; inline {
+			return fmt.Errorf("%s: cannot be used together with %s", field, name)
+		}
+		n[name] = strings.TrimRight(string(data), "\r\n")
+	case t.Kind() == reflect.Slice:
+		items, _ := n[name].([]any)
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if t.Elem().Kind() == reflect.String {
+				items = append(items, line)
+				continue
+			}
+			// 非字符串列表（例如 PID）的每一行按 YAML 标量解析
+			var v any
+			if err := yaml.Unmarshal([]byte(line), &v); err != nil {
+				return fmt.Errorf("%s: %s: %w", field, path, err)
+			}
+			items = append(items, v)
+		}
+		n[name] = items
+	default:
+		return fmt.Errorf("%s: %s cannot be read from a file", field, name)
+	}
+	return nil
+}
+
+// yamlFields 返回结构体中 yaml 键到字段类型的映射，不导出的字段和 yaml:"-" 被忽略
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// joinField 拼接用于错误信息的字段路径
+func joinField(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
@@ -0,0 +1,191 @@
+// internal/config/merge.go
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey 是顶层的 include 指令，值为要先行合并的其他配置文件列表
+const includeKey = "include"
+
+// mergeKey 是列表元素的标识字段，元素都带有此字段的列表按它合并，例如 rules.definitions
+const mergeKey = "name"
+
+// LoadConfigs 按顺序读取多个 YAML 文件并深度合并，然后校验合并结果。
+// 后面文件中的标量覆盖前面的值，映射逐键合并；元素都带有 name 字段的列表按 name 合并
+// （同名元素深度合并，新名称追加到末尾），其他列表整体替换。
+// 每个文件都可以通过顶层的 include 引用其他文件，见 loadDocument
+func LoadConfigs(paths []string) (*Config, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no config files given")
+	}
+
+	var merged any
+	for _, path := range paths {
+		doc, err := loadDocument(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeValues(merged, doc)
+	}
+
+	// 将合并后的文档重新编码，以便使用与单个文件相同的解析规则
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Rules.loadDefaults(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadDocument 读取一个 YAML 文件（可以是 gzip 压缩的）并递归展开其中的 include 和 X_file 引用。
+// 被引用的文件按顺序先行合并，引用它们的文件最后合并，因此本地的值覆盖共享的基础配置。
+// 相对路径相对于引用它的文件所在目录，stack 是当前的引用链，用于检测循环引用
+func loadDocument(path string, stack []string) (any, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range stack {
+		if p == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], abs), " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := expandFileRefs(doc, filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	resolveDefaultsFile(doc, filepath.Dir(path))
+
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return doc, nil
+	}
+	raw, ok := m[includeKey]
+	if !ok {
+		return doc, nil
+	}
+	delete(m, includeKey)
+
+	includes, err := includeList(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var merged any
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(path), inc)
+		}
+		included, err := loadDocument(inc, stack)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		merged = mergeValues(merged, included)
+	}
+	return mergeValues(merged, m), nil
+}
+
+// includeList 将 include 的值解析为文件列表，允许单个字符串或字符串列表
+func includeList(raw any) ([]string, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		paths := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include: expected a list of file paths, got %v", item)
+			}
+			paths = append(paths, s)
+		}
+		return paths, nil
+	default:
+		return nil, fmt.Errorf("include: expected a file path or a list of file paths")
+	}
+}
+
+// mergeValues 将 overlay 深度合并到 base 上，返回合并结果；overlay 为 nil（空文件或 null）时保留 base
+func mergeValues(base, overlay any) any {
+	if overlay == nil {
+		return base
+	}
+	switch o := overlay.(type) {
+	case map[string]any:
+		b, ok := base.(map[string]any)
+		if !ok {
+			return o
+		}
+		for k, v := range o {
+			b[k] = mergeValues(b[k], v)
+		}
+		return b
+	case []any:
+		b, ok := base.([]any)
+		if !ok || !keyedList(b) || !keyedList(o) {
+			return o
+		}
+		return mergeKeyedLists(b, o)
+	default:
+		return overlay
+	}
+}
+
+// keyedList 检查列表是否非空且每个元素都是带有 name 字段的映射
+func keyedList(list []any) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return false
+		}
+		if _, ok := m[mergeKey].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeKeyedLists 按 name 合并两个列表，保持 base 中的顺序，overlay 中的新元素追加到末尾
+func mergeKeyedLists(base, overlay []any) []any {
+	index := make(map[string]int, len(base))
+	for i, item := range base {
+		index[item.(map[string]any)[mergeKey].(string)] = i
+	}
+	for _, item := range overlay {
+		name := item.(map[string]any)[mergeKey].(string)
+		if i, ok := index[name]; ok {
+			base[i] = mergeValues(base[i], item)
+			continue
+		}
+		index[name] = len(base)
+		base = append(base, item)
+	}
+	return base
+}
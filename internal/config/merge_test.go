@@ -0,0 +1,92 @@
+// internal/config/merge_test.go
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIncludeMergesBaseAndOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "shared/base.yaml", `
+rules:`+minimalRules+`
+  alert_cooldown_minutes: 30
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 1024
+      severity: "warning"
+    - name: "fast"
+      type: "rate"
+      threshold_kb_per_second: 500
+alerter:
+  telegram:
+    enabled: true
+    bot_token: "123:shared"
+    chat_id: "-100"
+`)
+	path := writeFile(t, dir, "config.yaml", `
+include: "shared/base.yaml"
+rules:
+  alert_cooldown_minutes: 5
+  definitions:
+    - name: "egress"
+      threshold_mb: 4096
+    - name: "scan"
+      type: "fan_out"
+      max_connections: 100
+alerter:
+  telegram:
+    chat_id: "-200"
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 本地的标量覆盖基础配置，未覆盖的键保留
+	if cfg.Rules.AlertCooldownMinutes != 5 || cfg.Rules.TimeWindowMinutes != 60 {
+		t.Errorf("cooldown = %d, window = %d; want 5 from the override and 60 from the base", cfg.Rules.AlertCooldownMinutes, cfg.Rules.TimeWindowMinutes)
+	}
+	tg := cfg.Alerter.Telegram
+	if !tg.Enabled || tg.BotToken != "123:shared" || tg.ChatID != "-200" {
+		t.Errorf("telegram = %+v, want the shared token with the local chat_id", tg)
+	}
+
+	// 同名规则逐字段合并，新规则追加到末尾
+	defs := cfg.Rules.Definitions
+	if len(defs) != 3 || defs[0].Name != "egress" || defs[1].Name != "fast" || defs[2].Name != "scan" {
+		t.Fatalf("definitions = %+v, want egress, fast, scan", defs)
+	}
+	if defs[0].ThresholdMB != 4096 || defs[0].Severity != SeverityWarning || defs[0].Type != RuleTypeTraffic {
+		t.Errorf("egress = %+v, want threshold 4096 with the base type and severity", defs[0])
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yaml", "include: [\"b.yaml\"]\n")
+	writeFile(t, dir, "b.yaml", "include: [\"sub/c.yaml\"]\n")
+	writeFile(t, dir, "sub/c.yaml", "include: \"../a.yaml\"\n")
+
+	_, err := LoadConfig(writeFile(t, dir, "config.yaml", "include: \"a.yaml\"\nrules:"+minimalRules))
+	if err == nil {
+		t.Fatal("LoadConfig succeeded, want an include cycle error")
+	}
+	if !strings.Contains(err.Error(), "include cycle") || !strings.Contains(err.Error(), "a.yaml -> ") || !strings.Contains(err.Error(), "c.yaml") {
+		t.Errorf("error %q does not describe the a -> b -> c -> a cycle", err)
+	}
+
+	self := writeFile(t, dir, "self.yaml", "include: \"self.yaml\"\n")
+	if _, err := LoadConfig(self); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("self include: err = %v, want an include cycle error", err)
+	}
+
+	// 同一个文件被两个文件引用（菱形）不是循环
+	writeFile(t, dir, "common.yaml", "rules:"+minimalRules)
+	writeFile(t, dir, "left.yaml", "include: \"common.yaml\"\n")
+	writeFile(t, dir, "right.yaml", "include: \"common.yaml\"\n")
+	if _, err := LoadConfig(writeFile(t, dir, "diamond.yaml", "include: [\"left.yaml\", \"right.yaml\"]\n")); err != nil {
+		t.Errorf("diamond include: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+// internal/config/mute.go
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MuteWindow 是一个按周重复的静默时间段，期间规则照常检查但不发送警报。
+// 时间使用本地时区，End 早于 Start 时表示跨越午夜，例如 23:00 - 02:00
+type MuteWindow struct {
+	// Days 是生效的星期（mon, tue, ...），为空表示每天；跨越午夜的时间段以开始的那天为准
+	Days  []string `yaml:"days"`
+	Start string   `yaml:"start"`
+	End   string   `yaml:"end"`
+}
+
+// weekdays 将配置中的星期缩写映射为 time.Weekday
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseClock 将 "HH:MM" 解析为从午夜开始的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validate 检查静默时间段的格式
+func (w *MuteWindow) validate() error {
+	if _, err := parseClock(w.Start); err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	if _, err := parseClock(w.End); err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	for _, d := range w.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("unknown day %q (want mon, tue, wed, thu, fri, sat or sun)", d)
+		}
+	}
+	return nil
+}
+
+// Contains 检查 t 是否落在静默时间段内，格式错误的时间段不会生效
+func (w *MuteWindow) Contains(t time.Time) bool {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if start <= end {
+		return minute >= start && minute < end && w.onDay(day)
+	}
+	// 跨越午夜：午夜之前属于当天，午夜之后属于前一天开始的时间段
+	if minute >= start {
+		return w.onDay(day)
+	}
+	return minute < end && w.onDay((day+6)%7)
+}
+
+// onDay 检查时间段是否在指定的星期生效
+func (w *MuteWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+// internal/config/mute_test.go
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMuteWindowContains(t *testing.T) {
+	// 2024-01-01 是星期一
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name   string
+		window MuteWindow
+		t      time.Time
+		want   bool
+	}{
+		{"inside", MuteWindow{Start: "01:00", End: "03:00"}, at(1, 2, 0), true},
+		{"at start", MuteWindow{Start: "01:00", End: "03:00"}, at(1, 1, 0), true},
+		{"end is exclusive", MuteWindow{Start: "01:00", End: "03:00"}, at(1, 3, 0), false},
+		{"before", MuteWindow{Start: "01:00", End: "03:00"}, at(1, 0, 59), false},
+		{"matching day", MuteWindow{Days: []string{"mon"}, Start: "01:00", End: "03:00"}, at(1, 2, 0), true},
+		{"other day", MuteWindow{Days: []string{"Mon"}, Start: "01:00", End: "03:00"}, at(2, 2, 0), false},
+		{"overnight before midnight", MuteWindow{Days: []string{"mon"}, Start: "23:00", End: "02:00"}, at(1, 23, 30), true},
+		// 午夜之后属于前一天开始的时间段
+		{"overnight after midnight", MuteWindow{Days: []string{"mon"}, Start: "23:00", End: "02:00"}, at(2, 1, 0), true},
+		{"overnight wrong day", MuteWindow{Days: []string{"mon"}, Start: "23:00", End: "02:00"}, at(1, 1, 0), false},
+		{"overnight after end", MuteWindow{Start: "23:00", End: "02:00"}, at(2, 2, 0), false},
+		{"malformed", MuteWindow{Start: "1am", End: "03:00"}, at(1, 2, 0), false},
+	}
+	for _, tt := range tests {
+		if got := tt.window.Contains(tt.t); got != tt.want {
+			t.Errorf("%s: Contains(%s) = %v, want %v", tt.name, tt.t.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,466 @@
+// internal/config/rules.go
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"traffic-guardian/internal/match"
+)
+
+// RuleType 表示规则统计的对象
+type RuleType string
+
+const (
+	// RuleTypeTraffic 比较单个进程在时间窗口内的累计流量
+	RuleTypeTraffic RuleType = "traffic"
+	// RuleTypePerUser 比较单个用户所有进程在时间窗口内的累计流量之和
+	RuleTypePerUser RuleType = "per_user"
+	// RuleTypeRate 比较单个进程发送速率的指数加权移动平均
+	RuleTypeRate RuleType = "rate"
+	// RuleTypeAnomaly 比较单个进程最新的发送速率与其自身的历史基线
+	RuleTypeAnomaly RuleType = "anomaly"
+	// RuleTypeGrowth 比较单个进程最新的发送速率相对其历史基线的增长百分比
+	RuleTypeGrowth RuleType = "growth"
+	// RuleTypeFanOut 比较单个进程在时间窗口内通信过的不同对端数量
+	RuleTypeFanOut RuleType = "fan_out"
+	// RuleTypeHost 比较所有进程在时间窗口内的累计流量之和，即整台主机的出口流量
+	RuleTypeHost RuleType = "host"
+	// RuleTypePort 比较从一个本端端口发出的累计流量之和，即监听该端口的服务提供的流量
+	RuleTypePort RuleType = "port"
+	// RuleTypeCIDR 比较所有进程发往指定目的网段的累计流量之和，用于按子网限额
+	RuleTypeCIDR RuleType = "cidr"
+	// RuleTypeBlocklist 在进程与指定网段（例如威胁情报中的恶意地址）中的任意地址通信时报警，与流量大小无关
+	RuleTypeBlocklist RuleType = "blocklist"
+)
+
+// isVolume 检查规则类型是否比较累计流量，这类规则共享阈值、预警和统计范围等字段
+func (t RuleType) isVolume() bool {
+	return t == RuleTypeTraffic || t == RuleTypePerUser || t == RuleTypeHost || t == RuleTypePort || t == RuleTypeCIDR
+}
+
+// isPerProcess 检查规则类型是否逐个进程评估，这类规则受进程开始被跟踪后的宽限期约束
+func (t RuleType) isPerProcess() bool {
+	return t == RuleTypeTraffic || t == RuleTypeRate || t == RuleTypeAnomaly || t == RuleTypeGrowth || t == RuleTypeFanOut
+}
+
+// 未配置 rules.definitions 时，根据单一规则字段生成的规则名称
+const (
+	LegacyTrafficRuleName = "traffic_threshold"
+	LegacyPerUserRuleName = "per_user_threshold"
+	LegacyAnomalyRuleName = "rate_anomaly"
+)
+
+// HostEgressRuleName 是根据 rules.host_egress_threshold_mb 生成的规则名称
+const HostEgressRuleName = "host_egress"
+
+// BlocklistRuleName 是根据 rules.blocklist_cidrs 生成的规则名称
+const BlocklistRuleName = "blocklist"
+
+// anomaly 规则未配置 sigma / warmup_samples 时使用的默认值
+const (
+	DefaultAnomalySigma         = 3.0
+	DefaultAnomalyWarmupSamples = 5
+)
+
+// Scope 表示 traffic / per_user 规则统计哪一部分流量，内部网络由 rules.internal_cidrs 定义
+type Scope string
+
+const (
+	// ScopeExternal 只统计发往内部网络以外的流量，是默认值
+	ScopeExternal Scope = "external"
+	// ScopeInternal 只统计发往内部网络的流量
+	ScopeInternal Scope = "internal"
+	// ScopeAll 统计所有流量
+	ScopeAll Scope = "all"
+)
+
+// RuleDefinition 定义了一条命名规则，所有规则在每个检查周期内同时生效
+type RuleDefinition struct {
+	Name        string   `yaml:"name" json:"name"`
+	Type        RuleType `yaml:"type" json:"type"`
+	ThresholdMB int      `yaml:"threshold_mb" json:"threshold_mb,omitempty"`
+	// ThresholdPercent 和 Interface 让 traffic / per_user 规则以接口链路速率的百分比表示阈值，
+	// 每次检查时按 /sys/class/net/<interface>/speed 换算为时间窗口内的字节预算，配置后忽略 ThresholdMB
+	ThresholdPercent float64 `yaml:"threshold_percent" json:"threshold_percent,omitempty"`
+	Interface        string  `yaml:"interface" json:"interface,omitempty"`
+	// Scope 是 traffic / per_user 规则统计的流量范围: external（默认）、internal 或 all。
+	// 未配置 rules.internal_cidrs 时所有流量都是外部流量
+	Scope Scope `yaml:"scope" json:"scope,omitempty"`
+	// WarnThresholdMB 是 traffic / per_user 规则可选的预警阈值，必须小于 ThresholdMB。
+	// 超过时发出一条低一级严重级别的预警，与主阈值的警报分别计算冷却时间
+	WarnThresholdMB int `yaml:"warn_threshold_mb" json:"warn_threshold_mb,omitempty"`
+	// RateThresholdKBps 是 rate 规则的阈值（单位: KB/s）；
+	// 对 anomaly 规则是可选的最低速率，低于此速率的突增不会报警
+	RateThresholdKBps int `yaml:"threshold_kb_per_second" json:"threshold_kb_per_second,omitempty"`
+	// SmoothingSamples 让 rate 规则使用最近 N 个速率样本的简单移动平均代替 EWMA，0 或 1 表示使用 EWMA。
+	// ConsecutiveBreaches 是报警前平滑速率需要连续超过阈值的样本数，默认为 1，用于抑制在阈值附近抖动的进程
+	SmoothingSamples    int `yaml:"smoothing_samples" json:"smoothing_samples,omitempty"`
+	ConsecutiveBreaches int `yaml:"consecutive_breaches" json:"consecutive_breaches,omitempty"`
+	// Sigma 仅用于 anomaly 规则，WarmupSamples 用于 anomaly 和 growth 规则，见 Anomaly
+	Sigma         float64 `yaml:"sigma" json:"sigma,omitempty"`
+	WarmupSamples int     `yaml:"warmup_samples" json:"warmup_samples,omitempty"`
+	// IncreasePercent 是 growth 规则允许的增长百分比: 最新速率超过基线 × (1 + IncreasePercent/100) 时报警。
+	// 基线为 0（一直空闲）的进程不做判断，threshold_kb_per_second 是可选的最低速率
+	IncreasePercent float64 `yaml:"increase_percent" json:"increase_percent,omitempty"`
+	// MaxConnections 是 fan_out 规则允许的不同对端 (IP:端口) 数量
+	MaxConnections int `yaml:"max_connections" json:"max_connections,omitempty"`
+	// LocalPort 是 port 规则统计的本端端口，例如 443。内核临时端口范围内的端口不做统计
+	LocalPort uint16 `yaml:"local_port" json:"local_port,omitempty"`
+	// CIDRs 是 cidr 规则统计的目的网段（IPv4 或 IPv6），发往其中任意一个网段的流量都计入规则。
+	// 统计范围由网段决定，不能同时配置 scope；无法解析对端地址的流量不计入。
+	// 对 blocklist 规则是禁止通信的网段或单个地址
+	CIDRs []string `yaml:"cidrs" json:"cidrs,omitempty"`
+	// CooldownMinutes 为 0 时使用 rules.alert_cooldown_minutes
+	CooldownMinutes int `yaml:"cooldown_minutes" json:"cooldown_minutes,omitempty"`
+	// RealertEveryMB 大于 0 时代替冷却时间: 比较流量的规则报警后，对象的流量每再增长 RealertEveryMB 才再次报警，
+	// 适合累计配额（例如超过 10 GB 后每多 1 GB 报警一次）。对象恢复到阈值以下后重新开始计算
+	RealertEveryMB int `yaml:"realert_every_mb" json:"realert_every_mb,omitempty"`
+	// GracePeriodSeconds 是逐进程评估的规则对新进程的宽限期，为 0 时使用 rules.grace_period_seconds。
+	// 例如 fan_out 规则可以给启动时建立大量连接的服务更长的宽限期
+	GracePeriodSeconds int       `yaml:"grace_period_seconds" json:"grace_period_seconds,omitempty"`
+	Severity           Severity  `yaml:"severity" json:"severity,omitempty"`
+	Direction          Direction `yaml:"direction" json:"direction,omitempty"`
+	// MatchComms 限定规则只作用于进程名匹配的进程，为空时作用于所有进程
+	MatchComms []string `yaml:"match_comms" json:"match_comms,omitempty"`
+	// Alerters 是接收此规则警报的警报器名称，为空时按 alerter.routing 的严重级别路由
+	Alerters []string `yaml:"alerters" json:"alerters,omitempty"`
+	// EscalateAfter 大于 0 时，同一对象在冷却期结束后持续超限、连续报警每达到 EscalateAfter 次，
+	// 警报的严重级别提升一级（最高为 critical），并改为发送给 EscalateAlerters（为空时仍使用 Alerters 或按严重级别路由）
+	EscalateAfter    int      `yaml:"escalate_after" json:"escalate_after,omitempty"`
+	EscalateAlerters []string `yaml:"escalate_alerters" json:"escalate_alerters,omitempty"`
+	// WindowMinutes 让 traffic 规则比较进程在最近 N 分钟滑动窗口内的流量，而不是自开始跟踪以来的累计流量。
+	// 不同规则可以使用不同的窗口同时生效（例如 1 分钟的突发和 60 分钟的配额），0 表示使用累计流量
+	WindowMinutes int `yaml:"window_minutes" json:"window_minutes,omitempty"`
+}
+
+// GetThresholdBytes 是一个辅助函数，将MB转换为Bytes
+func (d *RuleDefinition) GetThresholdBytes() uint64 {
+	return uint64(d.ThresholdMB) * 1024 * 1024
+}
+
+// GetWarnThresholdBytes 是一个辅助函数，将预警阈值从MB转换为Bytes，0 表示不启用
+func (d *RuleDefinition) GetWarnThresholdBytes() uint64 {
+	return uint64(d.WarnThresholdMB) * 1024 * 1024
+}
+
+// GetWarnSeverity 是一个辅助函数，返回预警的严重级别，比规则的严重级别低一级，最低为 info
+func (d *RuleDefinition) GetWarnSeverity() Severity {
+	if d.GetSeverity() == SeverityCritical {
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// GetRateThresholdBps 是一个辅助函数，将速率阈值从 KB/s 转换为 字节/秒
+func (d *RuleDefinition) GetRateThresholdBps() float64 {
+	return float64(d.RateThresholdKBps) * 1024
+}
+
+// GetConsecutiveBreaches 是一个辅助函数，返回 rate 规则报警前需要连续超限的样本数，未配置时为 1
+func (d *RuleDefinition) GetConsecutiveBreaches() int {
+	if d.ConsecutiveBreaches <= 0 {
+		return 1
+	}
+	return d.ConsecutiveBreaches
+}
+
+// GetSigma 是一个辅助函数，返回 anomaly 规则的标准差倍数，未配置时使用默认值
+func (d *RuleDefinition) GetSigma() float64 {
+	if d.Sigma <= 0 {
+		return DefaultAnomalySigma
+	}
+	return d.Sigma
+}
+
+// GetWarmupSamples 是一个辅助函数，返回 anomaly 规则开始检测前需要的基线样本数，未配置时使用默认值
+func (d *RuleDefinition) GetWarmupSamples() int {
+	if d.WarmupSamples <= 0 {
+		return DefaultAnomalyWarmupSamples
+	}
+	return d.WarmupSamples
+}
+
+// GetSeverity 是一个辅助函数，返回规则的严重级别，未配置时默认为 warning，blocklist 规则默认为 critical
+func (d *RuleDefinition) GetSeverity() Severity {
+	if d.Severity == "" {
+		if d.Type == RuleTypeBlocklist {
+			return SeverityCritical
+		}
+		return SeverityWarning
+	}
+	return d.Severity
+}
+
+// GetDirection 是一个辅助函数，返回规则统计的流量方向，未配置时默认为 tx
+func (d *RuleDefinition) GetDirection() Direction {
+	if d.Direction == "" {
+		return DirectionTX
+	}
+	return d.Direction
+}
+
+// GetScope 是一个辅助函数，返回规则统计的流量范围，未配置时默认为 external
+func (d *RuleDefinition) GetScope() Scope {
+	if d.Scope == "" {
+		return ScopeExternal
+	}
+	return d.Scope
+}
+
+// GetWindowName 是一个辅助函数，返回规则滑动窗口的名称（例如 "60m"），即 ProcessStats.Windows 的键；
+// 未配置 window_minutes 时返回空字符串
+func (d *RuleDefinition) GetWindowName() string {
+	if d.WindowMinutes <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dm", d.WindowMinutes)
+}
+
+// GetWindows 返回所有规则用到的滑动窗口，键为窗口名称，值为窗口长度
+func (r *Rules) GetWindows() map[string]time.Duration {
+	windows := make(map[string]time.Duration)
+	for _, d := range r.GetDefinitions() {
+		if name := d.GetWindowName(); name != "" {
+			windows[name] = time.Duration(d.WindowMinutes) * time.Minute
+		}
+	}
+	return windows
+}
+
+// GetCIDRs 返回所有 cidr 规则统计的目的网段，键为规则名称
+func (r *Rules) GetCIDRs() map[string][]string {
+	cidrs := make(map[string][]string)
+	for _, d := range r.GetDefinitions() {
+		if d.Type == RuleTypeCIDR {
+			cidrs[d.Name] = d.CIDRs
+		}
+	}
+	return cidrs
+}
+
+// GetCooldown 是一个辅助函数，返回规则的冷却时间，未配置时使用 fallback
+func (d *RuleDefinition) GetCooldown(fallback time.Duration) time.Duration {
+	if d.CooldownMinutes <= 0 {
+		return fallback
+	}
+	return time.Duration(d.CooldownMinutes) * time.Minute
+}
+
+// GetRealertEveryBytes 是一个辅助函数，将再次报警的流量增量从MB转换为Bytes，0 表示使用冷却时间
+func (d *RuleDefinition) GetRealertEveryBytes() uint64 {
+	return uint64(max(d.RealertEveryMB, 0)) * 1024 * 1024
+}
+
+// GetGracePeriod 是一个辅助函数，返回规则对新进程的宽限期，未配置时使用 fallback
+func (d *RuleDefinition) GetGracePeriod(fallback time.Duration) time.Duration {
+	if d.GracePeriodSeconds <= 0 {
+		return fallback
+	}
+	return time.Duration(d.GracePeriodSeconds) * time.Second
+}
+
+// GetDefinitions 返回所有生效的规则。未配置 definitions 时，
+// 根据 traffic_threshold_mb、per_user_threshold_mb 和 anomaly 生成等价的规则以保持兼容。
+// host_egress_threshold_mb 和 blocklist_cidrs 生成的规则总是生效；配置了 defaults_file 时，默认阈值表生成的规则追加在最后
+func (r *Rules) GetDefinitions() []RuleDefinition {
+	if len(r.Definitions) > 0 {
+		return mergeCommDefaults(r.appendBlocklist(r.appendHostEgress(slices.Clone(r.Definitions))), r.commDefaults)
+	}
+
+	var defs []RuleDefinition
+	if r.TrafficThresholdMB > 0 {
+		defs = append(defs, RuleDefinition{
+			Name:        LegacyTrafficRuleName,
+			Type:        RuleTypeTraffic,
+			ThresholdMB: r.TrafficThresholdMB,
+			Severity:    r.Severity,
+			MatchComms:  r.MatchComms,
+		})
+	}
+	if r.PerUserThresholdMB > 0 {
+		defs = append(defs, RuleDefinition{
+			Name:        LegacyPerUserRuleName,
+			Type:        RuleTypePerUser,
+			ThresholdMB: r.PerUserThresholdMB,
+			Severity:    r.Severity,
+		})
+	}
+	if r.Anomaly.Sigma > 0 {
+		defs = append(defs, RuleDefinition{
+			Name:              LegacyAnomalyRuleName,
+			Type:              RuleTypeAnomaly,
+			RateThresholdKBps: r.Anomaly.MinRateKBps,
+			Sigma:             r.Anomaly.Sigma,
+			WarmupSamples:     r.Anomaly.WarmupSamples,
+			Severity:          r.Severity,
+			MatchComms:        r.MatchComms,
+		})
+	}
+	return mergeCommDefaults(r.appendBlocklist(r.appendHostEgress(defs)), r.commDefaults)
+}
+
+// appendHostEgress 在配置了 host_egress_threshold_mb 时追加主机出口流量规则，
+// definitions 中已有同名规则时以显式配置为准
+func (r *Rules) appendHostEgress(defs []RuleDefinition) []RuleDefinition {
+	if r.HostEgressThresholdMB <= 0 {
+		return defs
+	}
+	if slices.ContainsFunc(defs, func(d RuleDefinition) bool { return d.Name == HostEgressRuleName }) {
+		return defs
+	}
+	return append(defs, RuleDefinition{
+		Name:        HostEgressRuleName,
+		Type:        RuleTypeHost,
+		ThresholdMB: r.HostEgressThresholdMB,
+		Severity:    r.Severity,
+	})
+}
+
+// appendBlocklist 在配置了 blocklist_cidrs 时追加禁止通信地址的规则，definitions 中已有同名规则时以显式配置为准
+func (r *Rules) appendBlocklist(defs []RuleDefinition) []RuleDefinition {
+	if len(r.BlocklistCIDRs) == 0 {
+		return defs
+	}
+	if slices.ContainsFunc(defs, func(d RuleDefinition) bool { return d.Name == BlocklistRuleName }) {
+		return defs
+	}
+	return append(defs, RuleDefinition{
+		Name:     BlocklistRuleName,
+		Type:     RuleTypeBlocklist,
+		CIDRs:    r.BlocklistCIDRs,
+		Severity: SeverityCritical,
+	})
+}
+
+// validateDefinitions 检查命名规则列表是否合法
+func (r *Rules) validateDefinitions() []error {
+	var errs []error
+	names := make(map[string]bool)
+	for i, d := range r.Definitions {
+		field := fmt.Sprintf("rules.definitions[%d]", i)
+		if d.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", field))
+		} else if names[d.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate rule name %q", field, d.Name))
+		}
+		names[d.Name] = true
+
+		switch d.Type {
+		case RuleTypeTraffic, RuleTypePerUser, RuleTypeHost, RuleTypePort, RuleTypeCIDR:
+			switch {
+			case d.ThresholdPercent != 0:
+				if d.ThresholdPercent < 0 || d.ThresholdPercent > 100 {
+					errs = append(errs, fmt.Errorf("%s: threshold_percent must be in (0, 100]", field))
+				}
+				if d.Interface == "" {
+					errs = append(errs, fmt.Errorf("%s: interface is required with threshold_percent", field))
+				}
+				if d.WarnThresholdMB != 0 {
+					errs = append(errs, fmt.Errorf("%s: warn_threshold_mb cannot be combined with threshold_percent", field))
+				}
+			case d.ThresholdMB <= 0:
+				errs = append(errs, fmt.Errorf("%s: threshold_mb must be positive", field))
+			}
+			if d.WarnThresholdMB < 0 || (d.WarnThresholdMB > 0 && d.WarnThresholdMB >= d.ThresholdMB) {
+				errs = append(errs, fmt.Errorf("%s: warn_threshold_mb must be positive and below threshold_mb", field))
+			}
+		case RuleTypeRate:
+			if d.RateThresholdKBps <= 0 {
+				errs = append(errs, fmt.Errorf("%s: threshold_kb_per_second must be positive", field))
+			}
+			if d.SmoothingSamples < 0 || d.ConsecutiveBreaches < 0 {
+				errs = append(errs, fmt.Errorf("%s: smoothing_samples and consecutive_breaches must not be negative", field))
+			}
+		case RuleTypeAnomaly:
+			if d.Sigma < 0 || d.WarmupSamples < 0 || d.RateThresholdKBps < 0 {
+				errs = append(errs, fmt.Errorf("%s: sigma, warmup_samples and threshold_kb_per_second must not be negative", field))
+			}
+		case RuleTypeGrowth:
+			if d.IncreasePercent <= 0 {
+				errs = append(errs, fmt.Errorf("%s: increase_percent must be positive", field))
+			}
+			if d.WarmupSamples < 0 || d.RateThresholdKBps < 0 {
+				errs = append(errs, fmt.Errorf("%s: warmup_samples and threshold_kb_per_second must not be negative", field))
+			}
+		case RuleTypeFanOut:
+			if d.MaxConnections <= 0 {
+				errs = append(errs, fmt.Errorf("%s: max_connections must be positive", field))
+			}
+		case RuleTypeBlocklist:
+			// 只需要 cidrs，见下面的检查
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown type %q (want %q, %q, %q, %q, %q, %q, %q, %q, %q or %q)", field, d.Type, RuleTypeTraffic, RuleTypePerUser, RuleTypeHost, RuleTypePort, RuleTypeCIDR, RuleTypeRate, RuleTypeAnomaly, RuleTypeGrowth, RuleTypeFanOut, RuleTypeBlocklist))
+		}
+		if d.Type == RuleTypePort && d.LocalPort == 0 {
+			errs = append(errs, fmt.Errorf("%s: local_port is required", field))
+		} else if d.LocalPort != 0 && d.Type != RuleTypePort {
+			errs = append(errs, fmt.Errorf("%s: local_port is only supported by %q rules", field, RuleTypePort))
+		}
+		if d.Type == RuleTypeCIDR || d.Type == RuleTypeBlocklist {
+			if len(d.CIDRs) == 0 {
+				errs = append(errs, fmt.Errorf("%s: cidrs is required", field))
+			} else if _, err := match.ParsePrefixes(d.CIDRs); err != nil {
+				errs = append(errs, fmt.Errorf("%s: cidrs: %w", field, err))
+			}
+			if d.Scope != "" {
+				errs = append(errs, fmt.Errorf("%s: scope is not supported by %q rules, cidrs selects the traffic", field, d.Type))
+			}
+		} else if len(d.CIDRs) > 0 {
+			errs = append(errs, fmt.Errorf("%s: cidrs is only supported by %q and %q rules", field, RuleTypeCIDR, RuleTypeBlocklist))
+		}
+		if d.WarnThresholdMB != 0 && !d.Type.isVolume() {
+			errs = append(errs, fmt.Errorf("%s: warn_threshold_mb is only supported by %q, %q, %q, %q and %q rules", field, RuleTypeTraffic, RuleTypePerUser, RuleTypeHost, RuleTypePort, RuleTypeCIDR))
+		}
+		if (d.SmoothingSamples != 0 || d.ConsecutiveBreaches != 0) && d.Type != RuleTypeRate {
+			errs = append(errs, fmt.Errorf("%s: smoothing_samples and consecutive_breaches are only supported by %q rules", field, RuleTypeRate))
+		}
+		if d.WindowMinutes < 0 {
+			errs = append(errs, fmt.Errorf("%s: window_minutes must not be negative", field))
+		} else if d.WindowMinutes > 0 && d.Type != RuleTypeTraffic && d.Type != RuleTypeHost {
+			errs = append(errs, fmt.Errorf("%s: window_minutes is only supported by %q and %q rules", field, RuleTypeTraffic, RuleTypeHost))
+		}
+		if d.RealertEveryMB < 0 {
+			errs = append(errs, fmt.Errorf("%s: realert_every_mb must not be negative", field))
+		} else if d.RealertEveryMB > 0 {
+			if !d.Type.isVolume() {
+				errs = append(errs, fmt.Errorf("%s: realert_every_mb is only supported by %q, %q, %q, %q and %q rules", field, RuleTypeTraffic, RuleTypePerUser, RuleTypeHost, RuleTypePort, RuleTypeCIDR))
+			}
+			if d.CooldownMinutes > 0 || d.EscalateAfter > 0 {
+				errs = append(errs, fmt.Errorf("%s: realert_every_mb replaces the cooldown and cannot be combined with cooldown_minutes or escalate_after", field))
+			}
+		}
+		if d.GracePeriodSeconds < 0 {
+			errs = append(errs, fmt.Errorf("%s: grace_period_seconds must not be negative", field))
+		} else if d.GracePeriodSeconds > 0 && !d.Type.isPerProcess() {
+			errs = append(errs, fmt.Errorf("%s: grace_period_seconds is only supported by %q, %q, %q, %q and %q rules", field, RuleTypeTraffic, RuleTypeRate, RuleTypeAnomaly, RuleTypeGrowth, RuleTypeFanOut))
+		}
+		if d.IncreasePercent != 0 && d.Type != RuleTypeGrowth {
+			errs = append(errs, fmt.Errorf("%s: increase_percent is only supported by %q rules", field, RuleTypeGrowth))
+		}
+		if d.EscalateAfter < 0 {
+			errs = append(errs, fmt.Errorf("%s: escalate_after must not be negative", field))
+		} else if d.EscalateAfter == 0 && len(d.EscalateAlerters) > 0 {
+			errs = append(errs, fmt.Errorf("%s: escalate_alerters requires escalate_after", field))
+		}
+		if d.ThresholdPercent != 0 && !d.Type.isVolume() {
+			errs = append(errs, fmt.Errorf("%s: threshold_percent is only supported by %q, %q, %q, %q and %q rules", field, RuleTypeTraffic, RuleTypePerUser, RuleTypeHost, RuleTypePort, RuleTypeCIDR))
+		}
+		switch d.Severity {
+		case "", SeverityInfo, SeverityWarning, SeverityCritical:
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown severity %q", field, d.Severity))
+		}
+		switch d.Scope {
+		case "", ScopeExternal, ScopeInternal, ScopeAll:
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown scope %q (want %q, %q or %q)", field, d.Scope, ScopeExternal, ScopeInternal, ScopeAll))
+		}
+		// 探针目前只采集发送方向的流量
+		if d.GetDirection() != DirectionTX {
+			errs = append(errs, fmt.Errorf("%s: direction %q is not supported, only %q is collected", field, d.Direction, DirectionTX))
+		}
+	}
+	return errs
+}
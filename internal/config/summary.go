@@ -0,0 +1,70 @@
+// internal/config/summary.go
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Summary 返回生效配置的摘要，格式为 slog 的键值对，用于在启动时确认合并和默认值之后的实际配置。
+// Bot Token 等凭据只保留末尾几个字符
+func (c *Config) Summary() []any {
+	logOutput := c.LogOutput
+	if logOutput == "" {
+		logOutput = "stdout"
+	}
+	logLevel := c.LogLevel
+	if logLevel == "" {
+		logLevel = "info"
+	}
+
+	defs := c.Rules.GetDefinitions()
+	rules := make([]string, 0, len(defs))
+	for _, d := range defs {
+		rules = append(rules, fmt.Sprintf("%s(%s)", d.Name, d.Type))
+	}
+
+	var alerters []string
+	if c.Alerter.Telegram.Enabled {
+		alerters = append(alerters, fmt.Sprintf("telegram(chat=%s, token=%s)", c.Alerter.Telegram.ChatID, maskSecret(c.Alerter.Telegram.BotToken)))
+	}
+	if c.Alerter.Exec.Enabled {
+		alerters = append(alerters, fmt.Sprintf("exec(%s)", c.Alerter.Exec.Command))
+	}
+	if c.Alerter.Journal.Enabled {
+		alerters = append(alerters, "journal")
+	}
+	if c.Alerter.File.Enabled {
+		alerters = append(alerters, fmt.Sprintf("file(%s)", c.Alerter.File.Path))
+	}
+
+	api := "disabled"
+	if c.API.Enabled {
+		api = c.API.ListenAddress
+	}
+
+	return []any{
+		"log_level", logLevel,
+		"log_output", logOutput,
+		"time_window", c.Rules.GetTimeWindow(),
+		"check_interval", c.Rules.GetCheckInterval(),
+		"alert_cooldown", c.Rules.GetAlertCooldown(),
+		"rules", strings.Join(rules, ", "),
+		"alerters", strings.Join(alerters, ", "),
+		"collector_mode", c.Collector.GetMode(),
+		"api", api,
+		"history", c.History.IsEnabled(),
+	}
+}
+
+// maskSecret 隐藏凭据，只保留末尾 4 个字符，较短的凭据完全隐藏
+func maskSecret(s string) string {
+	const visible = 4
+	if s == "" {
+		return ""
+	}
+	if len(s) <= visible*2 {
+		return "****"
+	}
+	return "****" + s[len(s)-visible:]
+}
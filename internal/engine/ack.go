@@ -0,0 +1,34 @@
+// internal/engine/ack.go
+package engine
+
+import "time"
+
+// Acknowledge 确认一条规则对某个对象（进程规则为 PID，用户规则为 UID，端口规则为端口号，主机和网段规则为 0）的警报，
+// 在 d 时间内不再对该对象报警，预警和主阈值同时生效。规则不存在时返回 false。用于 Telegram 等警报器上的确认按钮
+func (e *Engine) Acknowledge(ruleName string, id uint32, d time.Duration) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, r := range e.compiled {
+		if r.Name == ruleName {
+			e.acknowledged[alertKey{rule: ruleName, id: id}] = e.now().Add(d)
+			e.log.Info("Alert acknowledged", "rule", ruleName, "id", id, "duration", d)
+			return true
+		}
+	}
+	return false
+}
+
+// acknowledgedLocked 检查规则对某个对象的警报是否已被确认且仍在确认期内，过期的确认记录会被删除。调用方必须持有 e.mu
+func (e *Engine) acknowledgedLocked(r *rule, id uint32) bool {
+	key := alertKey{rule: r.Name, id: id}
+	until, ok := e.acknowledged[key]
+	if !ok {
+		return false
+	}
+	if e.now().Before(until) {
+		return true
+	}
+	delete(e.acknowledged, key)
+	return false
+}
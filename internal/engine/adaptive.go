@@ -0,0 +1,68 @@
+// internal/engine/adaptive.go
+package engine
+
+import (
+	"time"
+
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/state"
+)
+
+// nextInterval 根据接近阈值的进程数量计算下一个检查间隔: 达到 busy_processes 时减半，
+// 没有接近阈值的进程时加倍，介于两者之间时恢复为 check_interval_seconds，结果限制在配置的上下限内
+func (e *Engine) nextInterval(current time.Duration, stats []state.ProcessStats) time.Duration {
+	adaptive := e.rules.AdaptiveInterval
+	base := e.rules.GetCheckInterval()
+	min, max := adaptive.GetBounds(base)
+
+	near := e.countNearThreshold(stats, adaptive.GetNearThresholdRatio())
+	next := base
+	switch {
+	case near >= adaptive.GetBusyProcesses():
+		next = current / 2
+	case near == 0:
+		next = current * 2
+	}
+	return clampDuration(next, min, max)
+}
+
+// countNearThreshold 统计流量或速率达到任意 traffic / rate 规则阈值 ratio 倍的进程数量
+func (e *Engine) countNearThreshold(stats []state.ProcessStats, ratio float64) int {
+	near := make(map[uint32]bool)
+	for _, r := range e.compiled {
+		if r.level != "" {
+			// 预警规则的阈值低于主阈值，不重复计算
+			continue
+		}
+		switch r.Type {
+		case config.RuleTypeTraffic:
+			threshold, ok := e.thresholdFor(r)
+			if !ok {
+				continue
+			}
+			for _, s := range stats {
+				if (len(r.matchComms) == 0 || r.matchComms.MatchAny(s.Comm)) && float64(r.processBytes(&s)) >= ratio*float64(threshold) {
+					near[s.PID] = true
+				}
+			}
+		case config.RuleTypeRate:
+			for _, s := range stats {
+				if (len(r.matchComms) == 0 || r.matchComms.MatchAny(s.Comm)) && s.EWMARateBps >= ratio*r.rateThreshold {
+					near[s.PID] = true
+				}
+			}
+		}
+	}
+	return len(near)
+}
+
+// clampDuration 将 d 限制在 [min, max] 之内
+func clampDuration(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,74 @@
+// internal/engine/audit.go
+package engine
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+// 规则评估的判断结果，记录在审计日志的 decision 字段中
+const (
+	// decisionAlert 表示对象超限并触发了警报（暂停和静默时间段仍可能抑制发送）
+	decisionAlert = "alert"
+	// decisionBelowThreshold 表示对象未超过阈值
+	decisionBelowThreshold = "below_threshold"
+	// decisionCooldown 表示对象超限，但仍处于上一次警报的冷却期内
+	decisionCooldown = "cooldown"
+	// decisionGracePeriod 表示进程仍处于开始被跟踪后的宽限期内，没有评估
+	decisionGracePeriod = "grace_period"
+	// decisionBelowIncrement 表示 realert_every_mb 规则的对象自上一次报警以来的流量增长还不到一个增量
+	decisionBelowIncrement = "below_increment"
+	// decisionWarmup 表示进程的速率基线样本不足，没有评估
+	decisionWarmup = "warmup"
+)
+
+// SetAuditLog 让引擎将每一次规则评估以 JSON 行的形式写入 w，见 rules.audit_log。必须在 Start 之前调用
+func (e *Engine) SetAuditLog(w io.Writer) {
+	e.auditLog = slog.New(slog.NewJSONHandler(w, nil))
+}
+
+// decide 判断对象是否应当报警: 超限（breached）且不在冷却期内。判断结果记录到审计日志，
+// value 和 threshold 是参与比较的流量、速率或对端数量
+func (e *Engine) decide(r *rule, id uint32, name string, value, threshold any, breached bool) bool {
+	decision := decisionAlert
+	switch {
+	case !breached:
+		decision = decisionBelowThreshold
+	case r.realertEvery > 0:
+		// realert_every_mb 只用于比较流量的规则，value 总是字节数
+		if used, _ := value.(uint64); e.belowIncrement(r, id, used) {
+			decision = decisionBelowIncrement
+		}
+	case e.inCooldown(r, id):
+		decision = decisionCooldown
+	}
+	e.audit(r, id, name, value, threshold, decision)
+	return decision == decisionAlert
+}
+
+// audit 记录一次规则评估，未配置审计日志时什么都不做。name 是进程名或用户名，value 和 threshold 为 nil 时不记录
+func (e *Engine) audit(r *rule, id uint32, name string, value, threshold any, decision string) {
+	if e.auditLog == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("rule", r.Name),
+		slog.String("type", string(r.Type)),
+		slog.Uint64("id", uint64(id)),
+		slog.String("decision", decision),
+	}
+	if r.level != "" {
+		attrs = append(attrs, slog.String("level", r.level))
+	}
+	if name != "" {
+		attrs = append(attrs, slog.String("name", name))
+	}
+	if value != nil {
+		attrs = append(attrs, slog.Any("value", value))
+	}
+	if threshold != nil {
+		attrs = append(attrs, slog.Any("threshold", threshold))
+	}
+	e.auditLog.LogAttrs(context.Background(), slog.LevelInfo, "Rule evaluated", attrs...)
+}
@@ -0,0 +1,82 @@
+// internal/engine/audit_test.go
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// auditEntry 是审计日志中一行 JSON 的判断字段
+type auditEntry struct {
+	Msg       string  `json:"msg"`
+	Rule      string  `json:"rule"`
+	Type      string  `json:"type"`
+	ID        uint32  `json:"id"`
+	Name      string  `json:"name"`
+	Decision  string  `json:"decision"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+}
+
+// readAudit 解码 buf 中的审计日志并清空 buf，以对象 ID 为键
+func readAudit(t *testing.T, buf *bytes.Buffer) map[uint32]auditEntry {
+	t.Helper()
+	entries := make(map[uint32]auditEntry)
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("audit line %q is not JSON: %v", scanner.Text(), err)
+		}
+		entries[entry.ID] = entry
+	}
+	buf.Reset()
+	return entries
+}
+
+func TestAuditLogDecisions(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 10
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 1
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	var buf bytes.Buffer
+	e.SetAuditLog(&buf)
+	feed(t, m,
+		xmit(pidA, "curl", 2*mb, "198.51.100.1", 443),
+		xmit(pidB, "sshd", mb/2, "198.51.100.2", 22),
+	)
+
+	// 第一次检查: curl 超限报警，sshd 未超限；第二次检查: curl 仍处于冷却期
+	for i, want := range []map[uint32]string{
+		{pidA: decisionAlert, pidB: decisionBelowThreshold},
+		{pidA: decisionCooldown, pidB: decisionBelowThreshold},
+	} {
+		e.CheckRules()
+		received(ch)
+		entries := readAudit(t, &buf)
+		if len(entries) != len(want) {
+			t.Fatalf("check %d logged %d evaluations, want %d: %+v", i+1, len(entries), len(want), entries)
+		}
+		for pid, decision := range want {
+			got := entries[pid]
+			if got.Decision != decision || got.Rule != "egress" || got.Type != "traffic" || got.Msg != "Rule evaluated" {
+				t.Errorf("check %d, PID %d: %+v, want decision %q for rule egress", i+1, pid, got, decision)
+			}
+			if got.Threshold != mb {
+				t.Errorf("check %d, PID %d: threshold = %v, want %d", i+1, pid, got.Threshold, mb)
+			}
+		}
+		if a, b := entries[pidA], entries[pidB]; a.Name != "curl" || a.Value != 2*mb || b.Name != "sshd" || b.Value != mb/2 {
+			t.Errorf("check %d: curl = %+v, sshd = %+v; want the process names with their byte counts", i+1, a, b)
+		}
+	}
+}
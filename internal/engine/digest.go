@@ -0,0 +1,71 @@
+// internal/engine/digest.go
+package engine
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"traffic-guardian/internal/alerter"
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/state"
+)
+
+// sendDigest 在启用了 rules.digest 时每隔 interval_minutes 发送一条摘要，列出时间窗口内发送流量最多的 top_n 个进程。
+// 第一份摘要在引擎第一次检查一个间隔之后发送；与退出摘要一样不经过冷却期，但受暂停和静默时间段限制
+func (e *Engine) sendDigest(stats []state.ProcessStats) {
+	digest := e.rules.Digest
+	if !digest.Enabled {
+		return
+	}
+	now := e.now()
+	if e.nextDigest.IsZero() {
+		e.nextDigest = now.Add(digest.GetInterval())
+		return
+	}
+	if now.Before(e.nextDigest) {
+		return
+	}
+	e.nextDigest = now.Add(digest.GetInterval())
+
+	if len(stats) == 0 {
+		return
+	}
+	if e.paused.Load() {
+		e.log.Debug("Digest suppressed while paused")
+		return
+	}
+	if e.muted {
+		e.suppressed++
+		e.log.Debug("Digest suppressed by mute window", "suppressed", e.suppressed)
+		return
+	}
+
+	var host state.ProcessStats
+	for _, s := range stats {
+		host.TotalBytes += s.TotalBytes
+		host.InternalBytes += s.InternalBytes
+		host.ExternalBytes += s.ExternalBytes
+	}
+	top := slices.Clone(stats)
+	slices.SortFunc(top, func(a, b state.ProcessStats) int {
+		return cmp.Compare(b.TotalBytes, a.TotalBytes)
+	})
+	top = top[:min(len(top), digest.GetTopN())]
+
+	e.log.Info("Sending traffic digest", "process_count", len(stats), "traffic_bytes", host.TotalBytes)
+	e.send(alerter.Alert{
+		Kind:      alerter.KindDigest,
+		Severity:  digest.GetSeverity(),
+		Timestamp: time.Now(),
+		RuleName:  config.DigestRuleName,
+		Reason:    alerter.ReasonDigest,
+		Detail: fmt.Sprintf("%d processes sent %s in total within %s, the top %d are listed above.",
+			len(stats), alerter.FormatBytes(host.TotalBytes), e.rules.GetTimeWindow(), len(top)),
+		Direction:    config.DirectionTX,
+		ProcessStats: host,
+		TopProcesses: top,
+		Alerters:     digest.Alerters,
+	})
+}
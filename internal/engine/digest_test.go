@@ -0,0 +1,70 @@
+// internal/engine/digest_test.go
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/alerter"
+)
+
+func TestDigestFiresOnSchedule(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  traffic_threshold_mb: 100
+  digest:
+    enabled: true
+    interval_minutes: 60
+    top_n: 2
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	feed(t, m,
+		xmit(pidA, "curl", 1*mb, "198.51.100.1", 443),
+		xmit(pidB, "rsync", 3*mb, "198.51.100.2", 873),
+		xmit(pidC, "nginx", 2*mb, "198.51.100.3", 443),
+	)
+	now := time.Unix(1700000000, 0)
+	e.now = func() time.Time { return now }
+
+	digests := func() []alerter.Alert {
+		var got []alerter.Alert
+		for _, a := range received(ch) {
+			if a.Kind == alerter.KindDigest {
+				got = append(got, a)
+			}
+		}
+		return got
+	}
+
+	// 第一次检查只安排下一份摘要，之后每 60 分钟发送一次
+	for _, step := range []struct {
+		after time.Duration
+		want  int
+	}{
+		{0, 0},
+		{30 * time.Minute, 0},
+		{30 * time.Minute, 1},
+		{59 * time.Minute, 0},
+		{time.Minute, 1},
+	} {
+		now = now.Add(step.after)
+		e.CheckRules()
+		got := digests()
+		if len(got) != step.want {
+			t.Fatalf("check at %s sent %d digests, want %d", now.Format(time.TimeOnly), len(got), step.want)
+		}
+		if step.want == 0 {
+			continue
+		}
+
+		d := got[0]
+		if d.Reason != alerter.ReasonDigest || d.ProcessStats.TotalBytes != 6*mb {
+			t.Errorf("digest reason = %q, total = %d; want %q, %d", d.Reason, d.ProcessStats.TotalBytes, alerter.ReasonDigest, 6*mb)
+		}
+		if len(d.TopProcesses) != 2 || d.TopProcesses[0].PID != pidB || d.TopProcesses[1].PID != pidC {
+			t.Errorf("top processes = %v, want rsync then nginx", d.TopProcesses)
+		}
+	}
+}
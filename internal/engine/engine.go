@@ -2,107 +2,940 @@
 package engine
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"traffic-guardian/internal/alerter"
 	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/match"
 	"traffic-guardian/internal/state"
 )
 
+// rule 是编译好的命名规则
+type rule struct {
+	config.RuleDefinition
+	threshold     uint64
+	rateThreshold float64
+	sigma         float64
+	warmup        int
+	cooldown      time.Duration
+	// realertEvery 大于 0 时代替冷却期: 流量每增长这么多字节才再次报警，见 RuleDefinition.RealertEveryMB
+	realertEvery uint64
+	matchComms   match.Set
+	// blocklist 是 blocklist 规则禁止通信的地址
+	blocklist *match.PrefixTable
+	// level 为 LevelWarn 时表示这是由 warn_threshold_mb 生成的预警规则
+	level string
+	// linkErr 是上一次读取链路速率失败的错误信息，用于只在变化时记录日志
+	linkErr string
+
+	// fired 和 lastFired 统计规则发出的警报，受 Engine.mu 保护
+	fired     uint64
+	lastFired time.Time
+}
+
+// LevelWarn 标识由 warn_threshold_mb 生成的预警规则
+const LevelWarn = "warn"
+
+// key 返回规则在冷却记录中的键，预警与主阈值分别计算冷却时间
+func (r *rule) key() string {
+	if r.level != "" {
+		return r.Name + "@" + r.level
+	}
+	return r.Name
+}
+
+// processBytes 返回进程在规则统计范围内的流量，配置了 window_minutes 的规则只统计滑动窗口内的流量
+func (r *rule) processBytes(s *state.ProcessStats) uint64 {
+	if name := r.GetWindowName(); name != "" {
+		w := s.Windows[name]
+		switch r.GetScope() {
+		case config.ScopeAll:
+			return w.TotalBytes()
+		case config.ScopeInternal:
+			return w.InternalBytes
+		default:
+			return w.ExternalBytes
+		}
+	}
+
+	switch r.GetScope() {
+	case config.ScopeAll:
+		return s.TotalBytes
+	case config.ScopeInternal:
+		return s.InternalBytes
+	default:
+		return s.ExternalBytes
+	}
+}
+
+// portBytes 返回端口在规则统计范围内的流量之和
+func (r *rule) portBytes(p *state.PortStats) uint64 {
+	switch r.GetScope() {
+	case config.ScopeAll:
+		return p.TotalBytes
+	case config.ScopeInternal:
+		return p.InternalBytes
+	default:
+		return p.ExternalBytes
+	}
+}
+
+// userBytes 返回用户在规则统计范围内的流量之和
+func (r *rule) userBytes(u *state.UserStats) uint64 {
+	switch r.GetScope() {
+	case config.ScopeAll:
+		return u.TotalBytes
+	case config.ScopeInternal:
+		return u.InternalBytes
+	default:
+		return u.ExternalBytes
+	}
+}
+
+// windowFor 返回规则统计流量的时间窗口，配置了 window_minutes 时为规则自己的滑动窗口
+func (e *Engine) windowFor(r *rule) time.Duration {
+	if r.WindowMinutes > 0 {
+		return time.Duration(r.WindowMinutes) * time.Minute
+	}
+	return e.rules.GetTimeWindow()
+}
+
+// reason 返回规则类型对应的警报原因
+func (r *rule) reason() alerter.Reason {
+	switch r.Type {
+	case config.RuleTypePerUser:
+		return alerter.ReasonPerUserThreshold
+	case config.RuleTypeHost:
+		return alerter.ReasonHostThreshold
+	case config.RuleTypePort:
+		return alerter.ReasonPortThreshold
+	case config.RuleTypeCIDR:
+		return alerter.ReasonSubnetThreshold
+	case config.RuleTypeRate:
+		return alerter.ReasonRate
+	case config.RuleTypeAnomaly:
+		return alerter.ReasonAnomaly
+	case config.RuleTypeGrowth:
+		return alerter.ReasonGrowth
+	case config.RuleTypeFanOut:
+		return alerter.ReasonFanOut
+	case config.RuleTypeBlocklist:
+		return alerter.ReasonBlocklist
+	default:
+		return alerter.ReasonCumulativeThreshold
+	}
+}
+
+// RuleStatus 是一条规则的配置及其触发统计，用于调优过于频繁的规则
+type RuleStatus struct {
+	config.RuleDefinition
+	// Level 为 "warn" 时表示这是规则的预警阈值
+	Level      string    `json:"level,omitempty"`
+	FiredCount uint64    `json:"fired_count"`
+	LastFired  time.Time `json:"last_fired"`
+}
+
+// alertKey 标识一条规则对某个对象（进程规则为 PID，用户规则为 UID）的警报
+type alertKey struct {
+	rule string
+	id   uint32
+}
+
 // Engine 负责将流量状态与规则进行比较并触发警报
 type Engine struct {
-	log             *slog.Logger
-	stateManager    *state.Manager
-	rules           config.Rules
-	alertChan       chan<- alerter.Alert
-	recentlyAlerted map[uint32]time.Time
-	mu              sync.Mutex
-	alertCooldown   time.Duration
+	log          *slog.Logger
+	stateManager *state.Manager
+	rules        config.Rules
+	compiled     []*rule
+	alertChan    chan<- alerter.Alert
+	// recentlyAlerted 记录每条规则对每个对象最近一次警报的时间
+	recentlyAlerted map[alertKey]time.Time
+	// rateWindows 记录 rate 规则对每个进程的平滑窗口和连续超限次数
+	rateWindows map[alertKey]*rateWindow
+	// streaks 记录每条规则对每个对象的连续报警次数，用于 escalate_after
+	streaks map[alertKey]*streak
+	// acknowledged 记录通过 Acknowledge 确认的警报的确认截止时间，键中的规则名称不包含级别
+	acknowledged map[alertKey]time.Time
+	// realerts 记录 realert_every_mb 规则对每个超限对象上一次报警时的流量
+	realerts map[alertKey]*realert
+	mu       sync.Mutex
+	// now 返回当前时间，用于判断静默时间段、宽限期和冷却期
+	now func() time.Time
+	// linkSpeed 返回接口的链路速率（单位: Mbit/s），用于 threshold_percent 规则
+	linkSpeed func(iface string) (uint64, error)
+	// muted 表示上一次检查时是否处于静默时间段，suppressed 是本次静默期间被抑制的警报数量
+	muted      bool
+	suppressed int
+	// lastCheck 是最近一次执行规则检查的时间（UnixNano）
+	lastCheck atomic.Int64
+	// paused 为 true 时规则照常检查但不发送警报，通过 API 的 /pause 和 /resume 切换
+	paused atomic.Bool
+	// pending 是通过 UpdateRules 提交、尚未应用的规则配置
+	pending atomic.Pointer[config.Rules]
+	// auditLog 不为 nil 时记录每一次规则评估，见 SetAuditLog
+	auditLog *slog.Logger
+	// nextDigest 是下一次发送流量摘要的时间，第一次检查时初始化
+	nextDigest time.Time
 }
 
 // NewEngine 创建一个新的规则引擎
 func NewEngine(log *slog.Logger, cfg *config.Config, stateManager *state.Manager, alertChan chan<- alerter.Alert) *Engine {
+	compiled := compileRules(log, cfg.Rules)
+	stateManager.SetBlocklist(blocklists(compiled))
 	return &Engine{
 		log:             log,
 		stateManager:    stateManager,
 		rules:           cfg.Rules,
+		compiled:        compiled,
 		alertChan:       alertChan,
-		recentlyAlerted: make(map[uint32]time.Time),
-		alertCooldown:   cfg.Rules.GetAlertCooldown(),
+		recentlyAlerted: make(map[alertKey]time.Time),
+		rateWindows:     make(map[alertKey]*rateWindow),
+		streaks:         make(map[alertKey]*streak),
+		acknowledged:    make(map[alertKey]time.Time),
+		realerts:        make(map[alertKey]*realert),
+		now:             time.Now,
+		linkSpeed:       readLinkSpeed,
+	}
+}
+
+// compileRules 将配置中的规则定义编译为引擎内部使用的规则
+func compileRules(log *slog.Logger, rules config.Rules) []*rule {
+	defs := rules.GetDefinitions()
+	compiled := make([]*rule, 0, len(defs))
+	for _, d := range defs {
+		matchComms, err := match.CompileAll(d.MatchComms)
+		if err != nil {
+			// 配置在加载时已经校验过，这里只在调用方绕过校验时发生
+			log.Error("Invalid match_comms, rule applies to all processes", "rule", d.Name, "error", err)
+		}
+		r := &rule{
+			RuleDefinition: d,
+			threshold:      d.GetThresholdBytes(),
+			rateThreshold:  d.GetRateThresholdBps(),
+			sigma:          d.GetSigma(),
+			warmup:         d.GetWarmupSamples(),
+			cooldown:       d.GetCooldown(rules.GetAlertCooldown()),
+			realertEvery:   d.GetRealertEveryBytes(),
+			matchComms:     matchComms,
+		}
+		if d.Type == config.RuleTypeBlocklist {
+			prefixes, err := match.ParsePrefixes(d.CIDRs)
+			if err != nil {
+				log.Error("Invalid blocklist cidrs, rule is disabled", "rule", d.Name, "error", err)
+			}
+			r.blocklist = match.NewPrefixTable(prefixes)
+		}
+		compiled = append(compiled, r)
+
+		// 预警阈值作为一条独立的规则，使用更低的严重级别和独立的冷却记录
+		if d.WarnThresholdMB > 0 {
+			warn := *r
+			warn.Severity = d.GetWarnSeverity()
+			warn.threshold = d.GetWarnThresholdBytes()
+			warn.level = LevelWarn
+			compiled = append(compiled, &warn)
+		}
+	}
+	return compiled
+}
+
+// blocklists 返回所有 blocklist 规则禁止通信的地址，交给状态管理器保证这些对端总是被记录
+func blocklists(compiled []*rule) []*match.PrefixTable {
+	var tables []*match.PrefixTable
+	for _, r := range compiled {
+		if r.Type == config.RuleTypeBlocklist && r.blocklist.Len() > 0 {
+			tables = append(tables, r.blocklist)
+		}
 	}
+	return tables
 }
 
 // Start 启动规则引擎的检查循环
 func (e *Engine) Start(ctx context.Context) {
 	e.log.Info("Starting rule engine")
-	ticker := time.NewTicker(e.rules.GetCheckInterval())
+	interval := e.rules.GetCheckInterval()
+	ticker := time.NewTicker(config.Jitter(interval, e.rules.CheckJitterPercent))
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
+			// 退出前做最后一次检查，避免丢失最后一个周期内的违规
+			e.CheckRules()
 			e.log.Info("Rule engine stopped")
 			return
 		case <-ticker.C:
-			e.checkRules()
+			stats := e.check()
+			next := interval
+			if e.rules.AdaptiveInterval.Enabled {
+				// 动态调整检查间隔；速率仍按 check_interval_seconds 采样
+				next = e.nextInterval(interval, stats)
+				if next != interval {
+					e.log.Debug("Adjusting check interval", "from", interval, "to", next)
+				}
+			}
+			// 启用抖动时每个周期都重新随机下一次检查的时间
+			if next != interval || e.rules.CheckJitterPercent > 0 {
+				interval = next
+				ticker.Reset(config.Jitter(interval, e.rules.CheckJitterPercent))
+			}
 		}
 	}
 }
 
-// checkRules 获取最新状态并与规则进行比较
-func (e *Engine) checkRules() {
+// CheckRules 获取最新状态并与所有规则进行比较
+func (e *Engine) CheckRules() {
+	e.check()
+}
+
+// check 执行一次规则检查，返回本次检查使用的进程状态，供自适应检查间隔使用
+func (e *Engine) check() []state.ProcessStats {
+	start := e.now()
+	e.lastCheck.Store(time.Now().UnixNano())
+	e.applyPendingRules()
 	stats := e.stateManager.GetStats()
-	if len(stats) == 0 {
+	exited := e.stateManager.TakeExited()
+	if len(stats) == 0 && len(exited) == 0 {
+		return stats
+	}
+
+	e.updateMute()
+	e.log.Debug("Checking rules", "process_count", len(stats), "rule_count", len(e.compiled), "muted", e.muted)
+	e.reportExits(exited)
+	e.sendDigest(stats)
+
+	var (
+		users   []state.UserStats
+		ports   []state.PortStats
+		subnets []state.SubnetStats
+	)
+	for _, r := range e.compiled {
+		switch r.Type {
+		case config.RuleTypePerUser:
+			if users == nil {
+				users = e.stateManager.GetStatsByUID()
+			}
+			e.checkUserRule(r, users)
+		case config.RuleTypeHost:
+			e.checkHostRule(r, stats)
+		case config.RuleTypePort:
+			if ports == nil {
+				ports = e.stateManager.GetStatsByLocalPort()
+			}
+			e.checkPortRule(r, ports)
+		case config.RuleTypeCIDR:
+			if subnets == nil {
+				subnets = e.stateManager.GetStatsBySubnet()
+			}
+			e.checkSubnetRule(r, subnets)
+		case config.RuleTypeRate:
+			e.checkRateRule(r, stats)
+		case config.RuleTypeAnomaly:
+			e.checkAnomalyRule(r, stats)
+		case config.RuleTypeGrowth:
+			e.checkGrowthRule(r, stats)
+		case config.RuleTypeFanOut:
+			e.checkFanOutRule(r, stats)
+		case config.RuleTypeBlocklist:
+			e.checkBlocklistRule(r, stats)
+		default:
+			e.checkProcessRule(r, stats)
+		}
+	}
+	e.pruneStreaks()
+	e.pruneRealerts(start)
+	return stats
+}
+
+// Pause 暂停发送警报，状态和规则检查不受影响。暂停期间的违规不进入冷却期，恢复后仍然超限的对象会立即报警
+func (e *Engine) Pause() {
+	if !e.paused.Swap(true) {
+		e.log.Info("Alerting paused")
+	}
+}
+
+// Resume 恢复发送警报
+func (e *Engine) Resume() {
+	if e.paused.Swap(false) {
+		e.log.Info("Alerting resumed")
+	}
+}
+
+// Paused 检查警报是否已暂停
+func (e *Engine) Paused() bool {
+	return e.paused.Load()
+}
+
+// LastCheck 返回最近一次执行规则检查的时间，尚未检查过时返回零值
+func (e *Engine) LastCheck() time.Time {
+	if ns := e.lastCheck.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// checkProcessRule 将每个进程的累计流量与规则阈值进行比较
+func (e *Engine) checkProcessRule(r *rule, stats []state.ProcessStats) {
+	threshold, ok := e.thresholdFor(r)
+	if !ok {
 		return
 	}
+	for _, s := range stats {
+		if len(r.matchComms) > 0 && !r.matchComms.MatchAny(s.Comm) {
+			continue
+		}
+		if e.inGracePeriod(r, &s) {
+			e.audit(r, s.PID, s.Comm, nil, nil, decisionGracePeriod)
+			continue
+		}
+		used := r.processBytes(&s)
+		if !e.decide(r, s.PID, s.Comm, used, threshold, used > threshold) {
+			continue
+		}
 
-	e.log.Debug("Checking rules", "process_count", len(stats))
+		e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "pid", s.PID, "comm", s.Comm, "scope", r.GetScope(), "traffic_bytes", used, "threshold_bytes", threshold)
 
-	threshold := e.rules.GetTrafficThresholdBytes()
+		e.emit(r, s.PID, alerter.Alert{
+			Kind:      alerter.KindProcess,
+			Severity:  r.GetSeverity(),
+			Timestamp: time.Now(),
+			RuleName:  r.Name,
+			Reason:    r.reason(),
+			Detail: fmt.Sprintf("Process %q sent %s of %s traffic, exceeding the %s limit within %s.",
+				s.Comm, alerter.FormatBytes(used), r.GetScope(), alerter.FormatBytes(threshold), e.windowFor(r)),
+			ThresholdBytes: threshold,
+			Direction:      r.GetDirection(),
+			ProcessStats:   s,
+			Alerters:       r.Alerters,
+		})
+	}
+}
 
+// checkRateRule 将每个进程平滑后的发送速率与规则阈值进行比较，
+// 平滑速率需要连续 consecutive_breaches 个样本超过阈值才报警
+func (e *Engine) checkRateRule(r *rule, stats []state.ProcessStats) {
+	defer e.pruneRateWindows(r, stats)
 	for _, s := range stats {
-		if s.TotalBytes > threshold {
-			if !e.isRecentlyAlerted(s.PID) {
-				e.log.Warn("Rule violated", "pid", s.PID, "traffic_bytes", s.TotalBytes, "threshold_bytes", threshold)
-
-				// 发送警报到警报 channel
-				e.alertChan <- alerter.Alert{
-					ProcessStats: s,
-					Timestamp:    time.Now(),
-				}
+		if len(r.matchComms) > 0 && !r.matchComms.MatchAny(s.Comm) {
+			continue
+		}
+		if e.inGracePeriod(r, &s) {
+			e.audit(r, s.PID, s.Comm, nil, nil, decisionGracePeriod)
+			continue
+		}
+		rate, breached := e.observeRate(r, &s)
+		if !e.decide(r, s.PID, s.Comm, rate, r.rateThreshold, breached) {
+			continue
+		}
 
-				// 标记此进程为已警报
-				e.markAsAlerted(s.PID)
-			}
+		e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "pid", s.PID, "comm", s.Comm, "smoothed_rate_bps", rate, "threshold_bps", r.rateThreshold, "consecutive_breaches", r.GetConsecutiveBreaches())
+
+		e.emit(r, s.PID, alerter.Alert{
+			Kind:      alerter.KindProcess,
+			Severity:  r.GetSeverity(),
+			Timestamp: time.Now(),
+			RuleName:  r.Name,
+			Reason:    r.reason(),
+			Detail: fmt.Sprintf("Process %q is sending %s/s (smoothed), exceeding the %s/s limit.",
+				s.Comm, alerter.FormatBytes(uint64(rate)), alerter.FormatBytes(uint64(r.rateThreshold))),
+			ThresholdRateBps: r.rateThreshold,
+			ObservedRateBps:  rate,
+			Direction:        r.GetDirection(),
+			ProcessStats:     s,
+			Alerters:         r.Alerters,
+		})
+	}
+}
+
+// checkAnomalyRule 将每个进程最新的速率样本与其 EWMA 基线进行比较，
+// 超过 mean + sigma×stddev（且不低于规则的最低速率）时报警
+func (e *Engine) checkAnomalyRule(r *rule, stats []state.ProcessStats) {
+	for _, s := range stats {
+		if len(r.matchComms) > 0 && !r.matchComms.MatchAny(s.Comm) {
+			continue
+		}
+		if e.inGracePeriod(r, &s) {
+			e.audit(r, s.PID, s.Comm, nil, nil, decisionGracePeriod)
+			continue
+		}
+		// 基线由最新样本之前的样本构成，样本不足时不做判断
+		if s.RateSamples-1 < r.warmup {
+			e.audit(r, s.PID, s.Comm, s.RateBps, nil, decisionWarmup)
+			continue
+		}
+		limit := s.BaselineRateBps + r.sigma*s.BaselineStdDevBps
+		if !e.decide(r, s.PID, s.Comm, s.RateBps, limit, s.RateBps > limit && s.RateBps > r.rateThreshold) {
+			continue
+		}
+
+		e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "pid", s.PID, "comm", s.Comm, "rate_bps", s.RateBps, "baseline_bps", s.BaselineRateBps, "stddev_bps", s.BaselineStdDevBps, "limit_bps", limit)
+
+		e.emit(r, s.PID, alerter.Alert{
+			Kind:      alerter.KindProcess,
+			Severity:  r.GetSeverity(),
+			Timestamp: time.Now(),
+			RuleName:  r.Name,
+			Reason:    r.reason(),
+			Detail: fmt.Sprintf("Process %q is sending %s/s, far above its usual %s/s (limit %s/s at %.1f sigma).",
+				s.Comm, alerter.FormatBytes(uint64(s.RateBps)), alerter.FormatBytes(uint64(s.BaselineRateBps)), alerter.FormatBytes(uint64(limit)), r.sigma),
+			ThresholdRateBps: limit,
+			ObservedRateBps:  s.RateBps,
+			Direction:        r.GetDirection(),
+			ProcessStats:     s,
+			Alerters:         r.Alerters,
+		})
+	}
+}
+
+// checkGrowthRule 将每个进程最新的速率样本与其 EWMA 基线比较，增长超过 increase_percent 时报警。
+// 基线为 0 的进程没有可比较的正常水平，不做判断
+func (e *Engine) checkGrowthRule(r *rule, stats []state.ProcessStats) {
+	for _, s := range stats {
+		if len(r.matchComms) > 0 && !r.matchComms.MatchAny(s.Comm) {
+			continue
+		}
+		if e.inGracePeriod(r, &s) {
+			e.audit(r, s.PID, s.Comm, nil, nil, decisionGracePeriod)
+			continue
+		}
+		// 基线由最新样本之前的样本构成，样本不足时不做判断
+		if s.RateSamples-1 < r.warmup || s.BaselineRateBps <= 0 {
+			e.audit(r, s.PID, s.Comm, s.RateBps, nil, decisionWarmup)
+			continue
 		}
+		limit := s.BaselineRateBps * (1 + r.IncreasePercent/100)
+		if !e.decide(r, s.PID, s.Comm, s.RateBps, limit, s.RateBps > limit && s.RateBps > r.rateThreshold) {
+			continue
+		}
+		increase := (s.RateBps/s.BaselineRateBps - 1) * 100
+
+		e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "pid", s.PID, "comm", s.Comm, "rate_bps", s.RateBps, "baseline_bps", s.BaselineRateBps, "increase_percent", increase, "limit_bps", limit)
+
+		e.emit(r, s.PID, alerter.Alert{
+			Kind:      alerter.KindProcess,
+			Severity:  r.GetSeverity(),
+			Timestamp: time.Now(),
+			RuleName:  r.Name,
+			Reason:    r.reason(),
+			Detail: fmt.Sprintf("Process %q is sending %s/s, %.0f%% above its usual %s/s (limit +%.0f%%).",
+				s.Comm, alerter.FormatBytes(uint64(s.RateBps)), increase, alerter.FormatBytes(uint64(s.BaselineRateBps)), r.IncreasePercent),
+			ThresholdRateBps: limit,
+			ObservedRateBps:  s.RateBps,
+			Direction:        r.GetDirection(),
+			ProcessStats:     s,
+			Alerters:         r.Alerters,
+		})
 	}
 }
 
-// isRecentlyAlerted 检查一个进程是否在冷却期内
-func (e *Engine) isRecentlyAlerted(pid uint32) bool {
+// checkFanOutRule 将每个进程在时间窗口内通信过的不同对端数量与规则阈值进行比较，
+// 用于发现端口扫描或向大量地址回连的进程
+func (e *Engine) checkFanOutRule(r *rule, stats []state.ProcessStats) {
+	for _, s := range stats {
+		if len(r.matchComms) > 0 && !r.matchComms.MatchAny(s.Comm) {
+			continue
+		}
+		if e.inGracePeriod(r, &s) {
+			e.audit(r, s.PID, s.Comm, nil, nil, decisionGracePeriod)
+			continue
+		}
+		if !e.decide(r, s.PID, s.Comm, s.ConnectionCount, r.MaxConnections, s.ConnectionCount > r.MaxConnections) {
+			continue
+		}
+
+		e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "pid", s.PID, "comm", s.Comm, "connection_count", s.ConnectionCount, "max_connections", r.MaxConnections)
+
+		e.emit(r, s.PID, alerter.Alert{
+			Kind:      alerter.KindProcess,
+			Severity:  r.GetSeverity(),
+			Timestamp: time.Now(),
+			RuleName:  r.Name,
+			Reason:    r.reason(),
+			Detail: fmt.Sprintf("Process %q talked to %d distinct remote endpoints, exceeding the limit of %d within %s.",
+				s.Comm, s.ConnectionCount, r.MaxConnections, e.rules.GetTimeWindow()),
+			ThresholdConnections: r.MaxConnections,
+			Direction:            r.GetDirection(),
+			ProcessStats:         s,
+			Alerters:             r.Alerters,
+		})
+	}
+}
+
+// checkBlocklistRule 检查每个进程在时间窗口内通信过的对端是否在规则禁止的地址中，命中即报警，与流量大小无关。
+// 不受新进程宽限期约束；只能发现 perf 模式下记录了对端地址的流量
+func (e *Engine) checkBlocklistRule(r *rule, stats []state.ProcessStats) {
+	if r.blocklist.Len() == 0 {
+		return
+	}
+	matched := e.stateManager.MatchDestinations(r.blocklist)
+	for _, s := range stats {
+		if len(r.matchComms) > 0 && !r.matchComms.MatchAny(s.Comm) {
+			continue
+		}
+		dests := matched[s.PID]
+		if !e.decide(r, s.PID, s.Comm, len(dests), 0, len(dests) > 0) {
+			continue
+		}
+
+		first := dests[0]
+		prefix, _ := r.blocklist.Lookup(first.Addr)
+		e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "pid", s.PID, "comm", s.Comm, "remote", netip.AddrPortFrom(first.Addr, first.Port), "cidr", prefix, "endpoint_count", len(dests))
+
+		e.emit(r, s.PID, alerter.Alert{
+			Kind:      alerter.KindProcess,
+			Severity:  r.GetSeverity(),
+			Timestamp: time.Now(),
+			RuleName:  r.Name,
+			Reason:    r.reason(),
+			Detail: fmt.Sprintf("Process %q (PID %d) communicated with %d blocklisted endpoint(s) within %s, including %s (listed as %s).",
+				s.Comm, s.PID, len(dests), e.rules.GetTimeWindow(), netip.AddrPortFrom(first.Addr, first.Port), prefix),
+			Direction:    r.GetDirection(),
+			ProcessStats: s,
+			Destinations: dests[:min(len(dests), topDestinationCount)],
+			Alerters:     r.Alerters,
+		})
+	}
+}
+
+// checkUserRule 将每个用户所有进程的流量之和与规则阈值进行比较
+func (e *Engine) checkUserRule(r *rule, users []state.UserStats) {
+	threshold, ok := e.thresholdFor(r)
+	if !ok {
+		return
+	}
+	for _, u := range users {
+		used := r.userBytes(&u)
+		if !e.decide(r, u.UID, u.Username, used, threshold, used > threshold) {
+			continue
+		}
+
+		e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "uid", u.UID, "username", u.Username, "scope", r.GetScope(), "traffic_bytes", used, "threshold_bytes", threshold)
+
+		userStats := u
+		e.emit(r, u.UID, alerter.Alert{
+			Kind:      alerter.KindUser,
+			Severity:  r.GetSeverity(),
+			Timestamp: time.Now(),
+			RuleName:  r.Name,
+			Reason:    r.reason(),
+			Detail: fmt.Sprintf("User %q sent %s of %s traffic across %d processes, exceeding the %s per-user limit within %s.",
+				u.Username, alerter.FormatBytes(used), r.GetScope(), u.ProcessCount, alerter.FormatBytes(threshold), e.rules.GetTimeWindow()),
+			ThresholdBytes: threshold,
+			Direction:      r.GetDirection(),
+			ProcessStats:   state.ProcessStats{UID: u.UID, Username: u.Username, TotalBytes: u.TotalBytes, InternalBytes: u.InternalBytes, ExternalBytes: u.ExternalBytes},
+			UserStats:      &userStats,
+			Alerters:       r.Alerters,
+		})
+	}
+}
+
+// checkPortRule 将规则指定的本端端口上的流量之和与阈值进行比较，冷却记录以端口号为对象 ID
+func (e *Engine) checkPortRule(r *rule, ports []state.PortStats) {
+	i := slices.IndexFunc(ports, func(p state.PortStats) bool { return p.Port == r.LocalPort })
+	if i < 0 {
+		return
+	}
+	threshold, ok := e.thresholdFor(r)
+	if !ok {
+		return
+	}
+	p := ports[i]
+	used := r.portBytes(&p)
+	if !e.decide(r, uint32(p.Port), "", used, threshold, used > threshold) {
+		return
+	}
+
+	e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "local_port", p.Port, "scope", r.GetScope(), "traffic_bytes", used, "threshold_bytes", threshold)
+
+	e.emit(r, uint32(p.Port), alerter.Alert{
+		Kind:      alerter.KindPort,
+		Severity:  r.GetSeverity(),
+		Timestamp: time.Now(),
+		RuleName:  r.Name,
+		Reason:    r.reason(),
+		Detail: fmt.Sprintf("Local port %d served %s of %s traffic across %d processes, exceeding the %s limit within %s.",
+			p.Port, alerter.FormatBytes(used), r.GetScope(), p.ProcessCount, alerter.FormatBytes(threshold), e.rules.GetTimeWindow()),
+		ThresholdBytes: threshold,
+		Direction:      r.GetDirection(),
+		ProcessStats:   state.ProcessStats{TotalBytes: p.TotalBytes, InternalBytes: p.InternalBytes, ExternalBytes: p.ExternalBytes},
+		PortStats:      &p,
+		Alerters:       r.Alerters,
+	})
+}
+
+// checkSubnetRule 将所有进程发往规则目的网段的流量之和与阈值进行比较，每条规则只有一个对象
+func (e *Engine) checkSubnetRule(r *rule, subnets []state.SubnetStats) {
+	i := slices.IndexFunc(subnets, func(s state.SubnetStats) bool { return s.Rule == r.Name })
+	if i < 0 {
+		// 重新加载配置时新增的 cidr 规则在重启前没有统计
+		return
+	}
+	threshold, ok := e.thresholdFor(r)
+	if !ok {
+		return
+	}
+	s := subnets[i]
+	if !e.decide(r, hostAlertID, "", s.TotalBytes, threshold, s.TotalBytes > threshold) {
+		return
+	}
+
+	e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "cidrs", s.CIDRs, "process_count", s.ProcessCount, "traffic_bytes", s.TotalBytes, "threshold_bytes", threshold)
+
+	e.emit(r, hostAlertID, alerter.Alert{
+		Kind:      alerter.KindSubnet,
+		Severity:  r.GetSeverity(),
+		Timestamp: time.Now(),
+		RuleName:  r.Name,
+		Reason:    r.reason(),
+		Detail: fmt.Sprintf("Processes sent %s to %s across %d processes, exceeding the %s limit within %s.",
+			alerter.FormatBytes(s.TotalBytes), strings.Join(s.CIDRs, ", "), s.ProcessCount, alerter.FormatBytes(threshold), e.rules.GetTimeWindow()),
+		ThresholdBytes: threshold,
+		Direction:      r.GetDirection(),
+		ProcessStats:   state.ProcessStats{TotalBytes: s.TotalBytes},
+		SubnetStats:    &s,
+		Alerters:       r.Alerters,
+	})
+}
+
+// hostAlertID 是主机和网段规则在冷却记录中使用的对象 ID，这两类规则只有一个对象
+const hostAlertID = 0
+
+// topContributorCount 是主机警报中附带的进程数量
+const topContributorCount = 5
+
+// checkHostRule 将所有进程（配置了 match_comms 时只包括匹配的进程）在规则统计范围内的流量之和与阈值进行比较，
+// 警报中附带流量最多的几个进程
+func (e *Engine) checkHostRule(r *rule, stats []state.ProcessStats) {
+	threshold, ok := e.thresholdFor(r)
+	if !ok {
+		return
+	}
+	var (
+		used     uint64
+		host     state.ProcessStats
+		included []state.ProcessStats
+	)
+	for _, s := range stats {
+		if len(r.matchComms) > 0 && !r.matchComms.MatchAny(s.Comm) {
+			continue
+		}
+		used += r.processBytes(&s)
+		host.TotalBytes += s.TotalBytes
+		host.InternalBytes += s.InternalBytes
+		host.ExternalBytes += s.ExternalBytes
+		included = append(included, s)
+	}
+	if !e.decide(r, hostAlertID, "", used, threshold, used > threshold) {
+		return
+	}
+
+	e.log.Warn("Rule violated", "rule", r.Name, "level", r.level, "reason", r.reason(), "scope", r.GetScope(), "process_count", len(included), "traffic_bytes", used, "threshold_bytes", threshold)
+
+	slices.SortFunc(included, func(a, b state.ProcessStats) int {
+		return cmp.Compare(r.processBytes(&b), r.processBytes(&a))
+	})
+	e.emit(r, hostAlertID, alerter.Alert{
+		Kind:      alerter.KindHost,
+		Severity:  r.GetSeverity(),
+		Timestamp: time.Now(),
+		RuleName:  r.Name,
+		Reason:    r.reason(),
+		Detail: fmt.Sprintf("All processes together sent %s of %s traffic across %d processes, exceeding the %s host limit within %s.",
+			alerter.FormatBytes(used), r.GetScope(), len(included), alerter.FormatBytes(threshold), e.windowFor(r)),
+		ThresholdBytes: threshold,
+		Direction:      r.GetDirection(),
+		ProcessStats:   host,
+		TopProcesses:   included[:min(len(included), topContributorCount)],
+		Alerters:       r.Alerters,
+	})
+}
+
+// reportExits 为累计流量达到 exit_report.min_total_mb 的已退出进程发送摘要。
+// 摘要不经过冷却期（每个进程只会退出一次），但同样受暂停和静默时间段限制
+func (e *Engine) reportExits(exited []state.ProcessStats) {
+	report := e.rules.ExitReport
+	minBytes := report.GetMinTotalBytes()
+	for _, s := range exited {
+		if s.TotalBytes < minBytes {
+			continue
+		}
+		if e.paused.Load() {
+			e.log.Debug("Exit report suppressed while paused", "pid", s.PID)
+			continue
+		}
+		if e.muted {
+			e.suppressed++
+			e.log.Debug("Exit report suppressed by mute window", "pid", s.PID, "suppressed", e.suppressed)
+			continue
+		}
+
+		e.log.Info("Process finished", "pid", s.PID, "comm", s.Comm, "traffic_bytes", s.TotalBytes)
+		e.send(alerter.Alert{
+			Kind:      alerter.KindProcess,
+			Severity:  report.GetSeverity(),
+			Timestamp: time.Now(),
+			RuleName:  config.ExitReportRuleName,
+			Reason:    alerter.ReasonProcessExit,
+			Detail: fmt.Sprintf("Process %q finished after sending %s in %s.",
+				s.Comm, alerter.FormatBytes(s.TotalBytes), s.ExitedAt.Sub(s.FirstSeen).Round(time.Second)),
+			ThresholdBytes: minBytes,
+			Direction:      config.DirectionTX,
+			ProcessStats:   s,
+			Destinations:   e.stateManager.TopDestinations(s.PID, topDestinationCount),
+			Alerters:       report.Alerters,
+		})
+	}
+}
+
+// inGracePeriod 检查进程是否仍处于规则的宽限期内（从开始被跟踪时计算），宽限期内不触发进程规则
+func (e *Engine) inGracePeriod(r *rule, s *state.ProcessStats) bool {
+	grace := r.GetGracePeriod(e.rules.GetGracePeriod())
+	return grace > 0 && e.now().Sub(s.FirstSeen) < grace
+}
+
+// topDestinationCount 是进程警报中附带的对端数量
+const topDestinationCount = 5
+
+// emit 发送一条警报并记录冷却时间。暂停期间警报被丢弃，静默期间警报只被计数而不发送，
+// 也不进入冷却期，这样静默结束后仍然超限的对象会立即报警
+func (e *Engine) emit(r *rule, id uint32, alert alerter.Alert) {
+	if e.paused.Load() {
+		e.log.Debug("Alert suppressed while paused", "rule", r.Name, "id", id)
+		return
+	}
+	if e.muted {
+		e.suppressed++
+		e.log.Debug("Alert suppressed by mute window", "rule", r.Name, "id", id, "suppressed", e.suppressed)
+		return
+	}
+
+	if alert.Kind == alerter.KindProcess && alert.Destinations == nil {
+		alert.Destinations = e.stateManager.TopDestinations(id, topDestinationCount)
+	}
+
+	repeat := e.escalate(r, id, &alert)
+
+	// 发送警报到警报 channel
+	if !e.send(alert) {
+		return
+	}
+	e.markAsAlerted(r, id)
+	e.recordStreak(r, id, repeat)
+
+	e.mu.Lock()
+	r.fired++
+	r.lastFired = alert.Timestamp
+	e.mu.Unlock()
+}
+
+// send 将警报写入警报 channel。channel 已被关闭时（例如关闭过程中的竞争）丢弃警报并返回 false，
+// 而不是让进程崩溃
+func (e *Engine) send(alert alerter.Alert) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			e.log.Warn("Alert channel is closed, dropping alert", "rule", alert.RuleName, "pid", alert.ProcessStats.PID)
+			ok = false
+		}
+	}()
+	e.alertChan <- alert
+	return true
+}
+
+// RuleStatuses 按配置顺序返回每条规则的触发次数和最近一次触发时间
+func (e *Engine) RuleStatuses() []RuleStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	statuses := make([]RuleStatus, 0, len(e.compiled))
+	for _, r := range e.compiled {
+		statuses = append(statuses, RuleStatus{
+			RuleDefinition: r.RuleDefinition,
+			Level:          r.level,
+			FiredCount:     r.fired,
+			LastFired:      r.lastFired,
+		})
+	}
+	return statuses
+}
+
+// updateMute 根据当前时间更新静默状态，静默结束时记录期间被抑制的警报数量
+func (e *Engine) updateMute() {
+	muted := e.rules.IsMuted(e.now())
+	switch {
+	case muted && !e.muted:
+		e.log.Info("Entering mute window, alerts will be suppressed")
+	case !muted && e.muted:
+		e.log.Info("Mute window ended", "suppressed", e.suppressed)
+		e.suppressed = 0
+	}
+	e.muted = muted
+}
+
+// inCooldown 检查规则对某个对象的警报是否仍在冷却期或确认期内，冷却期已过的记录会被删除
+func (e *Engine) inCooldown(r *rule, id uint32) bool {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	lastAlertTime, ok := e.recentlyAlerted[pid]
+	if e.acknowledgedLocked(r, id) {
+		return true
+	}
+
+	key := alertKey{rule: r.key(), id: id}
+	lastAlertTime, ok := e.recentlyAlerted[key]
 	if !ok {
 		return false
 	}
 
-	if time.Since(lastAlertTime) > e.alertCooldown {
+	now := e.now()
+	elapsed := now.Sub(lastAlertTime)
+	if elapsed < 0 {
+		// 时钟倒退：从现在开始重新计算冷却期，避免冷却期被延长到时钟追上为止
+		e.recentlyAlerted[key] = now
+		return true
+	}
+	if elapsed > r.cooldown {
 		// 冷却期已过，可以再次报警
-		delete(e.recentlyAlerted, pid)
+		delete(e.recentlyAlerted, key)
 		return false
 	}
 
 	return true
 }
 
-// markAsAlerted 记录一个进程的警报时间
-func (e *Engine) markAsAlerted(pid uint32) {
+// markAsAlerted 记录规则对某个对象的警报时间
+func (e *Engine) markAsAlerted(r *rule, id uint32) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	e.recentlyAlerted[pid] = time.Now()
+	e.recentlyAlerted[alertKey{rule: r.key(), id: id}] = e.now()
+	if r.realertEvery > 0 {
+		e.recordRealertLocked(r, id)
+	}
+}
+
+// ClearAlert 清除一个进程在所有进程规则上的警报冷却和确认记录，使其在再次超限时可以立即报警
+func (e *Engine) ClearAlert(pid uint32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range e.compiled {
+		// 用户和端口规则的冷却记录以 UID 和端口号为对象 ID，网段规则只有一个对象，
+		// 主机规则以 hostAlertID (0) 为对象 ID，不能被某个进程的重置清除
+		switch r.Type {
+		case config.RuleTypePerUser, config.RuleTypePort, config.RuleTypeCIDR, config.RuleTypeHost:
+		default:
+			delete(e.recentlyAlerted, alertKey{rule: r.key(), id: pid})
+			delete(e.rateWindows, alertKey{rule: r.key(), id: pid})
+			delete(e.streaks, alertKey{rule: r.key(), id: pid})
+			delete(e.realerts, alertKey{rule: r.key(), id: pid})
+			delete(e.acknowledged, alertKey{rule: r.Name, id: pid})
+		}
+	}
 }
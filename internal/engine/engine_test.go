@@ -0,0 +1,610 @@
+// internal/engine/engine_test.go
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/alerter"
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/state"
+)
+
+// mb 是测试中常用的流量单位
+const mb = 1024 * 1024
+
+// 测试事件使用大于 PID_MAX_LIMIT (4194304) 的 PID，保证不会被当作 /proc 中的内核线程丢弃
+const (
+	pidA uint32 = 5000001
+	pidB uint32 = 5000002
+	pidC uint32 = 5000003
+)
+
+// discardLogger 返回一个丢弃所有输出的 Logger
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// loadConfig 将 YAML 写入临时文件并通过 config.LoadConfig 加载，与真实配置经过相同的展开和校验
+func loadConfig(t *testing.T, doc string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg
+}
+
+// newTestEngine 创建一个使用 cfg 的规则引擎，返回引擎、它读取的状态管理器和接收警报的 channel
+func newTestEngine(t *testing.T, cfg *config.Config) (*Engine, *state.Manager, chan alerter.Alert) {
+	t.Helper()
+	m := state.NewManager(discardLogger(), cfg)
+	alerts := make(chan alerter.Alert, 100)
+	return NewEngine(discardLogger(), cfg, m, alerts), m, alerts
+}
+
+// feed 通过状态管理器的主循环处理 events，返回时所有事件都已计入
+func feed(t *testing.T, m *state.Manager, events ...collector.TrafficEvent) {
+	t.Helper()
+	ch := make(chan collector.TrafficEvent)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Start(ctx, ch)
+	}()
+	for _, event := range events {
+		ch <- event
+	}
+	// 无缓冲的 channel 保证最后一个事件已被取走，Start 退出前会处理完它
+	cancel()
+	<-done
+}
+
+// xmit 构造一个发送到 remote 的事件，remote 为空时对端地址无法解析
+func xmit(pid uint32, comm string, n uint64, remote string, port uint16) collector.TrafficEvent {
+	event := collector.TrafficEvent{PID: pid, UID: uint32(os.Getuid()), Len: n, RemotePort: port, Packets: 1}
+	copy(event.Comm[:], comm)
+	if remote == "" {
+		return event
+	}
+	addr := netip.MustParseAddr(remote)
+	if addr.Is4() {
+		event.Family = 4
+		a4 := addr.As4()
+		copy(event.RemoteAddr[:], a4[:])
+	} else {
+		event.Family = 6
+		event.RemoteAddr = addr.As16()
+	}
+	return event
+}
+
+// received 不阻塞地取出 channel 中所有的警报
+func received(ch chan alerter.Alert) []alerter.Alert {
+	var alerts []alerter.Alert
+	for {
+		select {
+		case a := <-ch:
+			alerts = append(alerts, a)
+		default:
+			return alerts
+		}
+	}
+}
+
+// ruleNames 返回警报的规则名称，用于比较
+func ruleNames(alerts []alerter.Alert) []string {
+	names := make([]string, len(alerts))
+	for i, a := range alerts {
+		names[i] = a.RuleName
+	}
+	return names
+}
+
+func TestClearAlertKeepsHostCooldown(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 60
+  host_egress_threshold_mb: 1
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 1
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	feed(t, m, xmit(pidA, "curl", 2*mb, "198.51.100.1", 443))
+
+	e.CheckRules()
+	if got := received(ch); len(got) != 2 {
+		t.Fatalf("first check sent %v, want egress and host_egress", ruleNames(got))
+	}
+
+	// 主机规则的对象 ID 是 0，重置 PID 0 或某个进程都不能清除主机规则的冷却
+	e.ClearAlert(hostAlertID)
+	e.ClearAlert(pidA)
+	e.CheckRules()
+	got := received(ch)
+	if len(got) != 1 || got[0].RuleName != "egress" {
+		t.Errorf("after ClearAlert sent %v, want only the process rule egress", ruleNames(got))
+	}
+}
+
+func TestViolationSetsAlertContext(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 60
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 2
+      severity: "critical"
+      alerters: ["telegram"]
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	feed(t, m,
+		xmit(pidA, "curl", 3*mb, "198.51.100.1", 443),
+		xmit(pidA, "curl", 1*mb, "198.51.100.2", 443),
+		xmit(pidB, "sshd", 1*mb, "198.51.100.1", 22),
+	)
+	e.CheckRules()
+
+	got := received(ch)
+	if len(got) != 1 {
+		t.Fatalf("sent %d alerts, want 1 for the process over the threshold", len(got))
+	}
+	a := got[0]
+	if a.RuleName != "egress" || a.Reason != alerter.ReasonCumulativeThreshold || a.Kind != alerter.KindProcess {
+		t.Errorf("rule = %q, reason = %q, kind = %q; want egress, %q, %q", a.RuleName, a.Reason, a.Kind, alerter.ReasonCumulativeThreshold, alerter.KindProcess)
+	}
+	if a.ThresholdBytes != 2*mb {
+		t.Errorf("ThresholdBytes = %d, want %d", a.ThresholdBytes, 2*mb)
+	}
+	if a.Direction != config.DirectionTX {
+		t.Errorf("Direction = %q, want %q", a.Direction, config.DirectionTX)
+	}
+	if a.Severity != config.SeverityCritical || len(a.Alerters) != 1 || a.Alerters[0] != "telegram" {
+		t.Errorf("severity = %q, alerters = %v; want critical to telegram", a.Severity, a.Alerters)
+	}
+	if a.ProcessStats.PID != pidA || a.ProcessStats.Comm != "curl" || a.ProcessStats.TotalBytes != 4*mb {
+		t.Errorf("ProcessStats = PID %d %q %d bytes, want PID %d curl %d bytes", a.ProcessStats.PID, a.ProcessStats.Comm, a.ProcessStats.TotalBytes, pidA, 4*mb)
+	}
+	if len(a.Destinations) != 2 || a.Destinations[0].Addr != netip.MustParseAddr("198.51.100.1") {
+		t.Errorf("Destinations = %+v, want both endpoints with the busiest first", a.Destinations)
+	}
+	if a.Detail == "" || a.Timestamp.IsZero() {
+		t.Errorf("Detail = %q, Timestamp = %v; want both set", a.Detail, a.Timestamp)
+	}
+}
+
+func TestRateRuleUsesSmoothedRate(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  definitions:
+    - name: "fast"
+      type: "rate"
+      threshold_kb_per_second: 5
+`)
+	e, _, ch := newTestEngine(t, cfg)
+	r := e.compiled[0]
+
+	// 单个样本突增到 20 KB/s，但 EWMA 仍低于阈值
+	spike := state.ProcessStats{PID: pidA, Comm: "curl", RateBps: 20 * 1024, EWMARateBps: 4 * 1024, RateSamples: 4}
+	e.checkRateRule(r, []state.ProcessStats{spike})
+	if got := received(ch); len(got) != 0 {
+		t.Fatalf("spike with a low EWMA sent %v", ruleNames(got))
+	}
+
+	sustained := state.ProcessStats{PID: pidA, Comm: "curl", RateBps: 8 * 1024, EWMARateBps: 6 * 1024, RateSamples: 5}
+	e.checkRateRule(r, []state.ProcessStats{sustained})
+	got := received(ch)
+	if len(got) != 1 || got[0].Reason != alerter.ReasonRate {
+		t.Fatalf("sustained rate sent %v, want one rate alert", ruleNames(got))
+	}
+	if got[0].ThresholdRateBps != 5*1024 {
+		t.Errorf("ThresholdRateBps = %v, want %d", got[0].ThresholdRateBps, 5*1024)
+	}
+}
+
+func TestFanOutRule(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  definitions:
+    - name: "scan"
+      type: "fan_out"
+      max_connections: 20
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	var events []collector.TrafficEvent
+	for i := 0; i < 25; i++ {
+		events = append(events, xmit(pidA, "scanner", 100, fmt.Sprintf("192.0.2.%d", i+1), 22))
+	}
+	for i := 0; i < 20; i++ {
+		events = append(events, xmit(pidB, "nginx", 100, fmt.Sprintf("198.51.100.%d", i+1), 443))
+	}
+	feed(t, m, events...)
+
+	if s, ok := m.GetProcessStats(pidA); !ok || s.ConnectionCount != 25 {
+		t.Fatalf("scanner ConnectionCount = %d, want 25", s.ConnectionCount)
+	}
+	e.CheckRules()
+	got := received(ch)
+	if len(got) != 1 || got[0].ProcessStats.PID != pidA {
+		t.Fatalf("sent %v, want one alert for the scanner only (20 endpoints is not above the limit)", ruleNames(got))
+	}
+	if got[0].Reason != alerter.ReasonFanOut || got[0].ThresholdConnections != 20 {
+		t.Errorf("reason = %q, ThresholdConnections = %d; want %q, 20", got[0].Reason, got[0].ThresholdConnections, alerter.ReasonFanOut)
+	}
+}
+
+func TestMuteWindowSuppressesAlerts(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 60
+  mute_windows:
+    - days: ["mon"]
+      start: "01:00"
+      end: "03:30"
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 1
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	feed(t, m,
+		xmit(pidA, "rsync", 2*mb, "198.51.100.1", 873),
+		xmit(pidB, "tar", 2*mb, "198.51.100.2", 22),
+	)
+
+	// 2024-01-01 是星期一，静默期间规则照常检查但只计数
+	now := time.Date(2024, 1, 1, 2, 0, 0, 0, time.Local)
+	e.now = func() time.Time { return now }
+	e.CheckRules()
+	if got := received(ch); len(got) != 0 {
+		t.Fatalf("sent %v inside the mute window", ruleNames(got))
+	}
+	if !e.muted || e.suppressed != 2 {
+		t.Errorf("muted = %v, suppressed = %d; want true, 2", e.muted, e.suppressed)
+	}
+
+	// 静默期间被抑制的警报没有进入冷却期，静默结束后立即发送
+	now = time.Date(2024, 1, 1, 3, 30, 0, 0, time.Local)
+	e.CheckRules()
+	if got := received(ch); len(got) != 2 {
+		t.Fatalf("sent %v after the mute window, want both processes", ruleNames(got))
+	}
+	if e.muted || e.suppressed != 0 {
+		t.Errorf("muted = %v, suppressed = %d; want false, 0", e.muted, e.suppressed)
+	}
+
+	// 同一时间的星期二不在静默时间段内
+	if cfg.Rules.IsMuted(time.Date(2024, 1, 2, 2, 0, 0, 0, time.Local)) {
+		t.Error("mute window for mon applies on tue")
+	}
+}
+
+func TestRuleStatusesCountFirings(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 60
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 1
+    - name: "quiet"
+      type: "traffic"
+      threshold_mb: 100
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	feed(t, m, xmit(pidA, "curl", 2*mb, "198.51.100.1", 443))
+
+	statuses := e.RuleStatuses()
+	if len(statuses) != 2 || statuses[0].Name != "egress" || statuses[1].Name != "quiet" {
+		t.Fatalf("RuleStatuses = %+v, want egress and quiet in configuration order", statuses)
+	}
+	if statuses[0].FiredCount != 0 || !statuses[0].LastFired.IsZero() {
+		t.Errorf("before any check: fired %d at %v", statuses[0].FiredCount, statuses[0].LastFired)
+	}
+
+	e.CheckRules()
+	first := e.RuleStatuses()[0]
+	if first.FiredCount != 1 || first.LastFired.IsZero() {
+		t.Fatalf("after the first alert: fired %d at %v, want 1", first.FiredCount, first.LastFired)
+	}
+
+	// 冷却期内不会再次触发，计数不变
+	e.CheckRules()
+	if got := e.RuleStatuses()[0]; got.FiredCount != 1 || !got.LastFired.Equal(first.LastFired) {
+		t.Errorf("during cooldown: fired %d at %v, want unchanged", got.FiredCount, got.LastFired)
+	}
+
+	time.Sleep(time.Millisecond)
+	e.ClearAlert(pidA)
+	e.CheckRules()
+	second := e.RuleStatuses()
+	if second[0].FiredCount != 2 || !second[0].LastFired.After(first.LastFired) {
+		t.Errorf("after the second alert: fired %d at %v, want 2 after %v", second[0].FiredCount, second[0].LastFired, first.LastFired)
+	}
+	if second[1].FiredCount != 0 {
+		t.Errorf("quiet fired %d times", second[1].FiredCount)
+	}
+	if got := received(ch); len(got) != 2 {
+		t.Errorf("sent %d alerts, want 2", len(got))
+	}
+}
+
+func TestWarnThresholdThenCritical(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 60
+  definitions:
+    - name: "quota"
+      type: "traffic"
+      threshold_mb: 10
+      warn_threshold_mb: 5
+      severity: "critical"
+`)
+	e, m, ch := newTestEngine(t, cfg)
+
+	feed(t, m, xmit(pidA, "rsync", 6*mb, "198.51.100.1", 873))
+	e.CheckRules()
+	got := received(ch)
+	if len(got) != 1 {
+		t.Fatalf("crossing the warn level sent %v, want one warning", ruleNames(got))
+	}
+	if got[0].Severity != config.SeverityWarning || got[0].ThresholdBytes != 5*mb {
+		t.Errorf("warning: severity %q, threshold %d; want warning at %d", got[0].Severity, got[0].ThresholdBytes, 5*mb)
+	}
+
+	// 预警和正式警报的冷却记录相互独立
+	feed(t, m, xmit(pidA, "rsync", 5*mb, "198.51.100.1", 873))
+	e.CheckRules()
+	got = received(ch)
+	if len(got) != 1 {
+		t.Fatalf("crossing the main threshold sent %v, want one critical alert", ruleNames(got))
+	}
+	if got[0].Severity != config.SeverityCritical || got[0].ThresholdBytes != 10*mb || got[0].RuleName != "quota" {
+		t.Errorf("critical: rule %q, severity %q, threshold %d; want quota, critical at %d", got[0].RuleName, got[0].Severity, got[0].ThresholdBytes, 10*mb)
+	}
+
+	e.CheckRules()
+	if got := received(ch); len(got) != 0 {
+		t.Errorf("third check sent %v, both levels should be in cooldown", ruleNames(got))
+	}
+}
+
+func TestCooldownAfterBackwardClockJump(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 10
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 1
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	feed(t, m, xmit(pidA, "curl", 2*mb, "198.51.100.1", 443))
+	now := time.Unix(1700000000, 0)
+	e.now = func() time.Time { return now }
+
+	e.CheckRules()
+	if got := received(ch); len(got) != 1 {
+		t.Fatalf("first check sent %v, want one alert", ruleNames(got))
+	}
+
+	// 时钟倒退一小时：仍在冷却期内，冷却期从倒退后的时间重新计算，而不是等时钟追上
+	now = now.Add(-time.Hour)
+	e.CheckRules()
+	if got := received(ch); len(got) != 0 {
+		t.Fatalf("check after the jump sent %v, want none", ruleNames(got))
+	}
+	now = now.Add(5 * time.Minute)
+	e.CheckRules()
+	if got := received(ch); len(got) != 0 {
+		t.Fatalf("check 5m after the jump sent %v, want none", ruleNames(got))
+	}
+	now = now.Add(6 * time.Minute)
+	e.CheckRules()
+	if got := received(ch); len(got) != 1 {
+		t.Errorf("check 11m after the jump sent %v, want the alert again", ruleNames(got))
+	}
+}
+
+func TestSendOnClosedChannel(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 1
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	if !e.send(alerter.Alert{RuleName: "open"}) {
+		t.Fatal("send on an open channel failed")
+	}
+	<-ch
+
+	close(ch)
+	if e.send(alerter.Alert{RuleName: "closed"}) {
+		t.Error("send on a closed channel reported success")
+	}
+
+	// 完整的检查路径也不能因为关闭的 channel 崩溃，丢弃的警报不计入触发次数
+	feed(t, m, xmit(pidA, "curl", 2*mb, "198.51.100.1", 443))
+	e.CheckRules()
+	if got := e.RuleStatuses()[0].FiredCount; got != 0 {
+		t.Errorf("FiredCount = %d after a dropped alert, want 0", got)
+	}
+}
+
+func TestGrowthRuleFiresOnlyOnSpike(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 0
+  definitions:
+    - name: "growth"
+      type: "growth"
+      increase_percent: 300
+      warmup_samples: 5
+`)
+	e, _, ch := newTestEngine(t, cfg)
+	r := e.compiled[0]
+
+	// 在 10 KB/s 附近波动，第 12 个样本突增到 60 KB/s 后恢复；基线是此前样本的 EWMA
+	series := []float64{10, 11, 9, 10, 12, 10, 9, 11, 10, 10, 12, 60, 10, 11, 9}
+	const spike = 11
+	var baseline float64
+	for i, kbps := range series {
+		rate := kbps * 1024
+		s := state.ProcessStats{PID: pidA, Comm: "backup", RateBps: rate, BaselineRateBps: baseline, RateSamples: i + 1}
+		e.checkGrowthRule(r, []state.ProcessStats{s})
+
+		got := received(ch)
+		switch {
+		case i == spike && (len(got) != 1 || got[0].Reason != alerter.ReasonGrowth):
+			t.Errorf("sample %d (spike): sent %v, want one growth alert", i, ruleNames(got))
+		case i != spike && len(got) != 0:
+			t.Errorf("sample %d (%v KB/s over a %.1f KB/s baseline): sent %v, want none", i, kbps, baseline/1024, ruleNames(got))
+		}
+		if i == 0 {
+			baseline = rate
+		} else {
+			baseline = 0.3*rate + 0.7*baseline
+		}
+	}
+}
+
+func TestSubnetRuleCountsOnlyInRangeBytes(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  traffic_threshold_mb: 100
+  definitions:
+    - name: "private"
+      type: "cidr"
+      cidrs: ["10.0.0.0/8", "fd00::/8"]
+      threshold_mb: 3
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	// 网段外的 5MB 不计入，网段内的流量在阈值以下
+	feed(t, m,
+		xmit(pidA, "curl", 1*mb, "10.1.2.3", 443),
+		xmit(pidB, "rsync", 1*mb, "fd00::10", 873),
+		xmit(pidC, "backup", 5*mb, "198.51.100.1", 443),
+		xmit(pidC, "backup", 5*mb, "2001:db8::1", 443),
+	)
+	e.CheckRules()
+	if got := received(ch); len(got) != 0 {
+		t.Fatalf("2MB inside the subnets sent %v, want none", ruleNames(got))
+	}
+
+	feed(t, m, xmit(pidC, "backup", 2*mb, "10.200.0.1", 443))
+	e.CheckRules()
+	got := received(ch)
+	if len(got) != 1 || got[0].Kind != alerter.KindSubnet || got[0].SubnetStats == nil {
+		t.Fatalf("4MB inside the subnets sent %v, want one subnet alert", ruleNames(got))
+	}
+	if s := got[0].SubnetStats; s.TotalBytes != 4*mb || s.ProcessCount != 3 {
+		t.Errorf("subnet stats = %+v, want 4MB from 3 processes", s)
+	}
+}
+
+func TestGracePeriodSkipsYoungProcesses(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 10
+  grace_period_seconds: 60
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 1
+`)
+	e, _, ch := newTestEngine(t, cfg)
+	r := e.compiled[0]
+	now := time.Unix(1700000000, 0)
+	e.now = func() time.Time { return now }
+
+	young := state.ProcessStats{PID: pidA, Comm: "npm", TotalBytes: 2 * mb, ExternalBytes: 2 * mb, FirstSeen: now.Add(-10 * time.Second)}
+	old := state.ProcessStats{PID: pidB, Comm: "curl", TotalBytes: 2 * mb, ExternalBytes: 2 * mb, FirstSeen: now.Add(-5 * time.Minute)}
+	e.checkProcessRule(r, []state.ProcessStats{young, old})
+	got := received(ch)
+	if len(got) != 1 || got[0].ProcessStats.PID != pidB {
+		t.Fatalf("sent %v, want one alert for the process older than the grace period", ruleNames(got))
+	}
+
+	// 一分钟后新进程也已度过宽限期
+	now = now.Add(time.Minute)
+	e.checkProcessRule(r, []state.ProcessStats{young, old})
+	got = received(ch)
+	if len(got) != 1 || got[0].ProcessStats.PID != pidA {
+		t.Fatalf("sent %v after the grace period, want one alert for the formerly young process", ruleNames(got))
+	}
+}
+
+func TestRateAlertCarriesComparedRate(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  definitions:
+    - name: "fast"
+      type: "rate"
+      threshold_kb_per_second: 5
+      smoothing_samples: 3
+`)
+	e, _, ch := newTestEngine(t, cfg)
+	r := e.compiled[0]
+
+	// 第 3 个样本后最近 3 个样本的移动平均为 8 KB/s，而状态管理器的 EWMA (4 KB/s) 低于阈值
+	for i, kbps := range []float64{1, 2, 21} {
+		s := state.ProcessStats{PID: pidA, Comm: "curl", RateBps: kbps * 1024, EWMARateBps: 4 * 1024, RateSamples: i + 1}
+		e.checkRateRule(r, []state.ProcessStats{s})
+	}
+	got := received(ch)
+	if len(got) != 1 {
+		t.Fatalf("sent %v, want one rate alert after the third sample", ruleNames(got))
+	}
+	a := got[0]
+	if a.ObservedRateBps != 8*1024 {
+		t.Errorf("ObservedRateBps = %v, want the 3-sample moving average %d", a.ObservedRateBps, 8*1024)
+	}
+	if msg := alerter.FormatMessage(a, config.LocaleEnglish); !strings.Contains(msg, "`8.00 KB/s`") {
+		t.Errorf("message does not show the compared rate:\n%s", msg)
+	}
+}
@@ -0,0 +1,68 @@
+// internal/engine/escalation.go
+package engine
+
+import (
+	"time"
+
+	"traffic-guardian/internal/alerter"
+)
+
+// streak 记录一条规则对一个对象的连续报警次数和最近一次报警的时间
+type streak struct {
+	count int
+	last  time.Time
+}
+
+// escalate 计算本次警报在连续报警中的序号并写入 alert.Repeat，规则配置了 escalate_after 时
+// 按连续次数提升严重级别并改用 escalate_alerters。返回的序号在警报发出后由 recordStreak 记录
+func (e *Engine) escalate(r *rule, id uint32, alert *alerter.Alert) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	repeat := 1
+	if s, ok := e.streaks[alertKey{rule: r.key(), id: id}]; ok && e.continuesStreak(r, s) {
+		repeat = s.count + 1
+	}
+	alert.Repeat = repeat
+
+	if r.EscalateAfter > 0 && repeat >= r.EscalateAfter {
+		alert.Severity = alert.Severity.Raise(repeat / r.EscalateAfter)
+		if len(r.EscalateAlerters) > 0 {
+			alert.Alerters = r.EscalateAlerters
+		}
+		e.log.Info("Escalating repeated alert", "rule", r.Name, "id", id, "repeat", repeat, "severity", alert.Severity)
+	}
+	return repeat
+}
+
+// continuesStreak 检查上一次报警之后对象是否一直超限: 冷却期结束后的下一次检查就会再次报警，
+// 因此间隔超过冷却时间加两个（最长的）检查间隔时，说明对象在期间恢复过正常，重新计数
+func (e *Engine) continuesStreak(r *rule, s *streak) bool {
+	interval := e.rules.GetCheckInterval()
+	if e.rules.AdaptiveInterval.Enabled {
+		_, interval = e.rules.AdaptiveInterval.GetBounds(interval)
+	}
+	return e.now().Sub(s.last) <= r.cooldown+2*interval
+}
+
+// recordStreak 记录已发出的警报在连续报警中的序号
+func (e *Engine) recordStreak(r *rule, id uint32, repeat int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.streaks[alertKey{rule: r.key(), id: id}] = &streak{count: repeat, last: e.now()}
+}
+
+// pruneStreaks 删除已经不可能延续的连续报警记录，避免退出的进程的记录一直保留
+func (e *Engine) pruneStreaks() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rules := make(map[string]*rule, len(e.compiled))
+	for _, r := range e.compiled {
+		rules[r.key()] = r
+	}
+	for key, s := range e.streaks {
+		if r, ok := rules[key.rule]; !ok || !e.continuesStreak(r, s) {
+			delete(e.streaks, key)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// internal/engine/escalation_test.go
+package engine
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestEscalationAfterRepeatedBreaches(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 10
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 1
+      severity: "info"
+      escalate_after: 3
+      escalate_alerters: ["exec"]
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	feed(t, m, xmit(pidA, "curl", 2*mb, "198.51.100.1", 443))
+	now := time.Unix(1700000000, 0)
+	e.now = func() time.Time { return now }
+
+	// 进程一直超限，每次冷却期结束后的检查都再次报警；每满 3 次提升一级
+	want := []config.Severity{
+		config.SeverityInfo, config.SeverityInfo, config.SeverityWarning,
+		config.SeverityWarning, config.SeverityWarning, config.SeverityCritical,
+		config.SeverityCritical,
+	}
+	for i, severity := range want {
+		e.CheckRules()
+		got := received(ch)
+		if len(got) != 1 {
+			t.Fatalf("check %d sent %v, want one alert", i+1, ruleNames(got))
+		}
+		a := got[0]
+		if a.Repeat != i+1 || a.Severity != severity {
+			t.Errorf("alert %d: repeat = %d, severity = %s; want %d, %s", i+1, a.Repeat, a.Severity, i+1, severity)
+		}
+		if escalated := slices.Equal(a.Alerters, []string{"exec"}); escalated != (i+1 >= 3) {
+			t.Errorf("alert %d: alerters = %v", i+1, a.Alerters)
+		}
+		now = now.Add(10*time.Minute + 30*time.Second)
+	}
+
+	// 长时间没有报警（对象恢复过正常）后重新计数
+	now = now.Add(time.Hour)
+	e.CheckRules()
+	got := received(ch)
+	if len(got) != 1 || got[0].Repeat != 1 || got[0].Severity != config.SeverityInfo {
+		t.Fatalf("alert after a gap = %+v, want repeat 1 at info", got)
+	}
+}
@@ -0,0 +1,60 @@
+// internal/engine/link.go
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sysClassNet 是内核导出网络接口属性的目录
+const sysClassNet = "/sys/class/net"
+
+// readLinkSpeed 从 /sys/class/net/<iface>/speed 读取接口的链路速率（单位: Mbit/s）。
+// 接口未连接或驱动不报告速率时内核返回 -1 或读取失败，此时返回错误
+func readLinkSpeed(iface string) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(sysClassNet, iface, "speed"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read link speed of %s: %w", iface, err)
+	}
+	speed, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid link speed of %s: %w", iface, err)
+	}
+	if speed <= 0 {
+		return 0, fmt.Errorf("link speed of %s is unknown, the link may be down", iface)
+	}
+	return uint64(speed), nil
+}
+
+// linkBudget 将链路速率的百分比换算为时间窗口内的字节预算:
+// speedMbps × 10⁶ / 8 字节/秒 × 窗口秒数 × percent / 100
+func linkBudget(speedMbps uint64, window time.Duration, percent float64) uint64 {
+	bytesPerSecond := float64(speedMbps) * 1e6 / 8
+	return uint64(bytesPerSecond * window.Seconds() * percent / 100)
+}
+
+// thresholdFor 返回规则本次检查使用的字节阈值。配置了 threshold_percent 的规则根据接口当前的
+// 链路速率换算，速率无法读取时返回 false 并跳过本次检查；错误只在变化时记录，避免每个周期刷屏
+func (e *Engine) thresholdFor(r *rule) (uint64, bool) {
+	if r.ThresholdPercent <= 0 {
+		return r.threshold, true
+	}
+
+	speed, err := e.linkSpeed(r.Interface)
+	if err != nil {
+		if msg := err.Error(); msg != r.linkErr {
+			e.log.Warn("Cannot compute link-based threshold, skipping rule", "rule", r.Name, "interface", r.Interface, "error", err)
+			r.linkErr = msg
+		}
+		return 0, false
+	}
+	if r.linkErr != "" {
+		e.log.Info("Link speed available again", "rule", r.Name, "interface", r.Interface, "speed_mbps", speed)
+		r.linkErr = ""
+	}
+	return linkBudget(speed, e.windowFor(r), r.ThresholdPercent), true
+}
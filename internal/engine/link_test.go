@@ -0,0 +1,77 @@
+// internal/engine/link_test.go
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLinkBudget(t *testing.T) {
+	tests := []struct {
+		speedMbps uint64
+		window    time.Duration
+		percent   float64
+		want      uint64
+	}{
+		// 1 Gbit/s = 125 MB/s（十进制）
+		{1000, time.Second, 100, 125_000_000},
+		{1000, time.Hour, 10, 45_000_000_000},
+		{100, time.Minute, 50, 375_000_000},
+		{10000, 5 * time.Minute, 0.5, 1_875_000_000},
+		{1000, 0, 50, 0},
+	}
+	for _, tt := range tests {
+		if got := linkBudget(tt.speedMbps, tt.window, tt.percent); got != tt.want {
+			t.Errorf("linkBudget(%d Mbit/s, %s, %v%%) = %d, want %d", tt.speedMbps, tt.window, tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestThresholdForPercentRule(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  definitions:
+    - name: "uplink"
+      type: "traffic"
+      threshold_percent: 10
+      interface: "eth0"
+    - name: "uplink-short"
+      type: "traffic"
+      threshold_percent: 10
+      interface: "eth0"
+      window_minutes: 1
+    - name: "fixed"
+      type: "traffic"
+      threshold_mb: 5
+`)
+	e, _, _ := newTestEngine(t, cfg)
+	speeds := map[string]uint64{"eth0": 1000}
+	e.linkSpeed = func(iface string) (uint64, error) {
+		if s, ok := speeds[iface]; ok {
+			return s, nil
+		}
+		return 0, errors.New("link is down")
+	}
+
+	for i, want := range []uint64{45_000_000_000, 750_000_000, 5 * mb} {
+		got, ok := e.thresholdFor(e.compiled[i])
+		if !ok || got != want {
+			t.Errorf("%s: thresholdFor = %d, %v; want %d", e.compiled[i].Name, got, ok, want)
+		}
+	}
+
+	// 链路速率无法读取时跳过规则，而不是使用 0 作为阈值
+	delete(speeds, "eth0")
+	if got, ok := e.thresholdFor(e.compiled[0]); ok {
+		t.Errorf("thresholdFor with the link down = %d, want the rule skipped", got)
+	}
+
+	// 链路速率变化后按新的速率换算
+	speeds["eth0"] = 100
+	if got, _ := e.thresholdFor(e.compiled[0]); got != 4_500_000_000 {
+		t.Errorf("thresholdFor at 100 Mbit/s = %d, want 4500000000", got)
+	}
+}
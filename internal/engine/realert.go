@@ -0,0 +1,62 @@
+// internal/engine/realert.go
+package engine
+
+import "time"
+
+// realert 记录 realert_every_mb 规则对一个超限对象的报警进度
+type realert struct {
+	// alerted 是上一次报警时的流量（之后流量下降时跟随下降），fired 表示本轮超限中是否已经报过警
+	alerted uint64
+	fired   bool
+	// pending 是最近一次评估的流量，报警后成为 alerted；seen 是最近一次评估的时间
+	pending uint64
+	seen    time.Time
+}
+
+// belowIncrement 检查 realert_every_mb 规则的对象自上一次报警以来的流量增长是否还不到一个增量，
+// 代替按时间计算的冷却期；确认期内同样不报警。超限后的第一次评估总是报警。
+// 流量下降时（滑动窗口或计数被重置）从较低的值重新计算增量，避免在同一水平附近反复报警
+func (e *Engine) belowIncrement(r *rule, id uint32, used uint64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := alertKey{rule: r.key(), id: id}
+	st, ok := e.realerts[key]
+	if !ok {
+		st = &realert{}
+		e.realerts[key] = st
+	}
+	st.pending = used
+	st.seen = e.now()
+
+	if e.acknowledgedLocked(r, id) {
+		return true
+	}
+	if !st.fired {
+		return false
+	}
+	if used < st.alerted {
+		st.alerted = used
+	}
+	return used-st.alerted < r.realertEvery
+}
+
+// recordRealertLocked 将最近一次评估的流量记为报警时的流量。调用方必须持有 e.mu
+func (e *Engine) recordRealertLocked(r *rule, id uint32) {
+	if st, ok := e.realerts[alertKey{rule: r.key(), id: id}]; ok {
+		st.alerted = st.pending
+		st.fired = true
+	}
+}
+
+// pruneRealerts 删除本次检查中没有被评估（即已恢复到阈值以下或已退出）的对象的记录，
+// 它们再次超限时重新开始计算，第一次评估立即报警
+func (e *Engine) pruneRealerts(start time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for key, st := range e.realerts {
+		if st.seen.Before(start) {
+			delete(e.realerts, key)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// internal/engine/realert_test.go
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRealertEveryIncrement(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 1
+  definitions:
+    - name: "quota"
+      type: "traffic"
+      threshold_mb: 2
+      realert_every_mb: 1
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	now := time.Unix(1700000000, 0)
+	e.now = func() time.Time { return now }
+
+	// 每一步之后过去两个小时，远超冷却期；只有流量每增长 1MB 才再次报警
+	for i, step := range []struct {
+		add  uint64
+		want int
+	}{
+		{5 * mb / 2, 1},
+		{0, 0},
+		{mb / 2, 0},
+		{6 * mb / 10, 1},
+		{0, 0},
+		{mb, 1},
+	} {
+		if step.add > 0 {
+			feed(t, m, xmit(pidA, "rsync", step.add, "198.51.100.1", 873))
+		}
+		e.CheckRules()
+		if got := received(ch); len(got) != step.want {
+			t.Errorf("step %d (+%d bytes): sent %v, want %d alerts", i+1, step.add, ruleNames(got), step.want)
+		}
+		now = now.Add(2 * time.Hour)
+	}
+}
@@ -0,0 +1,58 @@
+// internal/engine/reload.go
+package engine
+
+import (
+	"maps"
+	"time"
+
+	"traffic-guardian/internal/config"
+)
+
+// UpdateRules 在运行时替换规则配置，例如在重新加载配置后调用。新规则在下一次检查开始时生效，
+// 名称和级别相同的规则保留触发统计和冷却记录，被删除的规则的冷却、平滑窗口等记录随之删除。
+// adaptive_interval 和 check_jitter_percent 从下一次调度检查时起生效；check_interval_seconds
+// 和 cidr 规则的目的网段只在启动时读取，修改后需要重启才能生效
+func (e *Engine) UpdateRules(rules config.Rules) {
+	e.pending.Store(&rules)
+}
+
+// applyPendingRules 应用 UpdateRules 提交的规则，只在检查循环中调用
+func (e *Engine) applyPendingRules() {
+	rules := e.pending.Swap(nil)
+	if rules == nil {
+		return
+	}
+	compiled := compileRules(e.log, *rules)
+	e.stateManager.SetBlocklist(blocklists(compiled))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	previous := make(map[string]*rule, len(e.compiled))
+	for _, r := range e.compiled {
+		previous[r.key()] = r
+	}
+	for _, r := range compiled {
+		if old, ok := previous[r.key()]; ok {
+			r.fired = old.fired
+			r.lastFired = old.lastFired
+		}
+	}
+	e.rules = *rules
+	e.compiled = compiled
+	e.pruneRemovedRulesLocked()
+	e.log.Info("Applied reloaded rules", "rule_count", len(compiled))
+}
+
+// pruneRemovedRulesLocked 删除已不在 e.compiled 中的规则对各个对象的记录，调用方必须持有 e.mu
+func (e *Engine) pruneRemovedRulesLocked() {
+	current := make(map[string]bool, len(e.compiled))
+	for _, r := range e.compiled {
+		current[r.key()] = true
+	}
+	removed := func(key alertKey) bool { return !current[key.rule] }
+	maps.DeleteFunc(e.recentlyAlerted, func(key alertKey, _ time.Time) bool { return removed(key) })
+	maps.DeleteFunc(e.rateWindows, func(key alertKey, _ *rateWindow) bool { return removed(key) })
+	maps.DeleteFunc(e.streaks, func(key alertKey, _ *streak) bool { return removed(key) })
+	maps.DeleteFunc(e.acknowledged, func(key alertKey, _ time.Time) bool { return removed(key) })
+	maps.DeleteFunc(e.realerts, func(key alertKey, _ *realert) bool { return removed(key) })
+}
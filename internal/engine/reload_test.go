@@ -0,0 +1,47 @@
+// internal/engine/reload_test.go
+package engine
+
+import "testing"
+
+func TestUpdateRulesPrunesRemovedRules(t *testing.T) {
+	cfg := loadConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 30
+  alert_cooldown_minutes: 60
+  definitions:
+    - name: "kept"
+      type: "traffic"
+      threshold_mb: 1
+    - name: "removed"
+      type: "traffic"
+      threshold_mb: 1
+`)
+	e, m, ch := newTestEngine(t, cfg)
+	feed(t, m, xmit(pidA, "curl", 2*mb, "198.51.100.1", 443))
+	e.CheckRules()
+	if got := received(ch); len(got) != 2 {
+		t.Fatalf("first check sent %v, want both rules", ruleNames(got))
+	}
+	e.rateWindows[alertKey{rule: "removed", id: pidA}] = &rateWindow{}
+
+	rules := cfg.Rules
+	rules.Definitions = rules.Definitions[:1]
+	e.UpdateRules(rules)
+	e.CheckRules()
+	if got := received(ch); len(got) != 0 {
+		t.Errorf("check after the reload sent %v, want none (kept is still in cooldown)", ruleNames(got))
+	}
+
+	if _, ok := e.recentlyAlerted[alertKey{rule: "kept", id: pidA}]; !ok {
+		t.Error("cooldown of the kept rule was dropped by the reload")
+	}
+	for key := range e.recentlyAlerted {
+		if key.rule == "removed" {
+			t.Errorf("cooldown %v of the removed rule is still recorded", key)
+		}
+	}
+	if len(e.rateWindows) != 0 {
+		t.Errorf("rate windows of the removed rule are still recorded: %v", e.rateWindows)
+	}
+}
@@ -0,0 +1,78 @@
+// internal/engine/smoothing.go
+package engine
+
+import "traffic-guardian/internal/state"
+
+// rateWindow 记录 rate 规则对单个进程的平滑窗口和连续超限次数，受 Engine.mu 保护
+type rateWindow struct {
+	// samples 是最近 smoothing_samples 个速率样本组成的环形缓冲区，next 是下一个写入位置
+	samples []float64
+	next    int
+	// seen 是最近一次计入窗口的样本序号（ProcessStats.RateSamples），同一个样本只计入一次
+	seen int
+	// breaches 是连续超过阈值的样本数，smoothed 是最近一次计算的平滑速率
+	breaches int
+	smoothed float64
+}
+
+// add 加入一个新的速率样本并返回窗口内的平均值
+func (w *rateWindow) add(rate float64, size int) float64 {
+	if len(w.samples) < size {
+		w.samples = append(w.samples, rate)
+	} else {
+		w.samples[w.next] = rate
+	}
+	w.next = (w.next + 1) % size
+
+	var sum float64
+	for _, v := range w.samples {
+		sum += v
+	}
+	return sum / float64(len(w.samples))
+}
+
+// observeRate 更新 rate 规则对进程的平滑速率和连续超限次数，返回平滑后的速率以及是否达到
+// consecutive_breaches 的要求。未配置 smoothing_samples 时使用状态管理器计算的 EWMA 速率。
+// 自适应检查间隔可能让一次采样被检查多次，只有新的样本才会改变连续超限次数
+func (e *Engine) observeRate(r *rule, s *state.ProcessStats) (float64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := alertKey{rule: r.key(), id: s.PID}
+	w, ok := e.rateWindows[key]
+	if !ok {
+		w = &rateWindow{}
+		e.rateWindows[key] = w
+	}
+	if s.RateSamples == w.seen {
+		return w.smoothed, w.breaches >= r.GetConsecutiveBreaches()
+	}
+	w.seen = s.RateSamples
+
+	w.smoothed = s.EWMARateBps
+	if n := r.SmoothingSamples; n > 1 {
+		w.smoothed = w.add(s.RateBps, n)
+	}
+	if w.smoothed > r.rateThreshold {
+		w.breaches++
+	} else {
+		w.breaches = 0
+	}
+	return w.smoothed, w.breaches >= r.GetConsecutiveBreaches()
+}
+
+// pruneRateWindows 删除 rate 规则中已不再被跟踪的进程的窗口
+func (e *Engine) pruneRateWindows(r *rule, stats []state.ProcessStats) {
+	alive := make(map[uint32]bool, len(stats))
+	for _, s := range stats {
+		alive[s.PID] = true
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for key := range e.rateWindows {
+		if key.rule == r.key() && !alive[key.id] {
+			delete(e.rateWindows, key)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// internal/logging/output.go
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+
+	"traffic-guardian/internal/config"
+)
+
+// nopCloser 包装不需要关闭的标准输出流
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// syslogTag 是写入 syslog 的消息标识
+const syslogTag = "traffic-guardian"
+
+// Open 根据 log_output 返回日志的输出目标: stdout（默认）、stderr、syslog 或按大小轮转的文件路径
+func Open(cfg *config.Config) (io.WriteCloser, error) {
+	return OpenOutput(cfg.LogOutput, cfg.GetLogMaxSizeBytes(), cfg.GetLogMaxBackups())
+}
+
+// OpenOutput 返回 target 指定的输出目标: stdout（空字符串）、stderr、syslog 或按 maxSize 和 maxBackups 轮转的文件路径。
+// syslog 通过本机的 syslog 套接字发送，每条日志记录是一条 daemon.info 消息
+func OpenOutput(target string, maxSize int64, maxBackups int) (io.WriteCloser, error) {
+	switch target {
+	case "", "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "stderr":
+		return nopCloser{os.Stderr}, nil
+	case "syslog":
+		w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, syslogTag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		return w, nil
+	default:
+		return NewRotatingFile(target, maxSize, maxBackups)
+	}
+}
+
+// Reopen 重新打开 OpenOutput 返回的日志文件，用于 logrotate 移走文件之后继续写入新文件；
+// 其他输出目标什么都不做
+func Reopen(w io.Writer) error {
+	if f, ok := w.(*RotatingFile); ok {
+		return f.Reopen()
+	}
+	return nil
+}
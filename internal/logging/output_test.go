@@ -0,0 +1,56 @@
+// internal/logging/output_test.go
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestOpenFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guardian.log")
+	cfg := config.DefaultConfig()
+	cfg.LogOutput = path
+	w, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer w.Close()
+
+	log := slog.New(slog.NewTextHandler(w, nil))
+	log.Info("Starting traffic guardian", "version", "test")
+	log.Warn("Rule violated", "pid", 4242)
+
+	readLines := func(path string) []string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	lines := readLines(path)
+	if len(lines) != 2 || !strings.Contains(lines[0], `msg="Starting traffic guardian"`) || !strings.Contains(lines[1], "pid=4242") {
+		t.Fatalf("log file contains %q, want the two records", lines)
+	}
+
+	// logrotate 移走文件后 (SIGHUP) 重新打开，新的记录写入新文件
+	rotated := path + ".1"
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reopen(w); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	log.Info("Reopened log output")
+	if lines := readLines(path); len(lines) != 1 || !strings.Contains(lines[0], "Reopened log output") {
+		t.Errorf("new log file contains %q, want only the record written after Reopen", lines)
+	}
+	if lines := readLines(rotated); len(lines) != 2 {
+		t.Errorf("rotated file has %d lines, want the 2 written before the rotation", len(lines))
+	}
+}
@@ -0,0 +1,92 @@
+// internal/match/cidr.go
+package match
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+// PrefixSet 是一组 CIDR，地址属于其中任意一个即视为匹配
+type PrefixSet []netip.Prefix
+
+// ParsePrefixes 解析一组 CIDR，单个地址（不带掩码）视为 /32 或 /128
+func ParsePrefixes(cidrs []string) (PrefixSet, error) {
+	set := make(PrefixSet, 0, len(cidrs))
+	for _, c := range cidrs {
+		prefix, err := netip.ParsePrefix(c)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(c)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", c, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		set = append(set, prefix.Masked())
+	}
+	return set, nil
+}
+
+// Contains 检查地址是否属于集合中的任意一个 CIDR
+func (s PrefixSet) Contains(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range s {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrefixTable 是按前缀长度索引的 CIDR 集合，查找的开销与不同前缀长度的数量有关而与 CIDR 的数量无关，
+// 用于威胁情报等包含大量地址的列表
+type PrefixTable struct {
+	// bits 是表中出现过的前缀长度，从长到短排序，使查找返回最具体的 CIDR
+	bits     []int
+	prefixes map[netip.Prefix]struct{}
+}
+
+// NewPrefixTable 根据一组 CIDR 创建查找表
+func NewPrefixTable(set PrefixSet) *PrefixTable {
+	t := &PrefixTable{prefixes: make(map[netip.Prefix]struct{}, len(set))}
+	seen := make(map[int]bool)
+	for _, p := range set {
+		t.prefixes[p] = struct{}{}
+		if !seen[p.Bits()] {
+			seen[p.Bits()] = true
+			t.bits = append(t.bits, p.Bits())
+		}
+	}
+	slices.Sort(t.bits)
+	slices.Reverse(t.bits)
+	return t
+}
+
+// Lookup 返回包含地址的最具体的 CIDR
+func (t *PrefixTable) Lookup(addr netip.Addr) (netip.Prefix, bool) {
+	if !addr.IsValid() {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+	for _, bits := range t.bits {
+		if bits > addr.BitLen() {
+			continue
+		}
+		p, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if _, ok := t.prefixes[p]; ok {
+			return p, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// Len 返回表中 CIDR 的数量
+func (t *PrefixTable) Len() int {
+	return len(t.prefixes)
+}
@@ -0,0 +1,158 @@
+// internal/match/match.go
+package match
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 模式的前缀，用于指定匹配方式
+const (
+	// PrefixRegex 表示正则表达式匹配，例如 "re:^nginx-\d+$"
+	PrefixRegex = "re:"
+	// PrefixContains 表示子串匹配，例如 "contains:java"
+	PrefixContains = "contains:"
+)
+
+// kind 表示模式的匹配方式
+type kind int
+
+const (
+	kindExact kind = iota
+	kindContains
+	kindGlob
+	kindRegex
+)
+
+// Matcher 是一个编译好的名称匹配模式
+//
+// 支持的语法:
+//   - "re:<expr>"      正则表达式
+//   - "contains:<sub>" 子串
+//   - 含有 * ? [ 的模式按 glob 匹配，* 可以匹配包括 '/' 在内的任意字符
+//   - 其他情况按精确匹配
+type Matcher struct {
+	pattern string
+	kind    kind
+	value   string
+	re      *regexp.Regexp
+}
+
+// Compile 编译一个匹配模式
+func Compile(pattern string) (*Matcher, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	m := &Matcher{pattern: pattern}
+	switch {
+	case strings.HasPrefix(pattern, PrefixRegex):
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, PrefixRegex))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern %q: %w", pattern, err)
+		}
+		m.kind = kindRegex
+		m.re = re
+	case strings.HasPrefix(pattern, PrefixContains):
+		m.kind = kindContains
+		m.value = strings.TrimPrefix(pattern, PrefixContains)
+	case strings.ContainsAny(pattern, "*?["):
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		m.kind = kindGlob
+		m.re = re
+	default:
+		m.kind = kindExact
+		m.value = pattern
+	}
+	return m, nil
+}
+
+// MustCompile 与 Compile 相同，但在模式无效时 panic
+func MustCompile(pattern string) *Matcher {
+	m, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Match 检查名称是否匹配此模式
+func (m *Matcher) Match(name string) bool {
+	switch m.kind {
+	case kindExact:
+		return name == m.value
+	case kindContains:
+		return strings.Contains(name, m.value)
+	default:
+		return m.re.MatchString(name)
+	}
+}
+
+// String 返回原始模式
+func (m *Matcher) String() string {
+	return m.pattern
+}
+
+// Set 是一组模式，任意一个匹配即视为匹配
+type Set []*Matcher
+
+// CompileAll 编译一组模式，遇到第一个无效模式时返回错误
+func CompileAll(patterns []string) (Set, error) {
+	set := make(Set, 0, len(patterns))
+	for _, p := range patterns {
+		m, err := Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, m)
+	}
+	return set, nil
+}
+
+// MatchAny 检查名称是否匹配集合中的任意一个模式，空集合永远不匹配
+func (s Set) MatchAny(name string) bool {
+	for _, m := range s {
+		if m.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// globToRegexp 将 glob 模式转换为锚定的正则表达式
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid glob pattern %q: unterminated character class", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", glob, err)
+	}
+	return re, nil
+}
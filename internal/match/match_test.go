@@ -0,0 +1,98 @@
+// internal/match/match_test.go
+package match
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		// 精确匹配
+		{"nginx", "nginx", true},
+		{"nginx", "nginx-worker", false},
+		{"nginx", "NGINX", false},
+		// 子串
+		{"contains:java", "openjdk-java17", true},
+		{"contains:java", "javac", true},
+		{"contains:java", "python3", false},
+		{"contains:", "anything", true},
+		// glob
+		{"nginx*", "nginx", true},
+		{"nginx*", "nginx-worker", true},
+		{"nginx*", "my-nginx", false},
+		{"*/bin/*", "/usr/bin/curl", true},
+		{"python?", "python3", true},
+		{"python?", "python", false},
+		{"python?", "python31", false},
+		{"kworker/[0-9]*", "kworker/3:1", true},
+		{"kworker/[0-9]*", "kworker/u8:2", false},
+		{"[!a]pt", "apt", false},
+		{"[!a]pt", "opt", true},
+		{"a.b*", "a.bc", true},
+		{"a.b*", "axbc", false},
+		// 正则表达式，不自动锚定
+		{`re:^nginx-\d+$`, "nginx-12", true},
+		{`re:^nginx-\d+$`, "nginx-x", false},
+		{"re:worker", "nginx-worker-1", true},
+		{"re:(?i)^curl$", "CURL", true},
+	}
+	for _, tt := range tests {
+		m, err := Compile(tt.pattern)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", tt.pattern, err)
+		}
+		if got := m.Match(tt.name); got != tt.want {
+			t.Errorf("%q.Match(%q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+		if m.String() != tt.pattern {
+			t.Errorf("String() = %q, want %q", m.String(), tt.pattern)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"", "empty pattern"},
+		{"re:(", "invalid regex pattern"},
+		{"re:[a-", "invalid regex pattern"},
+		{"nginx[0-9", "unterminated character class"},
+		{"[z-a]*", "invalid glob pattern"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.pattern)
+		if err == nil {
+			t.Errorf("Compile(%q) succeeded, want error", tt.pattern)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) error %q does not mention %q", tt.pattern, err, tt.want)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	set, err := CompileAll([]string{"nginx", "contains:java", "re:^py"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"nginx": true, "openjdk-java": true, "python3": true, "curl": false} {
+		if got := set.MatchAny(name); got != want {
+			t.Errorf("MatchAny(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	if Set(nil).MatchAny("nginx") {
+		t.Error("empty set matched")
+	}
+	if _, err := CompileAll([]string{"nginx", "re:("}); err == nil {
+		t.Error("CompileAll with an invalid pattern succeeded")
+	}
+}
@@ -0,0 +1,71 @@
+// internal/metrics/comm.go
+package metrics
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// otherComm 是超出数量上限的进程名汇总到的标签值
+const otherComm = "other"
+
+// commBytesCollector 在每次抓取时按进程名导出流量，只导出流量最大的 limit 个进程名，
+// 其余的汇总到 comm="other"，避免标签基数无限增长
+type commBytesCollector struct {
+	desc      *prometheus.Desc
+	direction string
+	limit     int
+	totals    func() map[string]uint64
+}
+
+// RegisterCommBytes 注册按进程名汇总的流量，totals 在每次抓取时调用
+func (m *Metrics) RegisterCommBytes(direction string, limit int, totals func() map[string]uint64) {
+	m.registry.MustRegister(&commBytesCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "comm_bytes"),
+			"Bytes sent within the time window, summed across PIDs per command name. Command names beyond the export limit are summed into comm=\"other\".",
+			[]string{"comm", "direction"}, nil,
+		),
+		direction: direction,
+		limit:     limit,
+		totals:    totals,
+	})
+}
+
+// Describe 实现了 prometheus.Collector 接口
+func (c *commBytesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect 实现了 prometheus.Collector 接口
+func (c *commBytesCollector) Collect(ch chan<- prometheus.Metric) {
+	for comm, bytes := range topComms(c.totals(), c.limit) {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(bytes), comm, c.direction)
+	}
+}
+
+// topComms 保留流量最大的 limit 个进程名，其余的汇总到 other。
+// 进程名本身为 "other" 时也汇总到 other，以免与汇总桶混淆
+func topComms(totals map[string]uint64, limit int) map[string]uint64 {
+	comms := make([]string, 0, len(totals))
+	for comm := range totals {
+		comms = append(comms, comm)
+	}
+	sort.Slice(comms, func(i, j int) bool {
+		if totals[comms[i]] != totals[comms[j]] {
+			return totals[comms[i]] > totals[comms[j]]
+		}
+		return comms[i] < comms[j]
+	})
+
+	top := make(map[string]uint64, min(len(comms), limit+1))
+	for i, comm := range comms {
+		if i < limit && comm != otherComm {
+			top[comm] = totals[comm]
+			continue
+		}
+		top[otherComm] += totals[comm]
+	}
+	return top
+}
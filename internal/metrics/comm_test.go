@@ -0,0 +1,52 @@
+// internal/metrics/comm_test.go
+package metrics
+
+import (
+	"maps"
+	"testing"
+)
+
+// gatherCommBytes 抓取一次 comm_bytes 指标，返回每个进程名的值，并检查 direction 标签
+func gatherCommBytes(t *testing.T, m *Metrics, direction string) map[string]float64 {
+	t.Helper()
+	families, err := m.Registry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != namespace+"_comm_bytes" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range metric.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["direction"] != direction {
+				t.Errorf("direction = %q, want %q", labels["direction"], direction)
+			}
+			values[labels["comm"]] = metric.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func TestCommBytesOtherBucket(t *testing.T) {
+	totals := map[string]uint64{"rsync": 5000, "curl": 3000, "nginx": 3000, "sshd": 200, "other": 50, "cron": 10}
+	m := New()
+	m.RegisterCommBytes("tx", 3, func() map[string]uint64 { return totals })
+
+	// 流量相同时按名称排序，保证每次抓取导出同一组进程名；名为 other 的进程并入汇总桶
+	want := map[string]float64{"rsync": 5000, "curl": 3000, "nginx": 3000, "other": 260}
+	if got := gatherCommBytes(t, m, "tx"); !maps.Equal(got, want) {
+		t.Errorf("comm_bytes = %v, want %v", got, want)
+	}
+
+	// 每次抓取时重新读取，进程名数量不超过上限时没有 other
+	totals = map[string]uint64{"rsync": 7000, "curl": 1}
+	want = map[string]float64{"rsync": 7000, "curl": 1}
+	if got := gatherCommBytes(t, m, "tx"); !maps.Equal(got, want) {
+		t.Errorf("second scrape comm_bytes = %v, want %v", got, want)
+	}
+}
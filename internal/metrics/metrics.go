@@ -0,0 +1,94 @@
+// internal/metrics/metrics.go
+package metrics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// namespace 是所有指标名称的前缀
+const namespace = "traffic_guardian"
+
+// Metrics 持有 traffic-guardian 的所有 Prometheus 指标
+type Metrics struct {
+	registry *prometheus.Registry
+
+	alerts *prometheus.CounterVec
+}
+
+// New 创建一个新的 Metrics 实例，使用独立的 Registry 以免与嵌入方的指标冲突
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		alerts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "alerts_total",
+			Help:      "Number of alerts emitted, by rule, reason and severity.",
+		}, []string{"rule", "reason", "severity"}),
+	}
+	m.registry.MustRegister(m.alerts)
+	m.registerSelf()
+	return m
+}
+
+// ObserveAlert 记录一条已发出的警报
+func (m *Metrics) ObserveAlert(rule, reason, severity string) {
+	m.alerts.WithLabelValues(rule, reason, severity).Inc()
+}
+
+// Registry 返回底层的 Prometheus Registry
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler 返回以 Prometheus 文本格式输出所有指标的 http.Handler
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Push 将所有指标推送到 Prometheus Pushgateway，替换 job 分组下的已有指标。
+// 用于 --once 等无法被长期抓取的短时运行
+func (m *Metrics) Push(ctx context.Context, url, job string) error {
+	return push.New(url, job).Gatherer(m.registry).PushContext(ctx)
+}
+
+// RegisterChannel 注册一个 channel 的当前长度和容量，两者都在抓取时采样
+func (m *Metrics) RegisterChannel(name string, length, capacity func() int) {
+	labels := prometheus.Labels{"channel": name}
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "channel_length",
+			Help:        "Number of items currently buffered in an internal channel.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(length()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "channel_capacity",
+			Help:        "Buffer capacity of an internal channel.",
+			ConstLabels: labels,
+		}, func() float64 { return float64(capacity()) }),
+	)
+}
+
+// RegisterLostSamples 注册内核因 perf 缓冲区写满而丢弃的事件数
+func (m *Metrics) RegisterLostSamples(count func() uint64) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_lost_samples_total",
+		Help:      "Number of events dropped by the kernel because the perf buffer was full.",
+	}, func() float64 { return float64(count()) }))
+}
+
+// RegisterShortRecords 注册因长度不足而被丢弃的 perf 记录数
+func (m *Metrics) RegisterShortRecords(count func() uint64) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_short_records_total",
+		Help:      "Number of perf records dropped because they were shorter than a TrafficEvent.",
+	}, func() float64 { return float64(count()) }))
+}
@@ -0,0 +1,60 @@
+// internal/metrics/self.go
+package metrics
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// heapObjectsMetric 是 runtime/metrics 中存活和尚未回收的堆对象占用的字节数
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// registerSelf 注册 traffic-guardian 自身的资源占用: goroutine 数量、堆内存和 CPU 时间，
+// 所有值都在抓取时采样，便于评估在资源受限主机上的开销
+func (m *Metrics) registerSelf() {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "goroutines",
+			Help:      "Number of goroutines currently running in traffic-guardian.",
+		}, func() float64 { return float64(runtime.NumGoroutine()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "heap_bytes",
+			Help:      "Bytes of heap memory occupied by live and not yet freed objects.",
+		}, readHeapBytes),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cpu_seconds_total",
+			Help:      "User and system CPU time consumed by traffic-guardian, in seconds.",
+		}, readCPUSeconds),
+	)
+}
+
+// readHeapBytes 从 runtime/metrics 读取堆对象占用的字节数
+func readHeapBytes() float64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return float64(sample[0].Value.Uint64())
+}
+
+// readCPUSeconds 返回进程消耗的用户态和内核态 CPU 时间。
+// runtime/metrics 中的 CPU 指标只是估算且不包含系统调用，因此使用 getrusage
+func readCPUSeconds() float64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	return timevalSeconds(usage.Utime) + timevalSeconds(usage.Stime)
+}
+
+// timevalSeconds 将 syscall.Timeval 转换为秒
+func timevalSeconds(tv syscall.Timeval) float64 {
+	return float64(tv.Sec) + float64(tv.Usec)/1e6
+}
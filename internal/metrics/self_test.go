@@ -0,0 +1,37 @@
+// internal/metrics/self_test.go
+package metrics
+
+import "testing"
+
+func TestSelfMetrics(t *testing.T) {
+	families, err := New().Registry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]float64)
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			switch {
+			case metric.GetGauge() != nil:
+				values[f.GetName()] = metric.GetGauge().GetValue()
+			case metric.GetCounter() != nil:
+				values[f.GetName()] = metric.GetCounter().GetValue()
+			}
+		}
+	}
+
+	for name, least := range map[string]float64{
+		namespace + "_goroutines":        1,
+		namespace + "_heap_bytes":        1,
+		namespace + "_cpu_seconds_total": 0,
+	} {
+		v, ok := values[name]
+		if !ok {
+			t.Errorf("%s is not exported", name)
+			continue
+		}
+		if v < least {
+			t.Errorf("%s = %v, want at least %v", name, v, least)
+		}
+	}
+}
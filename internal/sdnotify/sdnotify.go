@@ -0,0 +1,59 @@
+// internal/sdnotify/sdnotify.go
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// systemd 通知协议中使用的状态
+const (
+	// Ready 表示服务已完成启动，用于 Type=notify
+	Ready = "READY=1"
+	// Stopping 表示服务开始退出
+	Stopping = "STOPPING=1"
+	// Watchdog 是看门狗心跳
+	Watchdog = "WATCHDOG=1"
+)
+
+// Enabled 检查进程是否由 systemd 以 Type=notify 启动，即设置了 NOTIFY_SOCKET
+func Enabled() bool {
+	return os.Getenv("NOTIFY_SOCKET") != ""
+}
+
+// Notify 向 NOTIFY_SOCKET 发送一条状态消息。未设置 NOTIFY_SOCKET 时什么都不做并返回 false
+func Notify(state string) (bool, error) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return false, nil
+	}
+	// 以 @ 开头的是 Linux 抽象命名空间中的套接字
+	if path[0] == '@' {
+		path = "\x00" + path[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval 返回发送看门狗心跳的间隔，为 systemd 通过 WATCHDOG_USEC 设置的超时的一半。
+// 未启用看门狗，或 WATCHDOG_PID 指向其他进程时返回 false
+func WatchdogInterval() (time.Duration, bool) {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond / 2, true
+}
@@ -0,0 +1,35 @@
+// internal/state/allowlist.go
+package state
+
+import (
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/match"
+)
+
+// allowlist 判断一个事件的目的地是否不计入流量统计
+type allowlist struct {
+	cidrs match.PrefixSet
+	ports map[uint16]bool
+}
+
+// newAllowlist 根据配置创建目的地白名单
+func newAllowlist(cfg config.Allowlist) (*allowlist, error) {
+	cidrs, err := match.ParsePrefixes(cfg.CIDRs)
+	if err != nil {
+		return nil, err
+	}
+	ports := make(map[uint16]bool, len(cfg.Ports))
+	for _, p := range cfg.Ports {
+		ports[p] = true
+	}
+	return &allowlist{cidrs: cidrs, ports: ports}, nil
+}
+
+// contains 检查事件的目的地是否在白名单中
+func (a *allowlist) contains(event *collector.TrafficEvent) bool {
+	if event.RemotePort != 0 && a.ports[event.RemotePort] {
+		return true
+	}
+	return len(a.cidrs) > 0 && a.cidrs.Contains(event.Remote())
+}
@@ -0,0 +1,37 @@
+// internal/state/allowlist_test.go
+package state
+
+import (
+	"testing"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestAllowlistedDestinationsAreNotCounted(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Monitor.Allowlist = config.Allowlist{
+		CIDRs: []string{"10.1.0.0/16", "2001:db8::1"},
+		Ports: []uint16{873},
+	}
+	m := newTestManager(t, cfg)
+
+	m.updateState(xmit(100, "apt", 1000, "10.1.2.3", 443))     // CIDR 白名单
+	m.updateState(xmit(100, "apt", 2000, "2001:db8::1", 443))  // 单个地址视为 /128
+	m.updateState(xmit(100, "apt", 4000, "198.51.100.1", 873)) // 端口白名单
+	m.updateState(xmit(100, "apt", 300, "10.2.0.1", 443))
+	m.updateState(xmit(100, "apt", 50, "2001:db8::2", 80))
+
+	s := mustStats(t, m, 100)
+	if s.TotalBytes != 350 {
+		t.Errorf("TotalBytes = %d, want 350 (only non-allowlisted destinations)", s.TotalBytes)
+	}
+	if s.TotalPackets != 2 {
+		t.Errorf("TotalPackets = %d, want 2", s.TotalPackets)
+	}
+
+	// 只与白名单目的地通信的进程不被统计
+	m.updateState(xmit(200, "mirror-sync", 1<<20, "10.1.9.9", 80))
+	if _, ok := m.GetProcessStats(200); ok {
+		t.Error("process with only allowlisted traffic is tracked")
+	}
+}
@@ -0,0 +1,39 @@
+// internal/state/comm.go
+package state
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxCommLen 是内核 comm 的最大长度（TASK_COMM_LEN 减去结尾的 '\0'），更长的进程名会被截断
+const maxCommLen = 15
+
+// fullComm 返回进程未被截断的名称。只有长度达到 maxCommLen 的 comm 才可能被截断；
+// /proc/<pid>/comm 同样只有 15 个字符，因此依次尝试 argv[0] 和可执行文件的文件名，
+// 取第一个以 comm 开头的名称，这样通过解释器运行的脚本不会被改名为解释器。进程已退出或无法读取时返回 comm
+func fullComm(pid uint32, comm string) string {
+	if len(comm) < maxCommLen {
+		return comm
+	}
+	proc := "/proc/" + strconv.FormatUint(uint64(pid), 10)
+
+	var candidates []string
+	if cmdline, err := os.ReadFile(proc + "/cmdline"); err == nil {
+		argv0, _, _ := bytes.Cut(cmdline, []byte{0})
+		candidates = append(candidates, filepath.Base(string(argv0)))
+	}
+	if exe, err := os.Readlink(proc + "/exe"); err == nil {
+		// 可执行文件被替换或删除后链接目标带有 " (deleted)" 后缀
+		candidates = append(candidates, filepath.Base(strings.TrimSuffix(exe, " (deleted)")))
+	}
+	for _, name := range candidates {
+		if len(name) > len(comm) && strings.HasPrefix(name, comm) {
+			return name
+		}
+	}
+	return comm
+}
@@ -0,0 +1,132 @@
+// internal/state/endpoints.go
+package state
+
+import (
+	"net/netip"
+	"sort"
+	"time"
+
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/match"
+)
+
+// maxTrackedEndpoints 限制每个进程记录的对端数量，避免扫描类进程占用过多内存
+const maxTrackedEndpoints = 4096
+
+// endpoint 是一个对端的通信记录
+type endpoint struct {
+	lastSeen time.Time
+	bytes    uint64
+}
+
+// Destination 是一个进程在时间窗口内发送过流量的对端
+type Destination struct {
+	Addr  netip.Addr `json:"addr"`
+	Port  uint16     `json:"port"`
+	Bytes uint64     `json:"bytes"`
+	// Hostname 是反向解析得到的主机名，只在警报中按需填充
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// trackEndpoint 记录事件的对端地址和端口，无法解析对端地址的事件不计入。
+// 记录的对端达到上限后只再记录 pinned 返回 true 的对端，保证 blocklist 规则不会因为进程先与大量无害地址通信而漏报
+func (s *ProcessStats) trackEndpoint(event *collector.TrafficEvent, now time.Time, pinned func(netip.Addr) bool) {
+	addr := event.Remote()
+	if !addr.IsValid() {
+		return
+	}
+	ap := netip.AddrPortFrom(addr, event.RemotePort)
+
+	if s.endpoints == nil {
+		s.endpoints = make(map[netip.AddrPort]*endpoint)
+	}
+	ep, ok := s.endpoints[ap]
+	if !ok {
+		if len(s.endpoints) >= maxTrackedEndpoints && !pinned(addr) {
+			return
+		}
+		ep = &endpoint{}
+		s.endpoints[ap] = ep
+	}
+	ep.lastSeen = now
+	ep.bytes += event.Len
+	s.ConnectionCount = len(s.endpoints)
+}
+
+// pruneEndpoints 删除在时间窗口内没有通信的对端
+func (s *ProcessStats) pruneEndpoints(now time.Time, window time.Duration) {
+	for ap, ep := range s.endpoints {
+		elapsed := now.Sub(ep.lastSeen)
+		if elapsed < 0 {
+			ep.lastSeen = now
+			continue
+		}
+		if elapsed > window {
+			delete(s.endpoints, ap)
+		}
+	}
+	s.ConnectionCount = len(s.endpoints)
+}
+
+// topDestinations 返回发送字节数最多的 n 个对端
+func (s *ProcessStats) topDestinations(n int) []Destination {
+	dests := make([]Destination, 0, len(s.endpoints))
+	for ap, ep := range s.endpoints {
+		dests = append(dests, Destination{Addr: ap.Addr(), Port: ap.Port(), Bytes: ep.bytes})
+	}
+	sort.Slice(dests, func(i, j int) bool { return dests[i].Bytes > dests[j].Bytes })
+	if len(dests) > n {
+		dests = dests[:n]
+	}
+	return dests
+}
+
+// TopDestinations 返回指定进程在时间窗口内发送字节数最多的 n 个对端
+func (m *Manager) TopDestinations(pid uint32, n int) []Destination {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats, ok := m.trafficStates[pid]
+	if !ok {
+		return nil
+	}
+	return stats.topDestinations(n)
+}
+
+// SetBlocklist 设置 blocklist 规则禁止通信的地址，与这些地址的通信不受每个进程记录的对端数量上限的限制。
+// 规则引擎在启动和重新加载规则时调用
+func (m *Manager) SetBlocklist(tables []*match.PrefixTable) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blocklist = tables
+}
+
+// blocklisted 检查地址是否在任意一条 blocklist 规则中，调用方必须持有 m.mu
+func (m *Manager) blocklisted(addr netip.Addr) bool {
+	for _, t := range m.blocklist {
+		if _, ok := t.Lookup(addr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDestinations 返回每个进程在时间窗口内通信过、且地址在 table 中的对端，按发送字节数从多到少排序，
+// 没有匹配对端的进程不出现在结果中
+func (m *Manager) MatchDestinations(table *match.PrefixTable) map[uint32][]Destination {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matched := make(map[uint32][]Destination)
+	for pid, stats := range m.trafficStates {
+		for ap, ep := range stats.endpoints {
+			if _, ok := table.Lookup(ap.Addr()); ok {
+				matched[pid] = append(matched[pid], Destination{Addr: ap.Addr(), Port: ap.Port(), Bytes: ep.bytes})
+			}
+		}
+	}
+	for _, dests := range matched {
+		sort.Slice(dests, func(i, j int) bool { return dests[i].Bytes > dests[j].Bytes })
+	}
+	return matched
+}
@@ -0,0 +1,56 @@
+// internal/state/endpoints_test.go
+package state
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/match"
+)
+
+func TestConnectionCountWithinWindow(t *testing.T) {
+	m := newTestManager(t, nil)
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+
+	// 同一地址的不同端口是不同的对端，重复的对端和无法解析的对端不计入
+	for i := 0; i < 50; i++ {
+		m.updateState(xmit(100, "scanner", 10, "192.0.2.1", uint16(1+i)))
+	}
+	m.updateState(xmit(100, "scanner", 10, "192.0.2.1", 1))
+	m.updateState(xmit(100, "scanner", 10, "2001:db8::1", 1))
+	m.updateState(xmit(100, "scanner", 10, "", 0))
+	if got := mustStats(t, m, 100).ConnectionCount; got != 51 {
+		t.Fatalf("ConnectionCount = %d, want 51", got)
+	}
+
+	// 超过时间窗口没有通信的对端在清理时被删除
+	now = now.Add(m.timeWindow + time.Minute)
+	m.updateState(xmit(100, "scanner", 10, "198.51.100.9", 53))
+	m.cleanup()
+	if got := mustStats(t, m, 100).ConnectionCount; got != 1 {
+		t.Errorf("ConnectionCount after the window = %d, want 1", got)
+	}
+}
+
+func TestBlocklistedEndpointBypassesTrackingLimit(t *testing.T) {
+	m := newTestManager(t, nil)
+	blocked := match.NewPrefixTable(match.PrefixSet{netip.MustParsePrefix("203.0.113.0/24")})
+	m.SetBlocklist([]*match.PrefixTable{blocked})
+
+	// 先与足够多的无害对端通信，占满记录上限
+	for i := 0; i < maxTrackedEndpoints; i++ {
+		m.updateState(xmit(100, "scanner", 10, "10.0.0.1", uint16(1024+i)))
+	}
+	m.updateState(xmit(100, "scanner", 10, "10.0.0.2", 80))
+	m.updateState(xmit(100, "scanner", 500, "203.0.113.7", 443))
+
+	if got := mustStats(t, m, 100).ConnectionCount; got != maxTrackedEndpoints+1 {
+		t.Errorf("ConnectionCount = %d, want %d (only the blocklisted endpoint beyond the limit)", got, maxTrackedEndpoints+1)
+	}
+	dests := m.MatchDestinations(blocked)[100]
+	if len(dests) != 1 || dests[0].Addr != netip.MustParseAddr("203.0.113.7") || dests[0].Bytes != 500 {
+		t.Errorf("MatchDestinations = %+v, want the blocklisted endpoint with 500 bytes", dests)
+	}
+}
@@ -0,0 +1,39 @@
+// internal/state/filter.go
+package state
+
+import (
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/match"
+)
+
+// commFilter 根据进程名决定一个事件是否需要被统计
+type commFilter struct {
+	include match.Set
+	exclude match.Set
+}
+
+// newCommFilter 根据配置编译进程名过滤规则
+func newCommFilter(cfg config.Monitor) (*commFilter, error) {
+	include, err := match.CompileAll(cfg.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := match.CompileAll(cfg.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &commFilter{include: include, exclude: exclude}, nil
+}
+
+// isEmpty 检查过滤器是否没有配置任何规则
+func (f *commFilter) isEmpty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// allows 检查进程名为 comm 的进程是否需要被统计
+func (f *commFilter) allows(comm string) bool {
+	if f.exclude.MatchAny(comm) {
+		return false
+	}
+	return len(f.include) == 0 || f.include.MatchAny(comm)
+}
@@ -0,0 +1,54 @@
+// internal/state/kthread.go
+package state
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// pfKthread 是 /proc/<pid>/stat 中 flags 字段表示内核线程的标志位 (PF_KTHREAD)
+const pfKthread = 0x00200000
+
+// isKernelEvent 检查事件是否来自 PID 0（软中断或空闲任务中发送的数据包）或内核线程，
+// 这类流量无法归属到某个用户空间进程。procPID 是在本进程的 /proc 中访问该进程的 PID，为 0 时（进程不可见）
+// 只按 PID 和进程名判断。判断结果按 PID 缓存到下一次清理，避免每个事件都读取 /proc
+func (m *Manager) isKernelEvent(pid, procPID uint32, comm string) bool {
+	if pid == 0 {
+		return true
+	}
+	// ps 等工具将内核线程显示为 [comm]，只在探针或回放文件中出现这种名称时才会命中
+	if strings.HasPrefix(comm, "[") && strings.HasSuffix(comm, "]") {
+		return true
+	}
+
+	kthread, ok := m.kernelThreads[pid]
+	if !ok {
+		kthread = procPID != 0 && m.isKernelThread(procPID)
+		m.kernelThreads[pid] = kthread
+	}
+	return kthread
+}
+
+// isKernelThread 根据 /proc/<pid>/stat 的 flags 字段判断进程是否为内核线程，进程已不存在时返回 false
+func isKernelThread(pid uint32) bool {
+	data, err := os.ReadFile("/proc/" + strconv.FormatUint(uint64(pid), 10) + "/stat")
+	if err != nil {
+		return false
+	}
+	// comm 可能包含空格和括号，从最后一个 ')' 之后开始按空格分割: state ppid pgrp session tty_nr tpgid flags ...
+	i := bytes.LastIndexByte(data, ')')
+	if i < 0 {
+		return false
+	}
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 7 {
+		return false
+	}
+	flags, err := strconv.ParseUint(fields[6], 10, 64)
+	if err != nil {
+		return false
+	}
+	return flags&pfKthread != 0
+}
@@ -0,0 +1,52 @@
+// internal/state/kthread_test.go
+package state
+
+import (
+	"testing"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestKernelEventsAreIgnored(t *testing.T) {
+	// 201 在 /proc 中是内核线程，但进程名看不出来
+	const kthreadPID = 201
+	newManager := func(t *testing.T, keep bool) *Manager {
+		cfg := config.DefaultConfig()
+		cfg.Monitor.KeepKernelThreads = keep
+		m := newTestManager(t, cfg)
+		m.pidns = 0
+		m.isKernelThread = func(pid uint32) bool { return pid == kthreadPID }
+		return m
+	}
+	events := []struct {
+		pid    uint32
+		comm   string
+		kernel bool
+	}{
+		{0, "swapper/0", true},
+		{200, "[kworker/0:1]", true},
+		{kthreadPID, "ksoftirqd/0", true},
+		{300, "curl", false},
+	}
+
+	m := newManager(t, false)
+	for _, e := range events {
+		m.updateState(xmit(e.pid, e.comm, 100, "198.51.100.1", 443))
+	}
+	for _, e := range events {
+		if _, ok := m.GetProcessStats(e.pid); ok == e.kernel {
+			t.Errorf("PID %d (%s): tracked = %v, want %v", e.pid, e.comm, ok, !e.kernel)
+		}
+	}
+
+	// keep_kernel_threads 为 true 时照常统计
+	m = newManager(t, true)
+	for _, e := range events {
+		m.updateState(xmit(e.pid, e.comm, 100, "198.51.100.1", 443))
+	}
+	for _, e := range events {
+		if _, ok := m.GetProcessStats(e.pid); !ok {
+			t.Errorf("PID %d (%s) is not tracked with keep_kernel_threads", e.pid, e.comm)
+		}
+	}
+}
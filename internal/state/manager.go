@@ -4,18 +4,90 @@ package state
 import (
 	"context"
 	"log/slog"
+	"net/netip"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"traffic-guardian/internal/collector"
 	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/match"
 )
 
 // ProcessStats 存储单个进程的流量信息
 type ProcessStats struct {
-	PID        uint32
-	TotalBytes uint64
-	LastSeen   time.Time
+	PID      uint32 `json:"pid"`
+	Comm     string `json:"comm"`
+	UID      uint32 `json:"uid"`
+	Username string `json:"username"`
+	// NetNS 是进程所在网络命名空间的 inode 号，用于区分容器。PID 取自初始 PID 命名空间，不会在容器之间冲突
+	NetNS uint32 `json:"netns"`
+	// NSPID 是进程在其所在（最内层）PID 命名空间中的 PID，即容器内看到的 PID，无法获取时为 0
+	NSPID      uint32 `json:"ns_pid,omitempty"`
+	TotalBytes uint64 `json:"total_bytes"`
+	// TotalPackets 是发送的数据包数量，AvgPacketBytes 是平均包长 TotalBytes / TotalPackets。
+	// 包长很小通常意味着扫描或心跳回连，很大意味着批量传输。回放不包含数据包数量的旧录制文件时两者均为 0
+	TotalPackets   uint64  `json:"total_packets"`
+	AvgPacketBytes float64 `json:"avg_packet_bytes,omitempty"`
+	// InternalBytes 和 ExternalBytes 是 TotalBytes 中发往 rules.internal_cidrs 以内和以外的部分
+	InternalBytes uint64    `json:"internal_bytes"`
+	ExternalBytes uint64    `json:"external_bytes"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+	// DeltaBytes 是上一个采样周期（即检查间隔）内发送的字节数，RateBps 是对应的发送速率（字节/秒），
+	// EWMARateBps 是速率的指数加权移动平均
+	DeltaBytes  uint64  `json:"delta_bytes"`
+	RateBps     float64 `json:"rate_bps"`
+	EWMARateBps float64 `json:"ewma_rate_bps"`
+	// BaselineRateBps 和 BaselineStdDevBps 是加入最新样本之前速率的 EWMA 均值和标准差，
+	// RateSamples 是已采集的速率样本数，用于突增检测
+	BaselineRateBps   float64 `json:"baseline_rate_bps"`
+	BaselineStdDevBps float64 `json:"baseline_stddev_bps"`
+	RateSamples       int     `json:"rate_samples"`
+	// ConnectionCount 是时间窗口内通信过的不同对端 (IP:端口) 数量
+	ConnectionCount int `json:"connection_count"`
+	// Exited 表示进程已退出，此时的流量即为最终值；ExitedAt 是收到退出事件的时间
+	Exited   bool      `json:"exited"`
+	ExitedAt time.Time `json:"exited_at"`
+	// Windows 是规则配置的每个命名滑动窗口内的流量，见 RuleDefinition.WindowMinutes
+	Windows map[string]WindowTraffic `json:"windows,omitempty"`
+
+	// 用于计算速率的上一次采样状态
+	lastSampleAt    time.Time
+	lastSampleBytes uint64
+	rateVariance    float64
+	// endpoints 记录每个对端最近一次通信的时间和发送的字节数
+	endpoints map[netip.AddrPort]*endpoint
+	// windows 是每个命名滑动窗口的分桶计数
+	windows map[string]*slidingWindow
+	// localPorts 是从每个本端（非临时）端口发出的流量，见 Manager.GetStatsByLocalPort
+	localPorts map[uint16]*portTraffic
+	// subnets 是发往每条 cidr 规则的目的网段的流量，键为规则名称，见 Manager.GetStatsBySubnet
+	subnets map[string]uint64
+}
+
+// UserStats 存储单个用户所有进程的流量汇总
+type UserStats struct {
+	UID           uint32 `json:"uid"`
+	Username      string `json:"username"`
+	TotalBytes    uint64 `json:"total_bytes"`
+	InternalBytes uint64 `json:"internal_bytes"`
+	ExternalBytes uint64 `json:"external_bytes"`
+	ProcessCount  int    `json:"process_count"`
+}
+
+// NetNSStats 存储同一网络命名空间内所有进程的流量汇总
+type NetNSStats struct {
+	NetNS        uint32 `json:"netns"`
+	TotalBytes   uint64 `json:"total_bytes"`
+	ProcessCount int    `json:"process_count"`
+}
+
+// CommStats 存储同名进程的流量汇总
+type CommStats struct {
+	Comm         string `json:"comm"`
+	TotalBytes   uint64 `json:"total_bytes"`
+	ProcessCount int    `json:"process_count"`
 }
 
 // Manager 负责管理所有进程的流量状态
@@ -24,14 +96,111 @@ type Manager struct {
 	trafficStates map[uint32]*ProcessStats
 	mu            sync.RWMutex
 	timeWindow    time.Duration
+	// windows 是规则用到的命名滑动窗口及其长度；retention 是进程无活动后被清理前保留的时长，
+	// 不短于最长的滑动窗口，避免间歇发送的进程在窗口结束前丢失计数
+	windows      map[string]time.Duration
+	retention    time.Duration
+	rateInterval time.Duration
+	// jitterPercent 是清理间隔的随机抖动百分比，见 rules.check_jitter_percent；速率采样间隔不抖动
+	jitterPercent int
+	ewmaAlpha     float64
+	users         *userCache
+	filter        *commFilter
+	allowlist     *allowlist
+	// internal 是内部网络的地址段，用于区分内部和外部流量
+	internal match.PrefixSet
+	// subnets 是 cidr 规则统计的目的网段，只在启动时读取
+	subnets []subnetRule
+	// blocklist 是 blocklist 规则禁止通信的地址，由规则引擎通过 SetBlocklist 设置
+	blocklist []*match.PrefixTable
+	// ephemeral 是内核的临时端口范围，从这些本端端口发出的流量不按端口统计
+	ephemeral portRange
+	// lastEvent 是最近一次收到事件的时间（UnixNano），包括被过滤的事件
+	lastEvent atomic.Int64
+	// now 返回当前时间。time.Now 的返回值带有单调时钟读数，
+	// 同一进程内的时间差不受 NTP 调整影响；注入的时钟仍可能倒退，比较时需要防御负值
+	now func() time.Time
+	// ignoreKernel 为 true 时丢弃 PID 0 和内核线程的事件；kernelThreads 缓存尚未被跟踪的 PID 是否为内核线程，
+	// 每次清理时清空以应对 PID 复用。isKernelThread 在测试中可以替换
+	ignoreKernel   bool
+	kernelThreads  map[uint32]bool
+	isKernelThread func(pid uint32) bool
+	// pidns 是本进程所在 PID 命名空间的 inode 号，不在主机 PID 命名空间中时，/proc 只能通过事件的 NSPID 访问
+	// 同一命名空间中的进程，见 procPID
+	pidns uint32
+	// fullComm 不为 nil 时，第一次见到进程时用它获取未被截断的进程名，见 collector.enrich_comm。在测试中可以替换
+	fullComm func(pid uint32, comm string) string
+	// batchSize 大于 1 时，Start 在一次加锁中处理最多 batchSize 个已到达的事件；
+	// batch 是复用的缓冲区，只在 Start 所在的 goroutine 中使用
+	batchSize int
+	batch     []collector.TrafficEvent
+	// reportExits 为 true 时，退出的进程会被加入 exited，等待规则引擎通过 TakeExited 取走
+	reportExits bool
+	exited      []ProcessStats
 }
 
+// maxPendingExits 是等待规则引擎取走的退出进程数量上限，超过后新退出的进程不再加入
+const maxPendingExits = 1024
+
 // NewManager 创建一个新的状态管理器
 func NewManager(log *slog.Logger, cfg *config.Config) *Manager {
+	filter, err := newCommFilter(cfg.Monitor)
+	if err != nil {
+		// 配置在加载时已经校验过，这里只在调用方绕过校验时发生
+		log.Error("Invalid monitor filter, filtering disabled", "error", err)
+		filter = &commFilter{}
+	}
+	allow, err := newAllowlist(cfg.Monitor.Allowlist)
+	if err != nil {
+		log.Error("Invalid monitor allowlist, allowlist disabled", "error", err)
+		allow = &allowlist{}
+	}
+	internal, err := match.ParsePrefixes(cfg.Rules.InternalCIDRs)
+	if err != nil {
+		log.Error("Invalid internal CIDRs, all traffic is treated as external", "error", err)
+		internal = nil
+	}
+
+	windows := cfg.Rules.GetWindows()
+	retention := cfg.Rules.GetTimeWindow()
+	for _, w := range windows {
+		retention = max(retention, w)
+	}
+
+	var enrich func(uint32, string) string
+	if cfg.Collector.EnrichComm {
+		enrich = fullComm
+	}
+
+	pidns := selfPIDNS()
+	if pidns != 0 && pidns != initPIDNS && (cfg.Collector.EnrichComm || !cfg.Monitor.KeepKernelThreads) {
+		log.Warn("Not running in the host PID namespace, /proc lookups only work for processes in the same namespace; run with hostPID (Kubernetes) or --pid=host (Docker) to cover all processes",
+			"pidns", pidns)
+	}
+
 	return &Manager{
-		log:           log,
-		trafficStates: make(map[uint32]*ProcessStats),
-		timeWindow:    cfg.Rules.GetTimeWindow(),
+		log:            log,
+		trafficStates:  make(map[uint32]*ProcessStats),
+		timeWindow:     cfg.Rules.GetTimeWindow(),
+		windows:        windows,
+		subnets:        compileSubnets(cfg.Rules.GetCIDRs()),
+		retention:      retention,
+		rateInterval:   cfg.Rules.GetCheckInterval(),
+		ewmaAlpha:      cfg.Rules.GetEWMAAlpha(),
+		users:          newUserCache(),
+		filter:         filter,
+		allowlist:      allow,
+		internal:       internal,
+		ephemeral:      ephemeralPorts(),
+		now:            time.Now,
+		ignoreKernel:   !cfg.Monitor.KeepKernelThreads,
+		kernelThreads:  make(map[uint32]bool),
+		isKernelThread: isKernelThread,
+		fullComm:       enrich,
+		pidns:          pidns,
+		batchSize:      cfg.Monitor.BatchSize,
+		jitterPercent:  cfg.Rules.CheckJitterPercent,
+		reportExits:    cfg.Rules.ExitReport.Enabled,
 	}
 }
 
@@ -39,49 +208,204 @@ func NewManager(log *slog.Logger, cfg *config.Config) *Manager {
 func (m *Manager) Start(ctx context.Context, eventsChan <-chan collector.TrafficEvent) {
 	m.log.Info("Starting state manager")
 	// 创建一个定时器来定期清理过期的数据
-	ticker := time.NewTicker(m.timeWindow)
+	ticker := time.NewTicker(config.Jitter(m.timeWindow, m.jitterPercent))
 	defer ticker.Stop()
+	// 创建一个定时器来定期采样速率
+	rateTicker := time.NewTicker(m.rateInterval)
+	defer rateTicker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
+			m.drain(eventsChan)
 			m.log.Info("State manager stopped")
 			return
 		case event := <-eventsChan:
-			m.updateState(event)
+			if m.batchSize > 1 {
+				m.updateBatch(m.collectBatch(event, eventsChan))
+			} else {
+				m.updateState(event)
+			}
 		case <-ticker.C:
 			m.cleanup()
+			if m.jitterPercent > 0 {
+				ticker.Reset(config.Jitter(m.timeWindow, m.jitterPercent))
+			}
+		case now := <-rateTicker.C:
+			m.sampleRates(now)
+		}
+	}
+}
+
+// drain 处理 channel 中剩余的事件，在退出前调用
+func (m *Manager) drain(eventsChan <-chan collector.TrafficEvent) {
+	for {
+		select {
+		case event := <-eventsChan:
+			m.updateState(event)
+		default:
+			return
 		}
 	}
 }
 
 // updateState 更新一个进程的流量数据
 func (m *Manager) updateState(event collector.TrafficEvent) {
+	m.lastEvent.Store(time.Now().UnixNano())
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apply(&event)
+}
+
+// collectBatch 以 first 开头，不阻塞地从 channel 中再取出最多 batchSize-1 个已经到达的事件。
+// 返回的切片在下一次调用前有效
+func (m *Manager) collectBatch(first collector.TrafficEvent, eventsChan <-chan collector.TrafficEvent) []collector.TrafficEvent {
+	m.batch = append(m.batch[:0], first)
+	for len(m.batch) < m.batchSize {
+		select {
+		case event := <-eventsChan:
+			m.batch = append(m.batch, event)
+		default:
+			return m.batch
+		}
+	}
+	return m.batch
+}
+
+// updateBatch 在一次加锁中按顺序处理多个事件，结果与逐个调用 updateState 相同
+func (m *Manager) updateBatch(events []collector.TrafficEvent) {
+	m.lastEvent.Store(time.Now().UnixNano())
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	for i := range events {
+		m.apply(&events[i])
+	}
+}
+
+// apply 将一个事件计入对应进程的流量数据，调用方必须持有 m.mu
+func (m *Manager) apply(event *collector.TrafficEvent) {
+	if event.IsExit() {
+		m.markExited(event.PID)
+		return
+	}
+
+	// 发往白名单目的地的流量不计入统计
+	if m.allowlist.contains(event) {
+		return
+	}
 
 	stats, ok := m.trafficStates[event.PID]
-	if !ok {
-		stats = &ProcessStats{PID: event.PID}
+	// 已退出进程的 PID 被复用时，作为一个新进程重新统计
+	if !ok || stats.Exited {
+		// 只在第一次见到进程时进行过滤，已被统计的进程一定通过了过滤
+		comm := event.CommString()
+		procPID := m.procPID(event)
+		if m.ignoreKernel && m.isKernelEvent(event.PID, procPID, comm) {
+			return
+		}
+		// 进程名过滤、规则匹配和显示都使用补全后的名称
+		if m.fullComm != nil && procPID != 0 {
+			comm = m.fullComm(procPID, comm)
+		}
+		if !m.filter.isEmpty() && !m.filter.allows(comm) {
+			return
+		}
+
+		now := m.now()
+		stats = &ProcessStats{
+			PID:          event.PID,
+			NSPID:        event.NSPID,
+			Comm:         comm,
+			UID:          event.UID,
+			Username:     m.users.lookup(event.UID),
+			FirstSeen:    now,
+			lastSampleAt: now,
+		}
 		m.trafficStates[event.PID] = stats
 	}
 
+	now := m.now()
 	stats.TotalBytes += event.Len
-	stats.LastSeen = time.Now()
+	stats.TotalPackets += uint64(event.Packets)
+	// 进程可以通过 setns 切换网络命名空间，以最近一个事件为准
+	stats.NetNS = event.NetNS
+	// 无法解析对端地址的流量按外部流量计算
+	internal := m.internal.Contains(event.Remote())
+	if internal {
+		stats.InternalBytes += event.Len
+	} else {
+		stats.ExternalBytes += event.Len
+	}
+	stats.LastSeen = now
+	stats.trackEndpoint(event, now, m.blocklisted)
+	stats.trackWindows(m.windows, now, internal, event.Len)
+	stats.trackSubnets(m.subnets, event)
+	if !m.ephemeral.contains(event.LocalPort) {
+		stats.trackLocalPort(event.LocalPort, internal, event.Len)
+	}
+}
+
+// markExited 将进程标记为已退出，保留其最终流量直到在时间窗口后被清理，调用方必须持有 m.mu
+func (m *Manager) markExited(pid uint32) {
+	stats, ok := m.trafficStates[pid]
+	if !ok || stats.Exited {
+		// 没有发送过流量（或被过滤）的进程不需要记录
+		return
+	}
+	stats.Exited = true
+	stats.ExitedAt = m.now()
+	m.log.Debug("Process exited", "pid", pid, "comm", stats.Comm, "total_bytes", stats.TotalBytes)
+
+	if m.reportExits {
+		if len(m.exited) >= maxPendingExits {
+			m.log.Warn("Too many pending exited processes, dropping exit report", "pid", pid, "comm", stats.Comm)
+			return
+		}
+		m.exited = append(m.exited, stats.snapshot(stats.ExitedAt))
+	}
+}
+
+// TakeExited 返回自上次调用以来退出的进程的最终状态，只在启用了 exit_report 时有数据
+func (m *Manager) TakeExited() []ProcessStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	exited := m.exited
+	m.exited = nil
+	return exited
 }
 
-// cleanup 删除在时间窗口内没有活动的老数据
+// LastEvent 返回最近一次收到事件的时间，尚未收到任何事件时返回零值
+func (m *Manager) LastEvent() time.Time {
+	if ns := m.lastEvent.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// cleanup 删除在保留时长内没有活动的老数据
 func (m *Manager) cleanup() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	now := time.Now()
+	clear(m.kernelThreads)
+	now := m.now()
 	cleanedCount := 0
 	for pid, stats := range m.trafficStates {
-		if now.Sub(stats.LastSeen) > m.timeWindow {
+		elapsed := now.Sub(stats.LastSeen)
+		if elapsed < 0 {
+			// 时钟倒退：从现在开始重新计算，避免进程在时钟追上之前都无法被清理
+			stats.LastSeen = now
+			continue
+		}
+		if elapsed > m.retention {
 			delete(m.trafficStates, pid)
 			cleanedCount++
+			continue
 		}
+		stats.pruneEndpoints(now, m.timeWindow)
 	}
 	if cleanedCount > 0 {
 		m.log.Debug("Cleaned up old state entries", "count", cleanedCount)
@@ -93,9 +417,126 @@ func (m *Manager) GetStats() []ProcessStats {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	now := m.now()
 	statsCopy := make([]ProcessStats, 0, len(m.trafficStates))
 	for _, stats := range m.trafficStates {
-		statsCopy = append(statsCopy, *stats)
+		statsCopy = append(statsCopy, stats.snapshot(now))
 	}
 	return statsCopy
 }
+
+// GetProcessStats 返回指定进程流量状态的一个副本，以及该进程是否存在
+func (m *Manager) GetProcessStats(pid uint32) (ProcessStats, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats, ok := m.trafficStates[pid]
+	if !ok {
+		return ProcessStats{}, false
+	}
+	return stats.snapshot(m.now()), true
+}
+
+// Reset 删除指定进程的流量状态，返回该进程此前是否存在
+func (m *Manager) Reset(pid uint32) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.trafficStates[pid]
+	if ok {
+		delete(m.trafficStates, pid)
+		m.log.Info("Reset process stats", "pid", pid)
+	}
+	return ok
+}
+
+// GetStatsByUID 返回按用户汇总的流量状态
+func (m *Manager) GetStatsByUID() []UserStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byUID := make(map[uint32]*UserStats)
+	for _, stats := range m.trafficStates {
+		u, ok := byUID[stats.UID]
+		if !ok {
+			u = &UserStats{UID: stats.UID, Username: stats.Username}
+			byUID[stats.UID] = u
+		}
+		u.TotalBytes += stats.TotalBytes
+		u.InternalBytes += stats.InternalBytes
+		u.ExternalBytes += stats.ExternalBytes
+		u.ProcessCount++
+	}
+
+	userStats := make([]UserStats, 0, len(byUID))
+	for _, u := range byUID {
+		userStats = append(userStats, *u)
+	}
+	return userStats
+}
+
+// GetStatsByNetNS 返回按网络命名空间汇总的流量状态
+func (m *Manager) GetStatsByNetNS() []NetNSStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byNetNS := make(map[uint32]*NetNSStats)
+	for _, stats := range m.trafficStates {
+		n, ok := byNetNS[stats.NetNS]
+		if !ok {
+			n = &NetNSStats{NetNS: stats.NetNS}
+			byNetNS[stats.NetNS] = n
+		}
+		n.TotalBytes += stats.TotalBytes
+		n.ProcessCount++
+	}
+
+	netnsStats := make([]NetNSStats, 0, len(byNetNS))
+	for _, n := range byNetNS {
+		netnsStats = append(netnsStats, *n)
+	}
+	return netnsStats
+}
+
+// GetStatsByComm 按进程名汇总所有进程的流量
+func (m *Manager) GetStatsByComm() []CommStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byComm := make(map[string]*CommStats)
+	for _, stats := range m.trafficStates {
+		c, ok := byComm[stats.Comm]
+		if !ok {
+			c = &CommStats{Comm: stats.Comm}
+			byComm[stats.Comm] = c
+		}
+		c.TotalBytes += stats.TotalBytes
+		c.ProcessCount++
+	}
+
+	commStats := make([]CommStats, 0, len(byComm))
+	for _, c := range byComm {
+		commStats = append(commStats, *c)
+	}
+	return commStats
+}
+
+// snapshot 返回进程状态的副本，不包含只在管理器内部使用的对端记录、端口计数和分桶，
+// 每个滑动窗口截至 now 的流量汇总到 Windows 中
+func (s *ProcessStats) snapshot(now time.Time) ProcessStats {
+	c := *s
+	c.endpoints = nil
+	c.windows = nil
+	c.localPorts = nil
+	c.subnets = nil
+	if s.TotalPackets > 0 {
+		c.AvgPacketBytes = float64(s.TotalBytes) / float64(s.TotalPackets)
+	}
+	if len(s.windows) > 0 {
+		c.Windows = make(map[string]WindowTraffic, len(s.windows))
+		for name, w := range s.windows {
+			c.Windows[name] = w.sum(now)
+		}
+	}
+	return c
+}
@@ -0,0 +1,186 @@
+// internal/state/manager_test.go
+package state
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/config"
+)
+
+// newTestManager 创建一个不读取 /proc 的状态管理器，cfg 为 nil 时使用 config.DefaultConfig
+func newTestManager(t testing.TB, cfg *config.Config) *Manager {
+	t.Helper()
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	m := NewManager(slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+	m.isKernelThread = func(uint32) bool { return false }
+	m.fullComm = nil
+	return m
+}
+
+// xmit 构造一个发送事件，remote 为空时对端地址无法解析
+func xmit(pid uint32, comm string, n uint64, remote string, port uint16) collector.TrafficEvent {
+	event := collector.TrafficEvent{PID: pid, UID: 1000, Len: n, RemotePort: port, Packets: 1}
+	copy(event.Comm[:], comm)
+	if remote == "" {
+		return event
+	}
+	addr := netip.MustParseAddr(remote)
+	if addr.Is4() {
+		event.Family = 4
+		a4 := addr.As4()
+		copy(event.RemoteAddr[:], a4[:])
+	} else {
+		event.Family = 6
+		event.RemoteAddr = addr.As16()
+	}
+	return event
+}
+
+// mustStats 返回指定进程的流量状态，进程不存在时使测试失败
+func mustStats(t *testing.T, m *Manager, pid uint32) ProcessStats {
+	t.Helper()
+	s, ok := m.GetProcessStats(pid)
+	if !ok {
+		t.Fatalf("process %d is not tracked", pid)
+	}
+	return s
+}
+
+func TestCleanupAfterBackwardClockJump(t *testing.T) {
+	m := newTestManager(t, nil)
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+	m.updateState(xmit(100, "curl", 100, "198.51.100.1", 443))
+
+	// 时钟倒退两小时：进程不会被立即清理，也不会被保留到时钟追上为止
+	now = now.Add(-2 * time.Hour)
+	m.cleanup()
+	if got := mustStats(t, m, 100).LastSeen; !got.Equal(now) {
+		t.Fatalf("LastSeen after the jump = %v, want it reset to %v", got, now)
+	}
+	now = now.Add(m.retention + time.Second)
+	m.cleanup()
+	if _, ok := m.GetProcessStats(100); ok {
+		t.Error("process is still tracked one retention period after the clock jump")
+	}
+}
+
+func TestGetStatsByCommSumsPIDs(t *testing.T) {
+	m := newTestManager(t, nil)
+	m.updateState(xmit(100, "nginx", 1000, "198.51.100.1", 443))
+	m.updateState(xmit(101, "nginx", 2000, "198.51.100.2", 443))
+	m.updateState(xmit(101, "nginx", 500, "198.51.100.2", 443))
+	m.updateState(xmit(200, "curl", 300, "198.51.100.3", 443))
+
+	got := make(map[string]CommStats)
+	for _, c := range m.GetStatsByComm() {
+		got[c.Comm] = c
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetStatsByComm = %+v, want nginx and curl", got)
+	}
+	if c := got["nginx"]; c.TotalBytes != 3500 || c.ProcessCount != 2 {
+		t.Errorf("nginx = %+v, want 3500 bytes from 2 processes", c)
+	}
+	if c := got["curl"]; c.TotalBytes != 300 || c.ProcessCount != 1 {
+		t.Errorf("curl = %+v, want 300 bytes from 1 process", c)
+	}
+}
+
+// mixedEvents 构造 n 个分布在多个进程和对端上的事件，中途有一个进程退出后 PID 被复用
+func mixedEvents(n int) []collector.TrafficEvent {
+	events := make([]collector.TrafficEvent, 0, n+1)
+	for i := range n {
+		pid := uint32(100 + i%5)
+		remote := fmt.Sprintf("198.51.100.%d", 1+i%7)
+		events = append(events, xmit(pid, "worker", uint64(100+i%13), remote, 443))
+		if i == n/2 {
+			exit := collector.TrafficEvent{PID: 100, Kind: collector.EventExit}
+			copy(exit.Comm[:], "worker")
+			events = append(events, exit)
+		}
+	}
+	return events
+}
+
+// statsByPID 以 PID 为键返回所有进程的流量状态
+func statsByPID(m *Manager) map[uint32]ProcessStats {
+	byPID := make(map[uint32]ProcessStats)
+	for _, s := range m.GetStats() {
+		byPID[s.PID] = s
+	}
+	return byPID
+}
+
+func TestBatchedUpdatesMatchPerEvent(t *testing.T) {
+	events := mixedEvents(1000)
+	now := time.Unix(1700000000, 0)
+
+	single := newTestManager(t, nil)
+	single.now = func() time.Time { return now }
+	for _, e := range events {
+		single.updateState(e)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Monitor.BatchSize = 64
+	batched := newTestManager(t, cfg)
+	batched.now = func() time.Time { return now }
+	eventsChan := make(chan collector.TrafficEvent, len(events))
+	for _, e := range events {
+		eventsChan <- e
+	}
+	batches := 0
+	for len(eventsChan) > 0 {
+		batch := batched.collectBatch(<-eventsChan, eventsChan)
+		if len(batch) > cfg.Monitor.BatchSize {
+			t.Fatalf("batch of %d events exceeds batch_size %d", len(batch), cfg.Monitor.BatchSize)
+		}
+		batched.updateBatch(batch)
+		batches++
+	}
+	if want := (len(events) + 63) / 64; batches != want {
+		t.Errorf("applied %d batches, want %d", batches, want)
+	}
+
+	want, got := statsByPID(single), statsByPID(batched)
+	if len(want) == 0 {
+		t.Fatal("no processes were tracked")
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("batched stats differ from per-event stats:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	events := mixedEvents(1024)
+	newManager := func(batchSize int) *Manager {
+		cfg := config.DefaultConfig()
+		cfg.Monitor.BatchSize = batchSize
+		return newTestManager(b, cfg)
+	}
+
+	b.Run("per-event", func(b *testing.B) {
+		m := newManager(0)
+		for i := 0; i < b.N; i++ {
+			m.updateState(events[i%len(events)])
+		}
+	})
+	b.Run("batched", func(b *testing.B) {
+		const batchSize = 64
+		m := newManager(batchSize)
+		for i := 0; i < b.N; i += batchSize {
+			start := i % len(events)
+			m.updateBatch(events[start:min(start+batchSize, len(events), start+b.N-i)])
+		}
+	})
+}
@@ -0,0 +1,43 @@
+// internal/state/pidns.go
+package state
+
+import (
+	"os"
+	"syscall"
+
+	"traffic-guardian/internal/collector"
+)
+
+// initPIDNS 是初始（主机）PID 命名空间的 inode 号，即内核中的 PROC_PID_INIT_INO
+const initPIDNS = 0xEFFFFFFC
+
+// selfPIDNS 返回本进程所在 PID 命名空间的 inode 号，无法读取时返回 0
+func selfPIDNS() uint32 {
+	info, err := os.Stat("/proc/self/ns/pid")
+	if err != nil {
+		return 0
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
+	return uint32(st.Ino)
+}
+
+// inHostPIDNS 检查本进程是否运行在主机 PID 命名空间中，无法判断时按是处理
+func (m *Manager) inHostPIDNS() bool {
+	return m.pidns == 0 || m.pidns == initPIDNS
+}
+
+// procPID 返回在本进程的 /proc 中访问事件所属进程时使用的 PID。事件中的 PID 取自主机 PID 命名空间，
+// 运行在容器中（没有 hostPID）时只能通过 NSPID 访问同一 PID 命名空间中的进程，其他进程不可见，返回 0
+func (m *Manager) procPID(event *collector.TrafficEvent) uint32 {
+	switch {
+	case m.inHostPIDNS():
+		return event.PID
+	case event.PIDNS != 0 && event.PIDNS == m.pidns:
+		return event.NSPID
+	default:
+		return 0
+	}
+}
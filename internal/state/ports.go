@@ -0,0 +1,104 @@
+// internal/state/ports.go
+package state
+
+import (
+	"fmt"
+	"os"
+)
+
+// maxTrackedLocalPorts 限制每个进程记录的本端端口数量
+const maxTrackedLocalPorts = 256
+
+// 无法读取 /proc/sys/net/ipv4/ip_local_port_range 时使用的内核默认临时端口范围
+const (
+	defaultEphemeralLow  = 32768
+	defaultEphemeralHigh = 60999
+)
+
+// portRange 是一个闭区间的端口范围
+type portRange struct {
+	low, high uint16
+}
+
+// contains 检查端口是否在范围内
+func (r portRange) contains(port uint16) bool {
+	return port >= r.low && port <= r.high
+}
+
+// ephemeralPorts 返回内核为主动连接分配本端端口的范围。客户端连接的本端端口落在此范围内，
+// 按端口统计时跳过它们，只保留监听端口（服务端进程）的流量
+func ephemeralPorts() portRange {
+	data, err := os.ReadFile("/proc/sys/net/ipv4/ip_local_port_range")
+	if err == nil {
+		var low, high uint16
+		if _, err := fmt.Sscan(string(data), &low, &high); err == nil && low <= high {
+			return portRange{low: low, high: high}
+		}
+	}
+	return portRange{low: defaultEphemeralLow, high: defaultEphemeralHigh}
+}
+
+// portTraffic 是一个进程从某个本端端口发出的流量
+type portTraffic struct {
+	internal uint64
+	external uint64
+}
+
+// PortStats 存储从同一个本端端口发出的流量汇总，即该端口上的服务对外提供的流量
+type PortStats struct {
+	Port          uint16 `json:"port"`
+	TotalBytes    uint64 `json:"total_bytes"`
+	InternalBytes uint64 `json:"internal_bytes"`
+	ExternalBytes uint64 `json:"external_bytes"`
+	ProcessCount  int    `json:"process_count"`
+}
+
+// trackLocalPort 将事件的流量计入其本端端口，非 TCP/UDP 的事件（端口为 0）不计入
+func (s *ProcessStats) trackLocalPort(port uint16, internal bool, n uint64) {
+	if port == 0 {
+		return
+	}
+	if s.localPorts == nil {
+		s.localPorts = make(map[uint16]*portTraffic)
+	}
+	p, ok := s.localPorts[port]
+	if !ok {
+		if len(s.localPorts) >= maxTrackedLocalPorts {
+			return
+		}
+		p = &portTraffic{}
+		s.localPorts[port] = p
+	}
+	if internal {
+		p.internal += n
+	} else {
+		p.external += n
+	}
+}
+
+// GetStatsByLocalPort 返回按本端端口汇总的流量状态，临时端口范围内的客户端连接不计入
+func (m *Manager) GetStatsByLocalPort() []PortStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byPort := make(map[uint16]*PortStats)
+	for _, stats := range m.trafficStates {
+		for port, t := range stats.localPorts {
+			p, ok := byPort[port]
+			if !ok {
+				p = &PortStats{Port: port}
+				byPort[port] = p
+			}
+			p.InternalBytes += t.internal
+			p.ExternalBytes += t.external
+			p.TotalBytes += t.internal + t.external
+			p.ProcessCount++
+		}
+	}
+
+	portStats := make([]PortStats, 0, len(byPort))
+	for _, p := range byPort {
+		portStats = append(portStats, *p)
+	}
+	return portStats
+}
@@ -0,0 +1,45 @@
+// internal/state/ports_test.go
+package state
+
+import (
+	"testing"
+
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/config"
+)
+
+func TestGetStatsByLocalPort(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Rules.InternalCIDRs = []string{"10.0.0.0/8"}
+	m := newTestManager(t, cfg)
+	m.ephemeral = portRange{low: 32768, high: 60999}
+
+	served := func(pid uint32, comm string, n uint64, remote string, local uint16) collector.TrafficEvent {
+		event := xmit(pid, comm, n, remote, 50000)
+		event.LocalPort = local
+		return event
+	}
+	// 两个 nginx worker 在 443 上提供服务，sshd 在 22 上，curl 的本端端口是临时端口
+	m.updateState(served(100, "nginx", 1000, "198.51.100.1", 443))
+	m.updateState(served(101, "nginx", 2000, "198.51.100.2", 443))
+	m.updateState(served(101, "nginx", 500, "10.0.0.7", 443))
+	m.updateState(served(200, "sshd", 300, "10.0.0.8", 22))
+	m.updateState(served(300, "curl", 4000, "198.51.100.3", 41000))
+
+	byPort := make(map[uint16]PortStats)
+	for _, p := range m.GetStatsByLocalPort() {
+		byPort[p.Port] = p
+	}
+	want := map[uint16]PortStats{
+		443: {Port: 443, TotalBytes: 3500, InternalBytes: 500, ExternalBytes: 3000, ProcessCount: 2},
+		22:  {Port: 22, TotalBytes: 300, InternalBytes: 300, ProcessCount: 1},
+	}
+	if len(byPort) != len(want) {
+		t.Errorf("got ports %v, want only 443 and 22 (ephemeral ports are skipped)", byPort)
+	}
+	for port, w := range want {
+		if got := byPort[port]; got != w {
+			t.Errorf("port %d = %+v, want %+v", port, got, w)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// internal/state/rate.go
+package state
+
+import (
+	"math"
+	"time"
+)
+
+// sampleRates 计算每个进程在上一个采样周期内的发送速率，并更新其指数加权移动平均
+func (m *Manager) sampleRates(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stats := range m.trafficStates {
+		stats.sampleRate(now, m.ewmaAlpha)
+	}
+}
+
+// sampleRate 根据自上次采样以来增加的字节数更新速率。
+// 第一个样本直接作为 EWMA 的初始值，之后 EWMA = alpha*rate + (1-alpha)*EWMA，
+// 方差使用同样的平滑系数递推: var = (1-alpha)*(var + alpha*diff²)。
+// 更新前的均值和标准差保存为基线，供突增检测与最新样本比较
+func (s *ProcessStats) sampleRate(now time.Time, alpha float64) {
+	elapsed := now.Sub(s.lastSampleAt).Seconds()
+	if elapsed < 0 {
+		// 时钟倒退：丢弃这个周期，以当前时间作为新的采样起点
+		s.lastSampleAt = now
+		s.lastSampleBytes = s.TotalBytes
+		return
+	}
+	if elapsed == 0 {
+		return
+	}
+
+	s.DeltaBytes = s.TotalBytes - s.lastSampleBytes
+	s.RateBps = float64(s.DeltaBytes) / elapsed
+	if s.RateSamples == 0 {
+		s.EWMARateBps = s.RateBps
+	} else {
+		s.BaselineRateBps = s.EWMARateBps
+		s.BaselineStdDevBps = math.Sqrt(s.rateVariance)
+
+		diff := s.RateBps - s.EWMARateBps
+		s.EWMARateBps += alpha * diff
+		s.rateVariance = (1 - alpha) * (s.rateVariance + alpha*diff*diff)
+	}
+
+	s.RateSamples++
+	s.lastSampleAt = now
+	s.lastSampleBytes = s.TotalBytes
+}
@@ -0,0 +1,104 @@
+// internal/state/rate_test.go
+package state
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestEWMASmoothsBursts(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Rules.EWMAAlpha = 0.5
+	m := newTestManager(t, cfg)
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+
+	// 每秒一个样本: 稳定在 1000 B/s，第四秒突增到 11000 B/s 后恢复
+	series := []uint64{1000, 1000, 1000, 11000, 1000, 1000}
+	want := []float64{1000, 1000, 1000, 6000, 3500, 2250}
+	for i, n := range series {
+		m.updateState(xmit(100, "backup", n, "198.51.100.1", 443))
+		now = now.Add(time.Second)
+		m.sampleRates(now)
+
+		s := mustStats(t, m, 100)
+		if s.RateBps != float64(n) {
+			t.Errorf("sample %d: RateBps = %v, want %d", i, s.RateBps, n)
+		}
+		if math.Abs(s.EWMARateBps-want[i]) > 1e-9 {
+			t.Errorf("sample %d: EWMARateBps = %v, want %v", i, s.EWMARateBps, want[i])
+		}
+	}
+
+	// 突增样本与突增前的基线比较，基线不包含突增本身
+	s := mustStats(t, m, 100)
+	if s.RateSamples != len(series) {
+		t.Errorf("RateSamples = %d, want %d", s.RateSamples, len(series))
+	}
+	if s.EWMARateBps >= s.BaselineRateBps {
+		t.Errorf("EWMA %v did not decay below the previous baseline %v after the burst", s.EWMARateBps, s.BaselineRateBps)
+	}
+}
+
+func TestRateAfterBackwardClockJump(t *testing.T) {
+	m := newTestManager(t, nil)
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+
+	m.updateState(xmit(100, "curl", 1000, "198.51.100.1", 443))
+	now = now.Add(time.Second)
+	m.sampleRates(now)
+
+	// 倒退的周期被丢弃，不会产生负的或巨大的速率
+	m.updateState(xmit(100, "curl", 5000, "198.51.100.1", 443))
+	now = now.Add(-time.Hour)
+	m.sampleRates(now)
+	s := mustStats(t, m, 100)
+	if s.RateSamples != 1 || s.RateBps != 1000 {
+		t.Fatalf("after the jump: %d samples, rate %v; want the sample discarded", s.RateSamples, s.RateBps)
+	}
+
+	// 之后的周期从倒退后的时间开始计算，倒退前发送的字节不再计入
+	m.updateState(xmit(100, "curl", 2000, "198.51.100.1", 443))
+	now = now.Add(2 * time.Second)
+	m.sampleRates(now)
+	if s := mustStats(t, m, 100); s.RateSamples != 2 || s.RateBps != 1000 {
+		t.Errorf("after recovering: %d samples, rate %v; want 2 samples at 1000 B/s", s.RateSamples, s.RateBps)
+	}
+}
+
+func TestDeltaBytesAcrossTicks(t *testing.T) {
+	m := newTestManager(t, nil)
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+
+	ticks := []struct {
+		sent      []uint64
+		elapsed   time.Duration
+		wantDelta uint64
+		wantTotal uint64
+	}{
+		{[]uint64{1000, 500}, 10 * time.Second, 1500, 1500},
+		{[]uint64{3000}, 20 * time.Second, 3000, 4500},
+		// 空闲的周期增量为零，累计值不变
+		{nil, 10 * time.Second, 0, 4500},
+	}
+	for i, tick := range ticks {
+		for _, n := range tick.sent {
+			m.updateState(xmit(100, "curl", n, "198.51.100.1", 443))
+		}
+		now = now.Add(tick.elapsed)
+		m.sampleRates(now)
+
+		s := mustStats(t, m, 100)
+		if s.DeltaBytes != tick.wantDelta || s.TotalBytes != tick.wantTotal {
+			t.Errorf("tick %d: DeltaBytes = %d, TotalBytes = %d; want %d, %d", i, s.DeltaBytes, s.TotalBytes, tick.wantDelta, tick.wantTotal)
+		}
+		if want := float64(tick.wantDelta) / tick.elapsed.Seconds(); s.RateBps != want {
+			t.Errorf("tick %d: RateBps = %v, want %v", i, s.RateBps, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// internal/state/subnets.go
+package state
+
+import (
+	"cmp"
+	"slices"
+
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/match"
+)
+
+// subnetRule 是一条 cidr 规则统计的目的网段
+type subnetRule struct {
+	name     string
+	cidrs    []string
+	prefixes match.PrefixSet
+}
+
+// compileSubnets 解析 cidr 规则的目的网段，按规则名称排序。无效的网段在配置校验时已经报告，这里跳过
+func compileSubnets(rules map[string][]string) []subnetRule {
+	subnets := make([]subnetRule, 0, len(rules))
+	for name, cidrs := range rules {
+		prefixes, err := match.ParsePrefixes(cidrs)
+		if err != nil {
+			continue
+		}
+		subnets = append(subnets, subnetRule{name: name, cidrs: cidrs, prefixes: prefixes})
+	}
+	slices.SortFunc(subnets, func(a, b subnetRule) int {
+		return cmp.Compare(a.name, b.name)
+	})
+	return subnets
+}
+
+// SubnetStats 存储所有进程发往一条 cidr 规则的目的网段的流量汇总
+type SubnetStats struct {
+	// Rule 是 cidr 规则的名称，CIDRs 是它统计的目的网段
+	Rule         string   `json:"rule"`
+	CIDRs        []string `json:"cidrs"`
+	TotalBytes   uint64   `json:"total_bytes"`
+	ProcessCount int      `json:"process_count"`
+}
+
+// trackSubnets 将事件的流量计入对端地址所在的每一条 cidr 规则，无法解析对端地址的事件不计入
+func (s *ProcessStats) trackSubnets(subnets []subnetRule, event *collector.TrafficEvent) {
+	if len(subnets) == 0 {
+		return
+	}
+	remote := event.Remote()
+	if !remote.IsValid() {
+		return
+	}
+	for _, r := range subnets {
+		if !r.prefixes.Contains(remote) {
+			continue
+		}
+		if s.subnets == nil {
+			s.subnets = make(map[string]uint64, len(subnets))
+		}
+		s.subnets[r.name] += event.Len
+	}
+}
+
+// GetStatsBySubnet 返回每条 cidr 规则的目的网段上的流量汇总，没有流量的规则也会返回
+func (m *Manager) GetStatsBySubnet() []SubnetStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subnetStats := make([]SubnetStats, len(m.subnets))
+	for i, r := range m.subnets {
+		subnetStats[i] = SubnetStats{Rule: r.name, CIDRs: r.cidrs}
+		for _, stats := range m.trafficStates {
+			if n := stats.subnets[r.name]; n > 0 {
+				subnetStats[i].TotalBytes += n
+				subnetStats[i].ProcessCount++
+			}
+		}
+	}
+	return subnetStats
+}
@@ -0,0 +1,37 @@
+// internal/state/users.go
+package state
+
+import (
+	"os/user"
+	"strconv"
+	"sync"
+)
+
+// userCache 缓存 UID 到用户名的解析结果，避免重复读取 /etc/passwd
+type userCache struct {
+	mu    sync.Mutex
+	names map[uint32]string
+}
+
+// newUserCache 创建一个新的用户名缓存
+func newUserCache() *userCache {
+	return &userCache{names: make(map[uint32]string)}
+}
+
+// lookup 将 UID 解析为用户名，无法解析时返回 UID 的字符串形式
+func (c *userCache) lookup(uid uint32) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name, ok := c.names[uid]; ok {
+		return name
+	}
+
+	id := strconv.FormatUint(uint64(uid), 10)
+	name := id
+	if u, err := user.LookupId(id); err == nil {
+		name = u.Username
+	}
+	c.names[uid] = name
+	return name
+}
@@ -0,0 +1,103 @@
+// internal/state/window.go
+package state
+
+import "time"
+
+// windowBuckets 是每个滑动窗口划分的分桶数量，窗口的精度为窗口长度 / windowBuckets
+const windowBuckets = 60
+
+// WindowTraffic 是进程在一个命名滑动窗口内发送的流量，键见 config.RuleDefinition.GetWindowName
+type WindowTraffic struct {
+	InternalBytes uint64 `json:"internal_bytes"`
+	ExternalBytes uint64 `json:"external_bytes"`
+}
+
+// TotalBytes 返回窗口内的全部流量
+func (w WindowTraffic) TotalBytes() uint64 {
+	return w.InternalBytes + w.ExternalBytes
+}
+
+// slidingWindow 按固定宽度的分桶累计最近一个窗口长度内的流量，
+// 每个进程、每个窗口各有一个，由 Manager.mu 保护
+type slidingWindow struct {
+	width   time.Duration
+	buckets [windowBuckets]WindowTraffic
+	// head 是最新分桶的下标，headStart 是它的起始时间
+	head      int
+	headStart time.Time
+}
+
+// newSlidingWindow 创建一个长度为 window 的滑动窗口，起点为 now
+func newSlidingWindow(window time.Duration, now time.Time) *slidingWindow {
+	width := window / windowBuckets
+	if width <= 0 {
+		width = time.Nanosecond
+	}
+	return &slidingWindow{width: width, headStart: now}
+}
+
+// add 将 n 字节计入 now 所在的分桶
+func (w *slidingWindow) add(now time.Time, internal bool, n uint64) {
+	w.advance(now)
+	if internal {
+		w.buckets[w.head].InternalBytes += n
+	} else {
+		w.buckets[w.head].ExternalBytes += n
+	}
+}
+
+// advance 将最新分桶移动到 now 所在的位置，并清空移出窗口的分桶。
+// 时钟倒退时计入当前的最新分桶
+func (w *slidingWindow) advance(now time.Time) {
+	steps := w.elapsedBuckets(now)
+	if steps == 0 {
+		return
+	}
+	for i := int64(0); i < min(steps, windowBuckets); i++ {
+		w.head = (w.head + 1) % windowBuckets
+		w.buckets[w.head] = WindowTraffic{}
+	}
+	w.headStart = w.headStart.Add(time.Duration(steps) * w.width)
+}
+
+// sum 返回截至 now 仍在窗口内的流量。它不修改分桶，因此可以在读锁下调用
+func (w *slidingWindow) sum(now time.Time) WindowTraffic {
+	steps := w.elapsedBuckets(now)
+	var total WindowTraffic
+	if steps >= windowBuckets {
+		return total
+	}
+	for age := 0; age+int(steps) < windowBuckets; age++ {
+		b := w.buckets[(w.head-age+windowBuckets)%windowBuckets]
+		total.InternalBytes += b.InternalBytes
+		total.ExternalBytes += b.ExternalBytes
+	}
+	return total
+}
+
+// elapsedBuckets 返回从最新分桶到 now 经过的分桶数量，时钟倒退时为 0
+func (w *slidingWindow) elapsedBuckets(now time.Time) int64 {
+	elapsed := now.Sub(w.headStart)
+	if elapsed < w.width {
+		return 0
+	}
+	return int64(elapsed / w.width)
+}
+
+// trackWindows 将事件计入进程的每个滑动窗口
+func (s *ProcessStats) trackWindows(windows map[string]time.Duration, now time.Time, internal bool, n uint64) {
+	if len(windows) == 0 {
+		return
+	}
+	if s.windows == nil {
+		s.windows = make(map[string]*slidingWindow, len(windows))
+	}
+	for name, length := range windows {
+		w, ok := s.windows[name]
+		if !ok {
+			w = newSlidingWindow(length, now)
+			s.windows[name] = w
+		}
+		w.add(now, internal, n)
+	}
+}
@@ -0,0 +1,46 @@
+// internal/state/window_test.go
+package state
+
+import (
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/config"
+)
+
+func TestNamedWindows(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Rules.Definitions = []config.RuleDefinition{
+		{Name: "burst", Type: config.RuleTypeTraffic, WindowMinutes: 1},
+		{Name: "hourly", Type: config.RuleTypeTraffic, WindowMinutes: 60},
+	}
+	m := newTestManager(t, cfg)
+	now := time.Unix(1700000000, 0)
+	m.now = func() time.Time { return now }
+
+	m.updateState(xmit(100, "rsync", 1000, "198.51.100.1", 443))
+	now = now.Add(2 * time.Minute)
+	m.updateState(xmit(100, "rsync", 300, "198.51.100.1", 443))
+
+	// 两分钟前的流量已移出 1 分钟窗口，但仍在 60 分钟窗口内
+	s := mustStats(t, m, 100)
+	if got := s.Windows["1m"].TotalBytes(); got != 300 {
+		t.Errorf("1m window = %d bytes, want 300", got)
+	}
+	if got := s.Windows["60m"].TotalBytes(); got != 1300 {
+		t.Errorf("60m window = %d bytes, want 1300", got)
+	}
+
+	// 读取时也按当前时间计算，无需新的事件推动分桶
+	now = now.Add(time.Hour)
+	s = mustStats(t, m, 100)
+	if got := s.Windows["1m"].TotalBytes(); got != 0 {
+		t.Errorf("1m window an hour later = %d bytes, want 0", got)
+	}
+	if got := s.Windows["60m"].TotalBytes(); got != 0 {
+		t.Errorf("60m window an hour later = %d bytes, want 0", got)
+	}
+	if s.TotalBytes != 1300 {
+		t.Errorf("TotalBytes = %d, want 1300", s.TotalBytes)
+	}
+}
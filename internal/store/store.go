@@ -0,0 +1,145 @@
+// internal/store/store.go
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	_ "modernc.org/sqlite" // 纯 Go 实现的 sqlite 驱动，无需 cgo
+
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/state"
+)
+
+// cleanupInterval 是删除过期样本的间隔
+const cleanupInterval = time.Hour
+
+// schema 创建样本表。ts 是 Unix 时间戳（秒），便于用 sqlite3 直接查询，例如:
+//
+//	SELECT max(total_bytes) FROM process_samples
+//	WHERE comm = 'nginx' AND ts BETWEEN strftime('%s', '2024-01-01 14:00') AND strftime('%s', '2024-01-01 16:00');
+const schema = `
+CREATE TABLE IF NOT EXISTS process_samples (
+	ts               INTEGER NOT NULL,
+	pid              INTEGER NOT NULL,
+	comm             TEXT    NOT NULL,
+	uid              INTEGER NOT NULL,
+	username         TEXT    NOT NULL,
+	total_bytes      INTEGER NOT NULL,
+	rate_bps         REAL    NOT NULL,
+	ewma_rate_bps    REAL    NOT NULL,
+	connection_count INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS process_samples_ts ON process_samples (ts);
+CREATE INDEX IF NOT EXISTS process_samples_comm_ts ON process_samples (comm, ts);
+`
+
+// Store 定期将每个进程的流量状态快照写入本地 sqlite 数据库，用于历史查询
+type Store struct {
+	log          *slog.Logger
+	db           *sql.DB
+	stateManager *state.Manager
+	interval     time.Duration
+	retention    time.Duration
+}
+
+// Open 打开（必要时创建）配置中的数据库
+func Open(log *slog.Logger, cfg config.History, stateManager *state.Manager) (*Store, error) {
+	db, err := sql.Open("sqlite", cfg.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	// sqlite 同一时间只允许一个写入者
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history schema: %w", err)
+	}
+
+	return &Store{
+		log:          log,
+		db:           db,
+		stateManager: stateManager,
+		interval:     cfg.GetSampleInterval(),
+		retention:    cfg.GetRetention(),
+	}, nil
+}
+
+// Start 启动采样和清理循环，上下文取消时写入最后一次快照并关闭数据库
+func (s *Store) Start(ctx context.Context) {
+	s.log.Info("Starting history store", "interval", s.interval, "retention", s.retention)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	cleanupTicker := time.NewTicker(cleanupInterval)
+	defer cleanupTicker.Stop()
+
+	s.cleanup(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			s.record(time.Now())
+			if err := s.db.Close(); err != nil {
+				s.log.Error("Failed to close history database", "error", err)
+			}
+			s.log.Info("History store stopped")
+			return
+		case now := <-ticker.C:
+			s.record(now)
+		case now := <-cleanupTicker.C:
+			s.cleanup(now)
+		}
+	}
+}
+
+// record 在一个事务中写入所有进程的当前状态
+func (s *Store) record(now time.Time) {
+	stats := s.stateManager.GetStats()
+	if len(stats) == 0 {
+		return
+	}
+	if err := s.insert(now, stats); err != nil {
+		s.log.Error("Failed to write history samples", "error", err)
+		return
+	}
+	s.log.Debug("Wrote history samples", "count", len(stats))
+}
+
+// insert 写入一组进程状态
+func (s *Store) insert(now time.Time, stats []state.ProcessStats) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO process_samples
+		(ts, pid, comm, uid, username, total_bytes, rate_bps, ewma_rate_bps, connection_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	ts := now.Unix()
+	for _, p := range stats {
+		if _, err := stmt.Exec(ts, p.PID, p.Comm, p.UID, p.Username, int64(p.TotalBytes), p.RateBps, p.EWMARateBps, p.ConnectionCount); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// cleanup 删除超过保留期限的样本
+func (s *Store) cleanup(now time.Time) {
+	res, err := s.db.Exec(`DELETE FROM process_samples WHERE ts < ?`, now.Add(-s.retention).Unix())
+	if err != nil {
+		s.log.Error("Failed to delete expired history samples", "error", err)
+		return
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		s.log.Debug("Deleted expired history samples", "count", n)
+	}
+}
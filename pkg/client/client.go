@@ -0,0 +1,170 @@
+// pkg/client/client.go
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"traffic-guardian/internal/api"
+	"traffic-guardian/internal/state"
+)
+
+// ProcessStats 是单个进程的流量信息，与守护进程共用同一类型
+type ProcessStats = state.ProcessStats
+
+// Destination 是进程发送过流量的一个对端
+type Destination = state.Destination
+
+// ResetResponse 是重置进程计数器接口的返回结构
+type ResetResponse = api.ResetResponse
+
+// ErrNotFound 表示请求的资源（例如某个 PID）不存在
+var ErrNotFound = errors.New("not found")
+
+// APIError 表示 API 返回了非 2xx 的状态码
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error 实现了 error 接口
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("traffic-guardian API returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("traffic-guardian API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is 使 errors.Is(err, ErrNotFound) 对 404 响应成立
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Client 是 traffic-guardian 控制 API 的类型化客户端
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Option 用于定制 Client
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义的 http.Client 发送请求
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// New 创建一个新的 Client，baseURL 形如 "http://127.0.0.1:9090"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Stats 返回所有进程的流量状态
+func (c *Client) Stats(ctx context.Context) ([]ProcessStats, error) {
+	var stats []ProcessStats
+	if err := c.do(ctx, http.MethodGet, "/stats", &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Stat 返回指定进程的流量状态，进程不存在时返回的错误满足 errors.Is(err, ErrNotFound)
+func (c *Client) Stat(ctx context.Context, pid uint32) (ProcessStats, error) {
+	var stats ProcessStats
+	if err := c.do(ctx, http.MethodGet, "/stats/"+strconv.FormatUint(uint64(pid), 10), &stats); err != nil {
+		return ProcessStats{}, err
+	}
+	return stats, nil
+}
+
+// Destinations 返回指定进程发送字节数最多的 limit 个对端，limit 为 0 时使用服务端的默认值。
+// 进程不存在时返回的错误满足 errors.Is(err, ErrNotFound)
+func (c *Client) Destinations(ctx context.Context, pid uint32, limit int) ([]Destination, error) {
+	path := "/stats/" + strconv.FormatUint(uint64(pid), 10) + "/destinations"
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var dests []Destination
+	if err := c.do(ctx, http.MethodGet, path, &dests); err != nil {
+		return nil, err
+	}
+	return dests, nil
+}
+
+// Reset 清除指定进程的流量计数和警报冷却记录，返回该进程此前是否存在
+func (c *Client) Reset(ctx context.Context, pid uint32) (bool, error) {
+	var resp ResetResponse
+	if err := c.do(ctx, http.MethodPost, "/stats/"+strconv.FormatUint(uint64(pid), 10)+"/reset", &resp); err != nil {
+		return false, err
+	}
+	return resp.Existed, nil
+}
+
+// Pause 暂停发送警报，流量采集和规则检查照常进行
+func (c *Client) Pause(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/pause", nil)
+}
+
+// Resume 恢复发送警报
+func (c *Client) Resume(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/resume", nil)
+}
+
+// Reload 请求守护进程重新加载配置
+func (c *Client) Reload(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/reload", nil)
+}
+
+// do 发送请求，并将 JSON 响应解析到 out 中（out 为 nil 时丢弃响应体）
+func (c *Client) do(ctx context.Context, method, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if data, err := io.ReadAll(io.LimitReader(resp.Body, 4096)); err == nil {
+			if json.Unmarshal(data, &body) == nil {
+				apiErr.Message = body.Error
+			} else {
+				apiErr.Message = strings.TrimSpace(string(data))
+			}
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
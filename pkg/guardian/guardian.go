@@ -0,0 +1,677 @@
+// pkg/guardian/guardian.go
+package guardian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"traffic-guardian/internal/alerter"
+	"traffic-guardian/internal/api"
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/engine"
+	"traffic-guardian/internal/logging"
+	"traffic-guardian/internal/metrics"
+	"traffic-guardian/internal/sdnotify"
+	"traffic-guardian/internal/state"
+	"traffic-guardian/internal/store"
+)
+
+// alertFlushTimeout 是退出时发送剩余警报的最长时间
+const alertFlushTimeout = 10 * time.Second
+
+// metricsPushTimeout 是退出时推送指标到 Pushgateway 的最长时间
+const metricsPushTimeout = 10 * time.Second
+
+// Alert 是规则引擎产生的警报
+type Alert = alerter.Alert
+
+// Config 是 traffic-guardian 的完整配置，导出为别名以便外部程序构造
+type Config = config.Config
+
+// LoadConfig 从指定路径读取并解析 YAML 配置文件
+func LoadConfig(path string) (*Config, error) {
+	return config.LoadConfig(path)
+}
+
+// LoadConfigs 按顺序读取并深度合并多个 YAML 配置文件，后面的文件优先，例如基础配置加环境覆盖
+func LoadConfigs(paths []string) (*Config, error) {
+	return config.LoadConfigs(paths)
+}
+
+// DefaultConfig 返回没有配置文件时使用的基础配置，所有警报器都不启用
+func DefaultConfig() *Config {
+	return config.DefaultConfig()
+}
+
+// OpenLogOutput 根据 log_output 打开日志的输出目标，调用方负责在退出时关闭
+func OpenLogOutput(cfg *Config) (io.WriteCloser, error) {
+	return logging.Open(cfg)
+}
+
+// FormatBytes 将字节数格式化为与警报消息相同的带单位字符串
+func FormatBytes(b uint64) string {
+	return alerter.FormatBytes(b)
+}
+
+// ReopenLogOutput 重新打开 OpenLogOutput 返回的日志文件，用于配合 logrotate；其他输出目标什么都不做
+func ReopenLogOutput(w io.Writer) error {
+	return logging.Reopen(w)
+}
+
+// ProcessStats 是单个进程的流量状态
+type ProcessStats = state.ProcessStats
+
+// TrafficEvent 是采集器产生的单个流量事件
+type TrafficEvent = collector.TrafficEvent
+
+// Source 是流量事件来源的抽象
+type Source = collector.Source
+
+// SourceFactory 根据事件 channel 创建一个事件来源
+type SourceFactory func(eventsChan chan<- TrafficEvent) Source
+
+// ReplayFile 返回一个尽快回放录制文件的 SourceFactory，录制文件由 collector.record_path 生成
+func ReplayFile(path string) SourceFactory {
+	return ReplayFileAtSpeed(path, 0)
+}
+
+// ReplayFileAtSpeed 返回一个按录制时的节奏回放录制文件的 SourceFactory，speed 为倍速（1 为原速），
+// 不大于 0 时与 ReplayFile 相同
+func ReplayFileAtSpeed(path string, speed float64) SourceFactory {
+	return func(eventsChan chan<- TrafficEvent) Source {
+		return collector.NewFileSource(path, eventsChan, speed)
+	}
+}
+
+// Option 用于定制 Guardian 的构建过程
+type Option func(*Guardian)
+
+// WithConfigFiles 记录配置来源的文件，Reload 按相同的顺序重新读取并合并它们
+func WithConfigFiles(paths []string) Option {
+	return func(g *Guardian) {
+		g.configFiles = paths
+	}
+}
+
+// WithSource 使用自定义的事件来源替代默认的 eBPF 采集器
+func WithSource(factory SourceFactory) Option {
+	return func(g *Guardian) {
+		g.sourceFactory = factory
+	}
+}
+
+// Guardian 负责构建并监管所有组件：采集器、状态管理器、规则引擎、警报器和控制 API
+type Guardian struct {
+	log *slog.Logger
+	cfg *Config
+
+	trafficEventsChan chan collector.TrafficEvent
+	alertsChan        chan alerter.Alert
+
+	stateManager *state.Manager
+	ruleEngine   *engine.Engine
+	router       *alerter.Router
+	history      *alerter.History
+	resolver     *alerter.Resolver
+	// telegram 用于接收警报消息上确认按钮的点击
+	telegram  *alerter.TelegramAlerter
+	metrics   *metrics.Metrics
+	source    Source
+	apiServer *api.Server
+	store     *store.Store
+	// auditLog 是规则评估审计日志的输出，在引擎停止后关闭
+	auditLog io.WriteCloser
+	// collected 在 RunOnce 期间收集发出的所有警报，不受历史记录容量的限制；其他时候为 nil。
+	// 只在警报处理 goroutine 中写入，RunOnce 在它退出后读取
+	collected *[]Alert
+
+	sourceFactory SourceFactory
+
+	// configFiles 是 Reload 重新读取的配置文件，reloadMu 保证同一时间只有一次重新加载。
+	// running 是当前生效的配置，即 cfg 加上重新加载时应用的规则和进程白名单，受 reloadMu 保护
+	configFiles []string
+	reloadMu    sync.Mutex
+	running     *Config
+}
+
+// New 根据配置创建一个新的 Guardian 实例，日志输出使用 slog 的默认 Logger
+func New(cfg *Config, opts ...Option) (*Guardian, error) {
+	if cfg == nil {
+		return nil, errors.New("guardian: config is nil")
+	}
+
+	logger := slog.Default()
+	g := &Guardian{
+		log:     logger,
+		cfg:     cfg,
+		running: cfg,
+		// 创建用于数据流转的 channels
+		trafficEventsChan: make(chan collector.TrafficEvent, 100),
+		alertsChan:        make(chan alerter.Alert, 10),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	// 创建状态管理器
+	g.stateManager = state.NewManager(logger.With("module", "state"), cfg)
+
+	// 创建规则引擎
+	g.ruleEngine = engine.NewEngine(logger.With("module", "engine"), cfg, g.stateManager, g.alertsChan)
+	if cfg.Rules.AuditLog != "" {
+		w, err := logging.OpenOutput(cfg.Rules.AuditLog, cfg.GetLogMaxSizeBytes(), cfg.GetLogMaxBackups())
+		if err != nil {
+			return nil, fmt.Errorf("rules.audit_log: %w", err)
+		}
+		g.auditLog = w
+		g.ruleEngine.SetAuditLog(w)
+		logger.Info("Rule evaluation audit log is enabled", "output", cfg.Rules.AuditLog)
+	}
+
+	// 创建并注册警报器
+	g.history = alerter.NewHistory(cfg.Alerter.HistorySize)
+	g.router = alerter.NewRouter(cfg.Alerter.Routing)
+	if cfg.Alerter.ReverseDNS.Enabled {
+		g.resolver = alerter.NewResolver(cfg.Alerter.ReverseDNS, nil)
+	}
+	telegramAlerter := alerter.NewTelegramAlerter(logger.With("module", "alerter-telegram"), cfg.Alerter.Telegram, cfg.Alerter.Timezone, cfg.Alerter.GetLocale())
+	g.telegram = telegramAlerter
+	if telegramAlerter.IsEnabled() {
+		logger.Info("Telegram alerter is enabled")
+		g.router.Register(telegramAlerter)
+	} else {
+		logger.Info("Telegram alerter is disabled")
+	}
+	execAlerter := alerter.NewExecAlerter(logger.With("module", "alerter-exec"), cfg.Alerter.Exec, cfg.Alerter.GetLocale())
+	if execAlerter.IsEnabled() {
+		logger.Info("Exec alerter is enabled", "command", cfg.Alerter.Exec.Command)
+		g.router.Register(execAlerter)
+	}
+	journalAlerter := alerter.NewJournalAlerter(logger.With("module", "alerter-journal"), cfg.Alerter.Journal, cfg.Alerter.GetLocale())
+	if journalAlerter.IsEnabled() {
+		logger.Info("Journal alerter is enabled", "socket", cfg.Alerter.Journal.GetSocketPath())
+		g.router.Register(journalAlerter)
+	}
+	fileAlerter := alerter.NewFileAlerter(logger.With("module", "alerter-file"), cfg.Alerter.File)
+	if fileAlerter.IsEnabled() {
+		logger.Info("File alerter is enabled", "path", cfg.Alerter.File.Path)
+		g.router.Register(fileAlerter)
+	}
+	for _, name := range g.router.UnknownRoutes() {
+		logger.Warn("Alert routing references an alerter that is not enabled", "alerter", name)
+	}
+	for _, d := range cfg.Rules.GetDefinitions() {
+		for _, name := range d.Alerters {
+			if !g.router.Has(name) {
+				logger.Warn("Rule references an alerter that is not enabled", "rule", d.Name, "alerter", name)
+			}
+		}
+	}
+	for _, name := range cfg.Rules.ExitReport.Alerters {
+		if !g.router.Has(name) {
+			logger.Warn("Exit report references an alerter that is not enabled", "alerter", name)
+		}
+	}
+	for _, name := range cfg.Rules.Digest.Alerters {
+		if !g.router.Has(name) {
+			logger.Warn("Digest references an alerter that is not enabled", "alerter", name)
+		}
+	}
+
+	// 启动自检：检查警报器的连通性和凭据
+	if cfg.Alerter.ValidateOnStartup {
+		if err := g.validateAlerters(); err != nil && cfg.Alerter.AbortOnInvalid {
+			return nil, err
+		}
+	}
+
+	// 创建事件来源，默认为 eBPF 采集器
+	if g.sourceFactory != nil {
+		g.source = g.sourceFactory(g.trafficEventsChan)
+	} else {
+		g.source = collector.New(logger.With("module", "collector"), cfg.Collector, g.trafficEventsChan)
+	}
+
+	// 注册内部指标
+	g.metrics = metrics.New()
+	g.metrics.RegisterChannel("events",
+		func() int { return len(g.trafficEventsChan) },
+		func() int { return cap(g.trafficEventsChan) })
+	g.metrics.RegisterChannel("alerts",
+		func() int { return len(g.alertsChan) },
+		func() int { return cap(g.alertsChan) })
+	g.metrics.RegisterCommBytes(string(config.DirectionTX), cfg.Metrics.GetMaxComms(), func() map[string]uint64 {
+		totals := make(map[string]uint64)
+		for _, c := range g.stateManager.GetStatsByComm() {
+			totals[c.Comm] = c.TotalBytes
+		}
+		return totals
+	})
+	if c, ok := g.source.(*collector.Collector); ok {
+		g.metrics.RegisterShortRecords(c.ShortRecords)
+		g.metrics.RegisterLostSamples(c.LostSamples)
+	}
+
+	// 打开历史数据库
+	if cfg.History.IsEnabled() {
+		s, err := store.Open(logger.With("module", "store"), cfg.History, g.stateManager)
+		if err != nil {
+			return nil, err
+		}
+		g.store = s
+	}
+
+	// 创建控制 API
+	g.apiServer = api.NewServer(logger.With("module", "api"), cfg, g.stateManager, g.ruleEngine, g.history, g.metrics)
+	g.apiServer.RegisterAlerters(g.router)
+	if c, ok := g.source.(*collector.Collector); ok {
+		g.apiServer.RegisterCollector(c.Attached)
+		g.apiServer.RegisterCollectorStats(c.Stats)
+	}
+	g.apiServer.RegisterReload(g.Reload)
+	g.apiServer.RegisterConfig(g.RunningConfig)
+
+	return g, nil
+}
+
+// alerterValidateTimeout 是启动自检时每个警报器的超时时间
+const alerterValidateTimeout = 10 * time.Second
+
+// validateAlerters 依次检查每个已启用的警报器，记录并返回所有失败
+func (g *Guardian) validateAlerters() error {
+	var errs []error
+	g.router.Each(func(name string, a alerter.Alerter) {
+		ctx, cancel := context.WithTimeout(context.Background(), alerterValidateTimeout)
+		defer cancel()
+
+		if err := a.Validate(ctx); err != nil {
+			g.log.Error("Alerter failed startup self-test", "alerter", name, "error", err)
+			errs = append(errs, fmt.Errorf("alerter %s: %w", name, err))
+			return
+		}
+		g.log.Info("Alerter passed startup self-test", "alerter", name)
+	})
+	return errors.Join(errs...)
+}
+
+// Run 启动所有组件并阻塞，直到上下文被取消（或某个组件启动失败）且所有 goroutine 退出。
+// 退出时按顺序停止各组件：先停止事件来源，再让状态管理器处理完剩余事件，
+// 然后由规则引擎做最后一次检查，最后让警报处理器发送完剩余警报，避免丢失退出前的违规。
+func (g *Guardian) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 记录第一个导致退出的组件错误
+	var (
+		runErr  error
+		errOnce sync.Once
+	)
+	fail := func(err error) {
+		errOnce.Do(func() { runErr = err })
+		cancel()
+	}
+
+	// 各组件使用独立的上下文，以便在退出时逐个停止
+	stateManager := g.launch(ctx, "state", fail, func(ctx context.Context) {
+		g.stateManager.Start(ctx, g.trafficEventsChan)
+	})
+	ruleEngine := g.launch(ctx, "engine", fail, g.ruleEngine.Start)
+	alertProcessor := g.launch(ctx, "alerts", fail, g.processAlerts)
+	channelMonitor := g.launch(ctx, "channel-monitor", fail, g.monitorChannels)
+	source := g.launch(ctx, "source", fail, func(ctx context.Context) {
+		if err := g.source.Start(ctx); err != nil {
+			g.log.Error("Failed to start eBPF collector", "error", err)
+			fail(fmt.Errorf("collector: %w", err)) // 如果采集器启动失败，则取消所有操作
+		}
+	})
+
+	// 在 systemd (Type=notify) 下运行时报告就绪状态并发送看门狗心跳
+	var systemdNotifier *component
+	if sdnotify.Enabled() {
+		systemdNotifier = g.launch(ctx, "sd-notify", fail, g.notifySystemd)
+	}
+
+	// 确认事件确实在流动，结果只记录日志
+	var selfCheck *component
+	if g.cfg.Collector.SelfCheck.Enabled {
+		selfCheck = g.launch(ctx, "self-check", fail, g.selfCheck)
+	}
+
+	// 接收 Telegram 警报消息上确认按钮的点击
+	var telegramAcks *component
+	if g.telegram.AckEnabled() {
+		telegramAcks = g.launch(ctx, "telegram-ack", fail, func(ctx context.Context) {
+			g.telegram.ListenAcks(ctx, g.ruleEngine.Acknowledge)
+		})
+	}
+
+	// 启动历史数据库的采样
+	var historyStore *component
+	if g.store != nil {
+		historyStore = g.launch(ctx, "store", fail, g.store.Start)
+	}
+
+	// 启动控制 API
+	var apiServer *component
+	if g.apiServer.IsEnabled() {
+		apiServer = g.launch(ctx, "api", fail, func(ctx context.Context) {
+			if err := g.apiServer.Start(ctx); err != nil {
+				g.log.Error("Failed to start API server", "error", err)
+				fail(fmt.Errorf("api: %w", err))
+			}
+		})
+	} else {
+		g.log.Info("API server is disabled")
+	}
+
+	g.log.Info("Traffic Guardian is running")
+	<-runCtx.Done()
+
+	// 按数据流向依次停止各组件
+	st := g.newStopper()
+	if systemdNotifier != nil {
+		st.stop(systemdNotifier)
+		g.sdNotify(sdnotify.Stopping)
+	}
+	if selfCheck != nil {
+		st.stop(selfCheck)
+	}
+	if telegramAcks != nil {
+		st.stop(telegramAcks)
+	}
+	g.log.Info("Stopping event source...")
+	st.stop(source)
+	g.log.Info("Draining pending events...")
+	st.stop(stateManager)
+	g.log.Info("Running final rule check...")
+	st.stop(ruleEngine)
+	g.closeAuditLog()
+	g.log.Info("Flushing pending alerts...")
+	st.stop(alertProcessor)
+	st.stop(channelMonitor)
+	if historyStore != nil {
+		st.stop(historyStore)
+	}
+	if apiServer != nil {
+		st.stop(apiServer)
+	}
+	g.pushMetrics()
+	return errors.Join(runErr, st.err())
+}
+
+// RunOnce 采集 duration 时长的流量后执行一次规则检查，发送产生的警报并返回它们。
+// 适用于 cron 等周期性检查的场景；如果 ctx 提前被取消，会立即使用已采集的数据进行检查。
+func (g *Guardian) RunOnce(ctx context.Context, duration time.Duration) ([]Alert, error) {
+	runCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var alerts []Alert
+	g.collected = &alerts
+	defer func() { g.collected = nil }()
+
+	var (
+		runErr  error
+		errOnce sync.Once
+	)
+	fail := func(err error) {
+		errOnce.Do(func() { runErr = err })
+		cancel()
+	}
+
+	stateManager := g.launch(ctx, "state", fail, func(ctx context.Context) {
+		g.stateManager.Start(ctx, g.trafficEventsChan)
+	})
+	alertProcessor := g.launch(ctx, "alerts", fail, g.processAlerts)
+	source := g.launch(ctx, "source", fail, func(ctx context.Context) {
+		if err := g.source.Start(ctx); err != nil {
+			g.log.Error("Failed to start eBPF collector", "error", err)
+			fail(fmt.Errorf("collector: %w", err))
+		}
+	})
+
+	g.log.Info("Collecting traffic for a single evaluation", "duration", duration)
+	<-runCtx.Done()
+
+	st := g.newStopper()
+	st.stop(source)
+	st.stop(stateManager)
+	if runErr == nil {
+		g.ruleEngine.CheckRules()
+	}
+	g.closeAuditLog()
+	st.stop(alertProcessor)
+	g.pushMetrics()
+
+	if err := errors.Join(runErr, st.err()); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// Watch 只运行事件来源和状态管理器，每隔 interval 以当前所有进程的流量状态调用一次 fn，
+// 直到上下文被取消。不检查规则也不发送警报，用于 -top 等交互式查看
+func (g *Guardian) Watch(ctx context.Context, interval time.Duration, fn func([]ProcessStats)) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		runErr  error
+		errOnce sync.Once
+	)
+	fail := func(err error) {
+		errOnce.Do(func() { runErr = err })
+		cancel()
+	}
+
+	stateManager := g.launch(ctx, "state", fail, func(ctx context.Context) {
+		g.stateManager.Start(ctx, g.trafficEventsChan)
+	})
+	source := g.launch(ctx, "source", fail, func(ctx context.Context) {
+		if err := g.source.Start(ctx); err != nil {
+			g.log.Error("Failed to start eBPF collector", "error", err)
+			fail(fmt.Errorf("collector: %w", err))
+		}
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case <-runCtx.Done():
+			done = true
+		case <-ticker.C:
+			fn(g.stateManager.GetStats())
+		}
+	}
+
+	st := g.newStopper()
+	st.stop(source)
+	st.stop(stateManager)
+	return errors.Join(runErr, st.err())
+}
+
+// closeAuditLog 关闭规则评估审计日志，失败只记录错误
+func (g *Guardian) closeAuditLog() {
+	if g.auditLog == nil {
+		return
+	}
+	if err := g.auditLog.Close(); err != nil {
+		g.log.Error("Failed to close rule audit log", "error", err)
+	}
+	g.auditLog = nil
+}
+
+// Stats 返回当前所有进程流量状态的副本
+func (g *Guardian) Stats() []ProcessStats {
+	return g.stateManager.GetStats()
+}
+
+// pushMetrics 在运行结束时将指标推送到 Pushgateway（如果已配置），失败只记录错误
+func (g *Guardian) pushMetrics() {
+	pg := g.cfg.Metrics.Pushgateway
+	if !pg.IsEnabled() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), metricsPushTimeout)
+	defer cancel()
+	if err := g.metrics.Push(ctx, pg.URL, pg.GetJob()); err != nil {
+		g.log.Error("Failed to push metrics to Pushgateway", "url", pg.URL, "error", err)
+		return
+	}
+	g.log.Info("Pushed metrics to Pushgateway", "url", pg.URL, "job", pg.GetJob())
+}
+
+// channelHighWatermark 是 channel 被视为接近满载的占用比例
+const channelHighWatermark = 0.8
+
+// monitorChannels 每个检查周期采样一次内部 channel 的深度，接近满载时发出警告
+func (g *Guardian) monitorChannels(ctx context.Context) {
+	ticker := time.NewTicker(g.cfg.Rules.GetCheckInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.sampleChannel("events", len(g.trafficEventsChan), cap(g.trafficEventsChan))
+			g.sampleChannel("alerts", len(g.alertsChan), cap(g.alertsChan))
+		}
+	}
+}
+
+// sampleChannel 记录一个 channel 的深度
+func (g *Guardian) sampleChannel(name string, length, capacity int) {
+	if capacity > 0 && float64(length) >= channelHighWatermark*float64(capacity) {
+		g.log.Warn("Channel is near capacity, consider increasing buffers", "channel", name, "len", length, "cap", capacity)
+		return
+	}
+	g.log.Debug("Channel depth", "channel", name, "len", length, "cap", capacity)
+}
+
+// component 是一个在独立上下文中运行、可以单独停止的 goroutine
+type component struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// launch 在一个不随 parent 取消而取消（但保留其中的值）的上下文中运行 run。
+// run 发生 panic 时记录堆栈并通过 fail 触发退出，组件仍会被标记为已退出，保证 stop 不会阻塞
+func (g *Guardian) launch(parent context.Context, name string, fail func(error), run func(ctx context.Context)) *component {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	c := &component{name: name, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		defer func() {
+			if r := recover(); r != nil {
+				g.log.Error("Component panicked", "component", name, "panic", r, "stack", string(debug.Stack()))
+				fail(fmt.Errorf("%s: panic: %v", name, r))
+			}
+		}()
+		run(ctx)
+	}()
+	return c
+}
+
+// stop 取消组件的上下文并等待其退出，超过 timeout 仍未退出时返回 false
+func (c *component) stop(timeout time.Duration) bool {
+	c.cancel()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-c.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// stopper 在退出时依次停止组件，并记录未能在 shutdown_timeout_seconds 内退出的组件。
+// 卡住的 goroutine（例如阻塞在内核读取中的采集器）被放弃，不会让整个进程挂起
+type stopper struct {
+	log     *slog.Logger
+	timeout time.Duration
+	stuck   []string
+}
+
+// newStopper 创建一个使用配置中退出超时的 stopper
+func (g *Guardian) newStopper() *stopper {
+	return &stopper{log: g.log, timeout: g.cfg.GetShutdownTimeout()}
+}
+
+// stop 停止一个组件，超时时记录错误并继续停止后面的组件
+func (s *stopper) stop(c *component) {
+	if c.stop(s.timeout) {
+		return
+	}
+	s.log.Error("Component did not stop in time, abandoning it", "component", c.name, "timeout", s.timeout)
+	s.stuck = append(s.stuck, c.name)
+}
+
+// err 返回未能按时退出的组件，全部正常退出时返回 nil
+func (s *stopper) err() error {
+	if len(s.stuck) == 0 {
+		return nil
+	}
+	return fmt.Errorf("components did not stop within %s: %s", s.timeout, strings.Join(s.stuck, ", "))
+}
+
+// processAlerts 按严重级别路由，将警报分发给对应的警报器
+func (g *Guardian) processAlerts(ctx context.Context) {
+	g.log.Info("Starting alert processor")
+	for {
+		select {
+		case <-ctx.Done():
+			g.flushAlerts()
+			g.log.Info("Alert processor stopped")
+			return
+		case alert := <-g.alertsChan:
+			g.dispatch(ctx, alert)
+		}
+	}
+}
+
+// flushAlerts 在退出前发送 channel 中剩余的警报
+func (g *Guardian) flushAlerts() {
+	ctx, cancel := context.WithTimeout(context.Background(), alertFlushTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case alert := <-g.alertsChan:
+			g.dispatch(ctx, alert)
+		default:
+			return
+		}
+	}
+}
+
+// dispatch 记录一条警报并发送给路由到的所有警报器
+func (g *Guardian) dispatch(ctx context.Context, alert alerter.Alert) {
+	if g.resolver != nil {
+		g.resolver.Annotate(ctx, &alert)
+	}
+	g.history.Add(alert)
+	if g.collected != nil {
+		*g.collected = append(*g.collected, alert)
+	}
+	g.metrics.ObserveAlert(alert.RuleName, string(alert.Reason), string(alert.Severity))
+	for _, t := range g.router.Targets(alert) {
+		err := t.Send(ctx, alert)
+		g.router.RecordResult(t.Name, err)
+		if err != nil {
+			g.log.Error("Failed to send alert", "alerter", t.Name, "rule", alert.RuleName, "reason", alert.Reason,
+				"pid", alert.ProcessStats.PID, "comm", alert.ProcessStats.Comm, "error", err)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+// pkg/guardian/guardian_test.go
+package guardian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/alerter"
+	"traffic-guardian/internal/collector"
+)
+
+// mb 是测试中常用的流量单位
+const mb = 1024 * 1024
+
+// 测试事件使用大于 PID_MAX_LIMIT (4194304) 的 PID，保证不会被当作 /proc 中的内核线程丢弃
+const (
+	pidA uint32 = 5000001
+	pidB uint32 = 5000002
+	pidC uint32 = 5000003
+)
+
+// testRules 是测试配置共用的规则字段，检查间隔足够长，测试期间只会在退出时检查
+const testRules = `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 3600
+  alert_cooldown_minutes: 60
+`
+
+// loadTestConfig 将 YAML 写入临时文件并加载，与真实配置经过相同的展开和校验
+func loadTestConfig(t *testing.T, doc string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg
+}
+
+// fakeSource 返回一个依次发送 events 的 FakeSource 的 SourceFactory
+func fakeSource(events ...TrafficEvent) SourceFactory {
+	return func(eventsChan chan<- TrafficEvent) Source {
+		return collector.NewFakeSource(eventsChan, events)
+	}
+}
+
+// newTestGuardian 创建一个以 FakeSource 为事件来源的 Guardian
+func newTestGuardian(t *testing.T, cfg *Config, events ...TrafficEvent) *Guardian {
+	t.Helper()
+	g, err := New(cfg, WithSource(fakeSource(events...)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return g
+}
+
+// xmit 构造一个发送到 remote 的事件
+func xmit(pid uint32, comm string, n uint64, remote string) TrafficEvent {
+	event := TrafficEvent{PID: pid, UID: uint32(os.Getuid()), Len: n, Family: 4, RemotePort: 443, Packets: 1}
+	copy(event.Comm[:], comm)
+	a4 := netip.MustParseAddr(remote).As4()
+	copy(event.RemoteAddr[:], a4[:])
+	return event
+}
+
+// waitFor 轮询 cond 直到它返回 true，超时时使测试失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// runAsync 在后台运行 g.Run，返回用于停止它的函数；停止函数等待 Run 返回并报告其错误
+func runAsync(t *testing.T, g *Guardian) (stop func() error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- g.Run(ctx) }()
+	return func() error {
+		cancel()
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(10 * time.Second):
+			t.Fatal("Run did not return after the context was cancelled")
+			return nil
+		}
+	}
+}
+
+func TestRunWithFakeSource(t *testing.T) {
+	cfg := loadTestConfig(t, testRules+`
+  traffic_threshold_mb: 100
+`)
+	g := newTestGuardian(t, cfg,
+		xmit(pidA, "curl", 1*mb, "198.51.100.1"),
+		xmit(pidB, "wget", 2*mb, "198.51.100.2"),
+		xmit(pidA, "curl", 1*mb, "198.51.100.1"),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := g.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run took %s to return after the context expired", elapsed)
+	}
+
+	for pid, want := range map[uint32]uint64{pidA: 2 * mb, pidB: 2 * mb} {
+		s, ok := g.stateManager.GetProcessStats(pid)
+		if !ok || s.TotalBytes != want {
+			t.Errorf("PID %d: tracked = %v, total = %d; want %d bytes", pid, ok, s.TotalBytes, want)
+		}
+	}
+	if alerts := g.history.List(); len(alerts) != 0 {
+		t.Errorf("sent %d alerts below the threshold, want none", len(alerts))
+	}
+}
+
+func TestFakeSourceDrivesAlert(t *testing.T) {
+	cfg := loadTestConfig(t, `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 1
+  alert_cooldown_minutes: 60
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 2
+`)
+	g := newTestGuardian(t, cfg,
+		xmit(pidA, "curl", 1*mb, "198.51.100.1"),
+		xmit(pidB, "wget", 1*mb, "198.51.100.2"),
+		xmit(pidA, "curl", 2*mb, "198.51.100.1"),
+	)
+	stop := runAsync(t, g)
+
+	// 警报由运行中的定期检查发出，而不是退出前的最后一次检查
+	waitFor(t, "the periodic check to alert", func() bool { return len(g.history.List()) > 0 })
+	if err := stop(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	alerts := g.history.List()
+	if len(alerts) != 1 {
+		t.Fatalf("sent %d alerts, want 1 (cooldown suppresses the final check)", len(alerts))
+	}
+	if a := alerts[0]; a.RuleName != "egress" || a.ProcessStats.PID != pidA || a.ProcessStats.TotalBytes != 3*mb {
+		t.Errorf("alert = rule %q PID %d %d bytes, want egress PID %d %d bytes", a.RuleName, a.ProcessStats.PID, a.ProcessStats.TotalBytes, pidA, 3*mb)
+	}
+}
+
+func TestShutdownAlertsPendingViolation(t *testing.T) {
+	// 检查间隔为一小时，运行期间不会检查，只有退出前的最后一次检查能发现违规
+	cfg := loadTestConfig(t, testRules+`
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 2
+`)
+	g := newTestGuardian(t, cfg, xmit(pidA, "curl", 3*mb, "198.51.100.1"))
+	stop := runAsync(t, g)
+	waitFor(t, "the event to be counted", func() bool {
+		_, ok := g.stateManager.GetProcessStats(pidA)
+		return ok
+	})
+	if alerts := g.history.List(); len(alerts) != 0 {
+		t.Fatalf("sent %d alerts before shutdown, want none", len(alerts))
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	alerts := g.history.List()
+	if len(alerts) != 1 || alerts[0].RuleName != "egress" || alerts[0].ProcessStats.PID != pidA {
+		t.Fatalf("alerts after shutdown = %+v, want one egress alert for PID %d", alerts, pidA)
+	}
+}
+
+func TestRunOnceReportsViolations(t *testing.T) {
+	// 历史记录只保留一条警报，RunOnce 仍然要返回本次检查发出的所有警报
+	cfg := loadTestConfig(t, testRules+`
+  definitions:
+    - name: "egress"
+      type: "traffic"
+      threshold_mb: 2
+alerter:
+  history_size: 1
+`)
+	g := newTestGuardian(t, cfg,
+		xmit(pidA, "curl", 3*mb, "198.51.100.1"),
+		xmit(pidB, "wget", 3*mb, "198.51.100.2"),
+		xmit(pidC, "rsync", 3*mb, "198.51.100.3"),
+	)
+
+	start := time.Now()
+	alerts, err := g.RunOnce(context.Background(), 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("RunOnce took %s, want it to return shortly after the 200ms collection", elapsed)
+	}
+
+	pids := make(map[uint32]bool)
+	for _, a := range alerts {
+		if a.RuleName != "egress" {
+			t.Errorf("unexpected alert from rule %q", a.RuleName)
+		}
+		pids[a.ProcessStats.PID] = true
+	}
+	if len(alerts) != 3 || !pids[pidA] || !pids[pidB] || !pids[pidC] {
+		t.Errorf("RunOnce returned alerts for %v, want one for each of PIDs %d, %d and %d", pids, pidA, pidB, pidC)
+	}
+}
+
+func TestStartupSelfTest(t *testing.T) {
+	const doc = testRules + `
+  traffic_threshold_mb: 100
+alerter:
+  validate_on_startup: true
+  abort_on_invalid: %v
+  telegram:
+    enabled: true
+    bot_token: "not-a-token"
+    chat_id: "-1001"
+`
+	_, err := New(loadTestConfig(t, fmt.Sprintf(doc, true)), WithSource(fakeSource()))
+	if err == nil || !strings.Contains(err.Error(), "alerter telegram") || !strings.Contains(err.Error(), "malformed") {
+		t.Fatalf("New with abort_on_invalid = %v, want the telegram self-test failure", err)
+	}
+
+	// 不中止启动时只记录错误
+	g := newTestGuardian(t, loadTestConfig(t, fmt.Sprintf(doc, false)))
+	g.router.Register(&failingAlerter{err: errors.New("401 Unauthorized")})
+	err = g.validateAlerters()
+	for _, want := range []string{"alerter telegram", "alerter failing: 401 Unauthorized"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validateAlerters = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+// failingAlerter 是一个自检和发送都返回 err 的警报器
+type failingAlerter struct {
+	err error
+}
+
+func (f *failingAlerter) Name() string                              { return "failing" }
+func (f *failingAlerter) IsEnabled() bool                           { return true }
+func (f *failingAlerter) Validate(context.Context) error            { return f.err }
+func (f *failingAlerter) Send(context.Context, alerter.Alert) error { return f.err }
+
+// panicSource 是一个启动后立即 panic 的事件来源
+type panicSource struct{}
+
+func (panicSource) Start(context.Context) error { panic("probe exploded") }
+
+func TestComponentPanicShutsDown(t *testing.T) {
+	cfg := loadTestConfig(t, testRules+`
+  traffic_threshold_mb: 100
+`)
+	g, err := New(cfg, WithSource(func(chan<- TrafficEvent) Source { return panicSource{} }))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// 上下文不会被取消，Run 必须因为 panic 自行退出
+	done := make(chan error, 1)
+	go func() { done <- g.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "panic: probe exploded") {
+			t.Errorf("Run = %v, want the source panic", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not return after a component panicked")
+	}
+}
+
+// stuckSource 是一个忽略上下文取消、一直阻塞到 release 被关闭的事件来源，模拟阻塞在内核读取中的采集器
+type stuckSource struct{ release chan struct{} }
+
+func (s stuckSource) Start(context.Context) error {
+	<-s.release
+	return nil
+}
+
+func TestShutdownAbandonsStuckComponent(t *testing.T) {
+	cfg := loadTestConfig(t, `
+shutdown_timeout_seconds: 1
+`+testRules)
+	source := stuckSource{release: make(chan struct{})}
+	defer close(source.release)
+	g, err := New(cfg, WithSource(func(chan<- TrafficEvent) Source { return source }))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = g.Run(ctx)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run took %s to return with a 1s shutdown timeout", elapsed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "did not stop within 1s: source") {
+		t.Errorf("Run = %v, want an error naming the stuck source", err)
+	}
+}
+
+// processTotals 返回每个进程的累计流量
+func processTotals(g *Guardian) map[uint32]uint64 {
+	totals := make(map[uint32]uint64)
+	for _, s := range g.stateManager.GetStats() {
+		totals[s.PID] = s.TotalBytes
+	}
+	return totals
+}
+
+func TestReplayMatchesRecordedTotals(t *testing.T) {
+	events := []TrafficEvent{
+		xmit(pidA, "curl", 1*mb, "198.51.100.1"),
+		xmit(pidB, "rsync", 3*mb, "198.51.100.2"),
+		xmit(pidA, "curl", 512, "198.51.100.3"),
+		xmit(pidC, "wget", 40, "198.51.100.1"),
+		xmit(pidB, "rsync", 7, "198.51.100.2"),
+	}
+	path := filepath.Join(t.TempDir(), "events.tgev")
+	rec, err := collector.NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		if err := rec.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(source SourceFactory) map[uint32]uint64 {
+		t.Helper()
+		g, err := New(loadTestConfig(t, testRules), WithSource(source))
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		if _, err := g.RunOnce(context.Background(), 300*time.Millisecond); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		return processTotals(g)
+	}
+	live := run(fakeSource(events...))
+	replayed := run(func(ch chan<- TrafficEvent) Source { return collector.NewFileSource(path, ch, 0) })
+
+	want := map[uint32]uint64{pidA: 1*mb + 512, pidB: 3*mb + 7, pidC: 40}
+	if !maps.Equal(live, want) {
+		t.Fatalf("live totals = %v, want %v", live, want)
+	}
+	if !maps.Equal(replayed, live) {
+		t.Errorf("replayed totals = %v, want %v", replayed, live)
+	}
+}
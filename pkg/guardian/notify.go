@@ -0,0 +1,66 @@
+// pkg/guardian/notify.go
+package guardian
+
+import (
+	"context"
+	"time"
+
+	"traffic-guardian/internal/collector"
+	"traffic-guardian/internal/sdnotify"
+)
+
+// attachPoll 是等待 eBPF 程序附加完成的轮询间隔
+const attachPoll = 100 * time.Millisecond
+
+// notifySystemd 在事件来源就绪后通知 systemd (READY=1)，并在启用了看门狗时定期发送心跳，
+// 直到上下文被取消。只在设置了 NOTIFY_SOCKET 时运行
+func (g *Guardian) notifySystemd(ctx context.Context) {
+	if !g.waitReady(ctx) {
+		return
+	}
+	g.sdNotify(sdnotify.Ready)
+	g.log.Info("Notified systemd of readiness")
+
+	interval, ok := sdnotify.WatchdogInterval()
+	if !ok {
+		<-ctx.Done()
+		return
+	}
+	g.log.Info("systemd watchdog enabled", "interval", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.sdNotify(sdnotify.Watchdog)
+		}
+	}
+}
+
+// waitReady 等待 eBPF 程序附加到 tracepoint；其他事件来源在启动后即视为就绪。
+// 上下文在此之前被取消时返回 false
+func (g *Guardian) waitReady(ctx context.Context) bool {
+	c, ok := g.source.(*collector.Collector)
+	if !ok {
+		return true
+	}
+	ticker := time.NewTicker(attachPoll)
+	defer ticker.Stop()
+	for !c.Attached() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+// sdNotify 发送一条 systemd 状态消息，失败时只记录日志
+func (g *Guardian) sdNotify(state string) {
+	if _, err := sdnotify.Notify(state); err != nil {
+		g.log.Warn("Failed to notify systemd", "state", state, "error", err)
+	}
+}
@@ -0,0 +1,45 @@
+// pkg/guardian/notify_test.go
+package guardian
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/sdnotify"
+)
+
+func TestSystemdNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+	t.Setenv("WATCHDOG_USEC", "")
+
+	receive := func() string {
+		t.Helper()
+		buf := make([]byte, 256)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("reading from NOTIFY_SOCKET: %v", err)
+		}
+		return string(buf[:n])
+	}
+
+	g := newTestGuardian(t, loadTestConfig(t, testRules))
+	stop := runAsync(t, g)
+	if got := receive(); got != sdnotify.Ready {
+		t.Errorf("first notification = %q, want %q", got, sdnotify.Ready)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := receive(); got != sdnotify.Stopping {
+		t.Errorf("notification on shutdown = %q, want %q", got, sdnotify.Stopping)
+	}
+}
@@ -0,0 +1,65 @@
+// pkg/guardian/reload.go
+package guardian
+
+import (
+	"errors"
+	"fmt"
+
+	"traffic-guardian/internal/config"
+	"traffic-guardian/internal/logging"
+)
+
+// includeUpdater 是可以在运行中替换进程白名单的事件来源，见 collector.Collector.UpdateInclude
+type includeUpdater interface {
+	UpdateInclude(include config.Include) error
+}
+
+// Reload 重新读取并校验配置文件，校验通过后在运行中应用规则 (rules) 和采集器的进程白名单
+// (collector.include)，返回生效配置的摘要（slog 键值对）。配置无效时返回的错误包含所有问题，
+// 原来的配置继续生效。警报器、API 等其他设置只在启动时读取，修改后需要重启。
+// SIGHUP 和 API 的 POST /reload 都通过此方法重新加载，审计日志文件也在此时重新打开
+func (g *Guardian) Reload() ([]any, error) {
+	g.reloadMu.Lock()
+	defer g.reloadMu.Unlock()
+
+	if g.auditLog != nil {
+		if err := logging.Reopen(g.auditLog); err != nil {
+			g.log.Error("Failed to reopen rule audit log", "error", err)
+		}
+	}
+
+	if len(g.configFiles) == 0 {
+		return nil, errors.New("no config file to reload, running with built-in defaults")
+	}
+	cfg, err := config.LoadConfigs(g.configFiles)
+	if err != nil {
+		g.log.Error("Failed to reload config, keeping the current configuration", "files", g.configFiles, "error", err)
+		return nil, err
+	}
+
+	// 先应用可能失败的进程白名单，成功后再替换规则，失败的重新加载不改变任何设置
+	if u, ok := g.source.(includeUpdater); ok {
+		if err := u.UpdateInclude(cfg.Collector.Include); err != nil {
+			g.log.Error("Failed to apply reloaded process include filter, keeping the current configuration", "error", err)
+			return nil, fmt.Errorf("collector.include: %w", err)
+		}
+	}
+	g.ruleEngine.UpdateRules(cfg.Rules)
+
+	running := *g.running
+	running.Rules = cfg.Rules
+	running.Collector.Include = cfg.Collector.Include
+	g.running = &running
+
+	summary := running.Summary()
+	g.log.Info("Configuration reloaded", summary...)
+	return summary, nil
+}
+
+// RunningConfig 返回当前生效的配置: 启动时的配置加上重新加载后应用的规则和进程白名单。
+// 返回的配置不应被修改
+func (g *Guardian) RunningConfig() *Config {
+	g.reloadMu.Lock()
+	defer g.reloadMu.Unlock()
+	return g.running
+}
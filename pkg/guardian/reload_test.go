@@ -0,0 +1,76 @@
+// pkg/guardian/reload_test.go
+package guardian
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"traffic-guardian/internal/config"
+)
+
+// rejectingSource 是一个拒绝更新进程白名单的事件来源，模拟写入 eBPF map 失败的采集器
+type rejectingSource struct{}
+
+func (rejectingSource) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (rejectingSource) UpdateInclude(config.Include) error {
+	return errors.New("map update failed")
+}
+
+// ruleDoc 返回只有一条名为 name 的 traffic 规则的配置
+func ruleDoc(name string) string {
+	return `
+rules:
+  time_window_minutes: 60
+  check_interval_seconds: 3600
+  definitions:
+    - name: "` + name + `"
+      type: "traffic"
+      threshold_mb: 1
+`
+}
+
+func TestFailedReloadChangesNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(ruleDoc("before")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	g, err := New(cfg, WithConfigFiles([]string{path}), WithSource(func(chan<- TrafficEvent) Source { return rejectingSource{} }))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	doc := ruleDoc("after") + `
+collector:
+  include:
+    comms: ["nginx"]
+`
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Reload(); err == nil || !strings.Contains(err.Error(), "map update failed") {
+		t.Fatalf("Reload = %v, want the include filter failure", err)
+	}
+
+	// 下一次检查仍然使用原来的规则，导出的配置也没有变化
+	g.ruleEngine.CheckRules()
+	statuses := g.ruleEngine.RuleStatuses()
+	if len(statuses) != 1 || statuses[0].Name != "before" {
+		t.Errorf("rules evaluated after the failed reload = %+v, want only %q", statuses, "before")
+	}
+	running := g.RunningConfig()
+	if defs := running.Rules.GetDefinitions(); len(defs) != 1 || defs[0].Name != "before" || !running.Collector.Include.IsEmpty() {
+		t.Errorf("running config changed after the failed reload: rules %+v, include %+v", defs, running.Collector.Include)
+	}
+}
@@ -0,0 +1,58 @@
+// pkg/guardian/selfcheck_test.go
+package guardian
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"traffic-guardian/internal/collector"
+)
+
+func TestVerifyPipeline(t *testing.T) {
+	cfg := loadTestConfig(t, testRules)
+	g := newTestGuardian(t, cfg)
+	stop := runAsync(t, g)
+	defer stop()
+
+	t.Run("events flow", func(t *testing.T) {
+		// 第一次调用时启动一个 FakeSource 代替探针，把事件送入与采集器相同的 channel
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var once sync.Once
+		triggers := 0
+		trigger := func() error {
+			triggers++
+			once.Do(func() {
+				source := collector.NewFakeSource(g.trafficEventsChan, []TrafficEvent{xmit(pidA, "curl", 100, "198.51.100.1")})
+				go source.Start(ctx)
+			})
+			return nil
+		}
+		if err := verifyPipeline(context.Background(), 5*time.Second, g.stateManager.LastEvent, trigger); err != nil {
+			t.Fatalf("verifyPipeline: %v", err)
+		}
+		if triggers == 0 {
+			t.Error("verifyPipeline passed without generating test traffic")
+		}
+	})
+
+	t.Run("no events", func(t *testing.T) {
+		err := verifyPipeline(context.Background(), 300*time.Millisecond, g.stateManager.LastEvent, func() error { return nil })
+		if !errors.Is(err, errSelfCheckTimeout) {
+			t.Fatalf("verifyPipeline = %v, want %v", err, errSelfCheckTimeout)
+		}
+	})
+
+	t.Run("trigger fails", func(t *testing.T) {
+		err := verifyPipeline(context.Background(), 300*time.Millisecond, g.stateManager.LastEvent, func() error {
+			return errors.New("network is unreachable")
+		})
+		if !errors.Is(err, errSelfCheckTimeout) || !strings.Contains(err.Error(), "network is unreachable") {
+			t.Fatalf("verifyPipeline = %v, want a timeout that reports the trigger error", err)
+		}
+	})
+}